@@ -0,0 +1,119 @@
+package confucius
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// isBig reports whether t is big.Int or big.Float.
+func isBig(t reflect.Type) bool {
+	return t == bigIntType || t == bigFloatType
+}
+
+// bigValue returns a pointer to the big.Int or big.Float v, or to a copy
+// of it when v is not addressable, as their methods have pointer receivers.
+func bigValue(v reflect.Value) interface{} {
+	if !v.CanAddr() {
+		cp := reflect.New(v.Type())
+		cp.Elem().Set(v)
+		v = cp.Elem()
+	}
+	return v.Addr().Interface()
+}
+
+// bigString formats the big.Int or big.Float v so that it parses back
+// to the same value.
+func bigString(v reflect.Value) string {
+	if f, ok := bigValue(v).(*big.Float); ok {
+		return f.Text('g', -1)
+	}
+	return fmt.Sprint(bigValue(v))
+}
+
+// setBig sets fv, a settable big.Int or big.Float, to the number in val.
+// Integers may be given in any base accepted by big.Int's SetString with
+// a base of 0, e.g. 0x prefixed hex.
+func setBig(fv reflect.Value, val string) error {
+	switch n := fv.Addr().Interface().(type) {
+	case *big.Int:
+		if _, ok := n.SetString(val, 0); !ok {
+			return fmt.Errorf("invalid integer %q", val)
+		}
+	case *big.Float:
+		if n.Prec() == 0 {
+			n.SetPrec(bigFloatPrec(val))
+		}
+		if _, ok := n.SetString(val); !ok {
+			return fmt.Errorf("invalid float %q", val)
+		}
+	}
+	return nil
+}
+
+// bigFloatPrec returns the precision used to parse val into a big.Float
+// which has none set: enough bits for every decimal digit of val, and at
+// least the 53 bits of a float64.
+func bigFloatPrec(val string) uint {
+	prec := uint(len(val)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	return prec
+}
+
+// bigHookFunc returns a DecodeHookFunc that converts strings and numbers
+// to big.Int and big.Float. Numbers too large for an int64 must be quoted
+// in the config file, as they are decoded as a float64 otherwise.
+func bigHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if !isBig(t) {
+			return data, nil
+		}
+
+		v := reflect.New(t)
+		d := reflect.ValueOf(data)
+		switch f.Kind() {
+		case reflect.String:
+			if err := setBig(v.Elem(), d.String()); err != nil {
+				return nil, err
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if t == bigIntType {
+				v.Interface().(*big.Int).SetInt64(d.Int())
+			} else {
+				v.Interface().(*big.Float).SetInt64(d.Int())
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if t == bigIntType {
+				v.Interface().(*big.Int).SetUint64(d.Uint())
+			} else {
+				v.Interface().(*big.Float).SetUint64(d.Uint())
+			}
+		case reflect.Float32, reflect.Float64:
+			if math.IsNaN(d.Float()) {
+				return nil, fmt.Errorf("cannot decode NaN into a %v", t)
+			}
+			bf := big.NewFloat(d.Float())
+			if t == bigFloatType {
+				return *bf, nil
+			}
+			i, acc := bf.Int(nil)
+			if i == nil || acc != big.Exact {
+				return nil, fmt.Errorf("cannot decode %v into an integer", data)
+			}
+			return *i, nil
+		default:
+			return data, nil
+		}
+		return v.Elem().Interface(), nil
+	}
+}
@@ -0,0 +1,119 @@
+package confucius
+
+import (
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_Load_Big(t *testing.T) {
+	type Config struct {
+		Supply  *big.Int   `conf:"supply" default:"115792089237316195423570985008687907853269984665640564039457584007913129639935"`
+		Balance big.Int    `conf:"balance"`
+		Mask    *big.Int   `conf:"mask" default:"0xffffffffffffffffffff"`
+		Rate    *big.Float `conf:"rate" default:"0.1000000000000000000000000001"`
+		Price   *big.Float `conf:"price"`
+		Fee     *big.Int   `conf:"fee"`
+	}
+
+	os.Clearenv()
+	setenv(t, "APP_FEE", "18446744073709551616")
+
+	var cfg Config
+	err := Load(&cfg, String("balance: \"98765432109876543210\"\nprice: 1.5", DecoderYaml), UseEnv("app"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		got  interface{ String() string }
+		want string
+	}{
+		"supply":  {cfg.Supply, "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		"balance": {&cfg.Balance, "98765432109876543210"},
+		"mask":    {cfg.Mask, "1208925819614629174706175"},
+		"fee":     {cfg.Fee, "18446744073709551616"},
+	} {
+		if tc.got.String() != tc.want {
+			t.Errorf("%s: want %s, got %s", name, tc.want, tc.got)
+		}
+	}
+
+	if got := cfg.Rate.Text('f', 28); got != "0.1000000000000000000000000001" {
+		t.Errorf("rate: want 0.1000000000000000000000000001, got %s", got)
+	}
+	if f, _ := cfg.Price.Float64(); f != 1.5 {
+		t.Errorf("price: want 1.5, got %v", cfg.Price)
+	}
+
+	t.Run("bad default", func(t *testing.T) {
+		var cfg struct {
+			Supply *big.Int `conf:"supply" default:"12ab"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["supply"]; !ok {
+			t.Fatalf("want supply in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("bad file value", func(t *testing.T) {
+		var cfg struct {
+			Supply *big.Int `conf:"supply"`
+		}
+		err := Load(&cfg, String(`supply: 1.5`, DecoderYaml))
+		if err == nil || !strings.Contains(err.Error(), "supply") {
+			t.Fatalf("want error decoding supply, got %v", err)
+		}
+	})
+
+	t.Run("nan and infinity", func(t *testing.T) {
+		var cfg struct {
+			Amount *big.Float `conf:"amount"`
+			Supply *big.Int   `conf:"supply"`
+		}
+		for _, content := range []string{`amount: .nan`, `supply: .nan`, `supply: .inf`} {
+			err := Load(&cfg, String(content, DecoderYaml))
+			if err == nil {
+				t.Errorf("%s: expected err", content)
+			}
+		}
+	})
+}
+
+func Test_confucius_Dump_Big(t *testing.T) {
+	cfg := struct {
+		Supply *big.Int  `conf:"supply"`
+		Rate   big.Float `conf:"rate"`
+	}{Supply: new(big.Int).Lsh(big.NewInt(1), 100)}
+	cfg.Rate.SetPrec(200).SetString("0.1000000000000000000000000001")
+
+	out, err := Dump(cfg)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := "supply: \"1267650600228229401496703205376\"\nrate: \"0.1000000000000000000000000001\"\n"
+	if string(out) != want {
+		t.Errorf("\nwant %q\ngot  %q", want, out)
+	}
+
+	var loaded struct {
+		Supply *big.Int   `conf:"supply"`
+		Rate   *big.Float `conf:"rate"`
+	}
+	if err := Load(&loaded, String(string(out), DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if loaded.Supply.Cmp(cfg.Supply) != 0 || loaded.Rate.Text('g', -1) != cfg.Rate.Text('g', -1) {
+		t.Errorf("want dump to load back %v %v, got %v %v", cfg.Supply, &cfg.Rate, loaded.Supply, loaded.Rate)
+	}
+}
+
+func Test_bigValue(t *testing.T) {
+	v := reflect.ValueOf(*big.NewInt(42))
+	if got := bigString(v); got != "42" {
+		t.Errorf("want 42, got %s", got)
+	}
+}
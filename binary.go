@@ -0,0 +1,62 @@
+package confucius
+
+import (
+	"encoding"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isBinaryUnmarshaler reports whether the pointer to t implements
+// encoding.BinaryUnmarshaler. Types that also implement
+// encoding.TextUnmarshaler, such as time.Time, are excluded: their binary
+// form is not meant to be written by hand and is rejected by
+// UnmarshalBinary when given as text.
+func isBinaryUnmarshaler(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return false
+	}
+	pt := reflect.PtrTo(t)
+	return pt.Implements(binaryUnmarshalerType) && !pt.Implements(textUnmarshalerType)
+}
+
+// binaryBytes returns the bytes given to the UnmarshalBinary method of a
+// value, val itself or, with the BinaryBase64 option, val base64 decoded.
+func (c *confucius) binaryBytes(val string) ([]byte, error) {
+	if !c.binaryBase64 {
+		return []byte(val), nil
+	}
+	return c.byteEncoding.DecodeString(val)
+}
+
+// setBinary sets fv, a settable value whose pointer implements
+// encoding.BinaryUnmarshaler, by calling UnmarshalBinary with val.
+func (c *confucius) setBinary(fv reflect.Value, val string) error {
+	b, err := c.binaryBytes(val)
+	if err != nil {
+		return err
+	}
+	return fv.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(b)
+}
+
+// binaryUnmarshalerHookFunc returns a hook that decodes the strings given
+// to the types implementing encoding.BinaryUnmarshaler by calling their
+// UnmarshalBinary method.
+func (c *confucius) binaryUnmarshalerHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || !isBinaryUnmarshaler(t) {
+			return data, nil
+		}
+
+		v := reflect.New(t).Elem()
+		if err := c.setBinary(v, reflect.ValueOf(data).String()); err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+}
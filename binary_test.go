@@ -0,0 +1,134 @@
+package confucius
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sealedKey only implements binary unmarshaling, like some key types.
+type sealedKey struct {
+	version  byte
+	material []byte
+}
+
+func (k *sealedKey) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 {
+		return fmt.Errorf("key too short")
+	}
+	k.version, k.material = b[0], append([]byte(nil), b[1:]...)
+	return nil
+}
+
+// textKey implements both binary and text unmarshaling, like netip.Addr.
+type textKey struct {
+	text string
+}
+
+func (k *textKey) UnmarshalBinary(b []byte) error {
+	return fmt.Errorf("unexpected slice size")
+}
+
+func (k *textKey) UnmarshalText(b []byte) error {
+	k.text = string(b)
+	return nil
+}
+
+func Test_isBinaryUnmarshaler(t *testing.T) {
+	for _, tc := range []struct {
+		Name string
+		Type reflect.Type
+		Want bool
+	}{
+		{"binary", reflect.TypeOf(sealedKey{}), true},
+		{"pointer", reflect.TypeOf(&sealedKey{}), false},
+		{"text", reflect.TypeOf(textKey{}), false},
+		{"time", reflect.TypeOf(time.Time{}), false},
+		{"string", reflect.TypeOf(""), false},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := isBinaryUnmarshaler(tc.Type); got != tc.Want {
+				t.Fatalf("isBinaryUnmarshaler(%v) == %v, expected %v", tc.Type, got, tc.Want)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_BinaryUnmarshaler(t *testing.T) {
+	type Config struct {
+		Signing  sealedKey  `conf:"signing" default:"1secret"`
+		Session  *sealedKey `conf:"session"`
+		Previous *sealedKey `conf:"previous"`
+		Since    time.Time  `conf:"since" default:"2020-01-01T00:00:00Z"`
+	}
+
+	os.Clearenv()
+	setenv(t, "APP_SESSION", "2session")
+
+	var cfg Config
+	err := Load(&cfg, String(`previous: 0old`, DecoderYaml), UseEnv("app"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		got     *sealedKey
+		version byte
+		want    string
+	}{
+		"signing":  {&cfg.Signing, '1', "secret"},
+		"session":  {cfg.Session, '2', "session"},
+		"previous": {cfg.Previous, '0', "old"},
+	} {
+		if tc.got == nil || tc.got.version != tc.version || string(tc.got.material) != tc.want {
+			t.Errorf("%s: want version %c and material %s, got %+v", name, tc.version, tc.want, tc.got)
+		}
+	}
+	if want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC); !cfg.Since.Equal(want) {
+		t.Errorf("since: want %v, got %v", want, cfg.Since)
+	}
+
+	t.Run("base64", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "APP_SESSION", base64.StdEncoding.EncodeToString([]byte{3, 0xff, 0x00}))
+
+		var cfg Config
+		content := "previous: " + base64.StdEncoding.EncodeToString([]byte{4, 0x01})
+		if err := Load(&cfg, String(content, DecoderYaml), UseEnv("app"), BinaryBase64()); err == nil {
+			t.Fatalf("expected err for the default that is not base64")
+		}
+
+		cfg = Config{}
+		cfg.Signing = sealedKey{version: 9, material: []byte("kept")}
+		if err := Load(&cfg, String(content, DecoderYaml), UseEnv("app"), BinaryBase64()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Session == nil || cfg.Session.version != 3 || string(cfg.Session.material) != "\xff\x00" {
+			t.Errorf("session: unexpected key %+v", cfg.Session)
+		}
+		if cfg.Previous == nil || cfg.Previous.version != 4 || string(cfg.Previous.material) != "\x01" {
+			t.Errorf("previous: unexpected key %+v", cfg.Previous)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		os.Clearenv()
+
+		var cfg struct {
+			Key sealedKey `conf:"key" default:"x"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["key"]; !ok {
+			t.Fatalf("want key in fieldErrors, got %+v", err)
+		}
+
+		err = Load(&cfg, String(`key: "y"`, DecoderYaml))
+		if err == nil || !strings.Contains(err.Error(), "key too short") {
+			t.Fatalf("want error decoding key, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package confucius
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var byteSizeType = reflect.TypeOf(ByteSize(0))
+
+// ByteSize is a number of bytes written with an optional SI or IEC unit,
+// e.g. 10MB or 2GiB, as often used for upload limits and buffer sizes.
+//
+//	B                        bytes
+//	KB MB GB TB PB EB        powers of 1000
+//	KiB MiB GiB TiB PiB EiB  powers of 1024
+//
+// The units are case insensitive and may be separated from the number by a
+// space. The number may have a fractional part as long as the size is a
+// whole number of bytes, e.g. 1.5KB.
+//
+//	type Config struct {
+//	  MaxUpload confucius.ByteSize `conf:"max_upload" default:"10MB"`
+//	}
+type ByteSize uint64
+
+// byteSizeUnits holds the number of bytes of each unit, the IEC ones first.
+var byteSizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"EiB", 1 << 60},
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"EB", 1e18},
+	{"PB", 1e15},
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parses a byte size such as 1024, 10MB, 2GiB or 1.5 KB.
+func ParseByteSize(s string) (ByteSize, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	num, unit := str, ""
+	if i >= 0 {
+		num, unit = str[:i], strings.TrimSpace(str[i:])
+	}
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size := uint64(1)
+	if unit != "" {
+		found := false
+		for _, u := range byteSizeUnits {
+			if strings.EqualFold(unit, u.name) {
+				size, found = u.size, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, unit)
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).SetUint64(size)))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid byte size %q: not a whole number of bytes", s)
+	}
+	if !r.Num().IsUint64() {
+		return 0, rangeError(s, byteSizeType, strconv.ErrRange)
+	}
+	return ByteSize(r.Num().Uint64()), nil
+}
+
+// String formats the size with the largest unit that keeps it whole,
+// preferring the IEC ones on a tie, e.g. 2GiB, 10MB or 0B.
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	best := byteSizeUnits[len(byteSizeUnits)-1]
+	for _, u := range byteSizeUnits {
+		if uint64(b)%u.size == 0 && u.size > best.size {
+			best = u
+		}
+	}
+	return strconv.FormatUint(uint64(b)/best.size, 10) + best.name
+}
+
+// setByteSize sets fv, a settable ByteSize, to the size in val.
+func setByteSize(fv reflect.Value, val string) error {
+	b, err := ParseByteSize(val)
+	if err != nil {
+		return err
+	}
+	fv.SetUint(uint64(b))
+	return nil
+}
+
+// byteSizeHookFunc returns a DecodeHookFunc that parses the strings
+// decoded into a ByteSize, e.g. "10MB". Numbers are decoded as bytes.
+func byteSizeHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != byteSizeType || f.Kind() != reflect.String {
+			return data, nil
+		}
+		return ParseByteSize(data.(string))
+	}
+}
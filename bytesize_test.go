@@ -0,0 +1,91 @@
+package confucius
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func Test_ParseByteSize(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want ByteSize
+		str  string
+	}{
+		{"1KiB", 1024, "1KiB"},
+		{"1KB", 1000, "1KB"},
+		{"0", 0, "0B"},
+		{"512", 512, "512B"},
+		{"10MB", 10000000, "10MB"},
+		{"2GiB", 2 << 30, "2GiB"},
+		{"1.5KB", 1500, "1500B"},
+		{"1.5 KiB", 1536, "1536B"},
+		{"4kib", 4096, "4KiB"},
+		{"3B", 3, "3B"},
+		{"1024MiB", 1 << 30, "1GiB"},
+		{"16EiB", 0, ""},
+		{"18EB", 18e18, "18EB"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseByteSize(tc.in)
+			if tc.str == "" {
+				if !errors.Is(err, strconv.ErrRange) {
+					t.Fatalf("want ErrRange, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("want %d, got %d", tc.want, got)
+			}
+			if got.String() != tc.str {
+				t.Errorf("want %s, got %s", tc.str, got.String())
+			}
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, in := range []string{"", "MB", "-1KB", "1.2.3MB", "10XB", "1e3", "0.5B", ".", "10 M B"} {
+			if _, err := ParseByteSize(in); err == nil {
+				t.Errorf("ParseByteSize(%q): expected err", in)
+			}
+		}
+	})
+}
+
+func Test_confucius_Load_ByteSize(t *testing.T) {
+	type Config struct {
+		MaxUpload ByteSize  `conf:"max_upload"`
+		Buffer    ByteSize  `conf:"buffer"`
+		Chunk     *ByteSize `conf:"chunk" default:"64KiB"`
+		Cache     ByteSize  `conf:"cache"`
+	}
+
+	setenv(t, "CACHE", "1GiB")
+
+	var cfg Config
+	if err := Load(&cfg, String("max_upload: \"10MB\"\nbuffer: 4096", DecoderYaml), UseEnv("")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.MaxUpload != 10000000 {
+		t.Errorf("want max upload 10MB, got %s", cfg.MaxUpload)
+	}
+	if cfg.Buffer != 4096 {
+		t.Errorf("want buffer 4096, got %d", cfg.Buffer)
+	}
+	if cfg.Chunk == nil || *cfg.Chunk != 64<<10 {
+		t.Errorf("want chunk 64KiB, got %v", cfg.Chunk)
+	}
+	if cfg.Cache != 1<<30 {
+		t.Errorf("want cache 1GiB, got %s", cfg.Cache)
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`max_upload: "10 megs"`, DecoderYaml)); err == nil {
+			t.Fatal("expected err")
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package confucius
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkOptions returns an error wrapping ErrConflictingOptions if the
+// options given are contradictory or cannot be used as given, so that
+// Load fails instead of silently ignoring some of them.
+//
+// Reader used with File or Dirs is not a conflict, the readers and the
+// files are merged in the order set by Precedence. Neither is Callback
+// used with SetOutput, the entries are sent to both.
+func (c *confucius) checkOptions() error {
+	conflict := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrConflictingOptions, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case c.tag == "":
+		return conflict("Tag requires a tag key")
+	case len(c.timeLayouts) == 0:
+		return conflict("TimeLayouts requires at least one layout")
+	case c.sliceDelimiter == "":
+		return conflict("SliceDelimiter requires a delimiter")
+	case c.byteEncoding == nil:
+		return conflict("ByteEncoding requires an encoding")
+	case c.searchDepth < 0:
+		return conflict("Recursive requires a depth of at least 0, got %d", c.searchDepth)
+	case c.envOnly && (c.filenameSet || len(c.filePatterns) > 0 || len(c.filePaths) > 0 || c.useReader):
+		return conflict("UseEnvOnly cannot be used with File, Files, FilePaths or Reader, only the environment is loaded")
+	case c.prefixEnvTags && !c.useEnv:
+		return conflict("PrefixEnvTags requires UseEnv")
+	case c.autoConfD && len(c.filePatterns) > 0:
+		return conflict("AutoConfD cannot be used with Files, the main file it applies to is not loaded")
+	case len(c.filePaths) > 0 && (len(c.filePatterns) > 0 || c.autoConfD):
+		return conflict("FilePaths cannot be used with Files or AutoConfD, the files are not searched for")
+	case (c.warnUnboundEnv || c.strictEnv) && (!c.useEnv || c.envPrefix == ""):
+		return conflict("WarnUnboundEnv and StrictEnv require UseEnv with a prefix")
+	case c.prompter != nil && (c.prompter.in == nil || c.prompter.out == nil):
+		return conflict("PromptMissing requires a reader and a writer")
+	}
+
+	for _, src := range c.precedence {
+		if !containsSource(defaultPrecedence, src) {
+			return conflict("Precedence given unknown source %d", src)
+		}
+	}
+
+	for _, hook := range c.afterLoadHooks {
+		if hook == nil {
+			return conflict("AfterLoad requires a hook")
+		}
+	}
+
+	for _, transform := range c.transforms {
+		if transform == nil {
+			return conflict("TransformMap requires a transform")
+		}
+	}
+
+	if c.section != "" {
+		for _, key := range strings.Split(c.section, ".") {
+			if key == "" {
+				return conflict("Section given empty key in %q", c.section)
+			}
+		}
+	}
+
+	return nil
+}
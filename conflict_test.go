@@ -0,0 +1,72 @@
+package confucius
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_checkOptions(t *testing.T) {
+	for _, tc := range []struct {
+		Name    string
+		Options []Option
+		WantErr string
+	}{
+		{Name: "defaults"},
+		{Name: "compatible options", Options: []Option{UseEnv("app"), PrefixEnvTags(), AutoConfD(), Precedence(SourceEnv, SourceFile), Section("a.b")}},
+		{Name: "reader with file and dirs", Options: []Option{String(`host: localhost`, DecoderYaml), File("app.yaml"), Dirs("conf")}},
+		{Name: "callback with output", Options: []Option{Logger(Callback(func(LogLevel, string, string, int) {}), SetOutput(os.Stderr))}},
+		{Name: "env only", Options: []Option{UseEnvOnly("app"), PrefixEnvTags()}},
+		{Name: "env only with file", Options: []Option{UseEnvOnly("app"), File("app.yaml")}, WantErr: "UseEnvOnly cannot be used with File, Files, FilePaths or Reader"},
+		{Name: "env only with files", Options: []Option{UseEnvOnly("app"), Files("conf/*.yaml")}, WantErr: "UseEnvOnly cannot be used with File, Files, FilePaths or Reader"},
+		{Name: "env only with file paths", Options: []Option{UseEnvOnly("app"), FilePaths("base.yaml")}, WantErr: "UseEnvOnly cannot be used with File, Files, FilePaths or Reader"},
+		{Name: "env only with reader", Options: []Option{UseEnvOnly("app"), String(`host: localhost`, DecoderYaml)}, WantErr: "UseEnvOnly cannot be used with File, Files, FilePaths or Reader"},
+		{Name: "empty tag", Options: []Option{Tag("")}, WantErr: "Tag requires a tag key"},
+		{Name: "no time layouts", Options: []Option{TimeLayouts()}, WantErr: "TimeLayouts requires at least one layout"},
+		{Name: "empty slice delimiter", Options: []Option{SliceDelimiter("")}, WantErr: "SliceDelimiter requires a delimiter"},
+		{Name: "nil byte encoding", Options: []Option{ByteEncoding(nil)}, WantErr: "ByteEncoding requires an encoding"},
+		{Name: "negative depth", Options: []Option{Recursive(-1)}, WantErr: "Recursive requires a depth of at least 0, got -1"},
+		{Name: "prefix env tags without env", Options: []Option{PrefixEnvTags()}, WantErr: "PrefixEnvTags requires UseEnv"},
+		{Name: "auto conf.d with files", Options: []Option{AutoConfD(), Files("conf/*.yaml")}, WantErr: "AutoConfD cannot be used with Files"},
+		{Name: "file paths with files", Options: []Option{FilePaths("base.yaml"), Files("conf/*.yaml")}, WantErr: "FilePaths cannot be used with Files or AutoConfD"},
+		{Name: "file paths with auto conf.d", Options: []Option{FilePaths("base.yaml"), AutoConfD()}, WantErr: "FilePaths cannot be used with Files or AutoConfD"},
+		{Name: "warn unbound env without env", Options: []Option{WarnUnboundEnv()}, WantErr: "WarnUnboundEnv and StrictEnv require UseEnv with a prefix"},
+		{Name: "strict env without prefix", Options: []Option{UseEnv(""), StrictEnv()}, WantErr: "WarnUnboundEnv and StrictEnv require UseEnv with a prefix"},
+		{Name: "prompt without reader", Options: []Option{PromptMissing(nil, os.Stdout)}, WantErr: "PromptMissing requires a reader and a writer"},
+		{Name: "prompt without writer", Options: []Option{PromptMissing(os.Stdin, nil)}, WantErr: "PromptMissing requires a reader and a writer"},
+		{Name: "unknown source", Options: []Option{Precedence(Source(7))}, WantErr: "Precedence given unknown source 7"},
+		{Name: "nil after load hook", Options: []Option{AfterLoad(nil)}, WantErr: "AfterLoad requires a hook"},
+		{Name: "nil transform", Options: []Option{TransformMap(nil)}, WantErr: "TransformMap requires a transform"},
+		{Name: "empty section key", Options: []Option{Section("apps..billing")}, WantErr: `Section given empty key in "apps..billing"`},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := New(tc.Options...).c.checkOptions()
+			if tc.WantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrConflictingOptions) {
+				t.Fatalf("want err %v, got %v", ErrConflictingOptions, err)
+			}
+			if !strings.Contains(err.Error(), tc.WantErr) {
+				t.Fatalf("want err containing %q, got %v", tc.WantErr, err)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_ConflictingOptions(t *testing.T) {
+	var cfg struct {
+		Host string `conf:"host"`
+	}
+	err := Load(&cfg, String(`host: localhost`, DecoderYaml), PrefixEnvTags())
+	if !errors.Is(err, ErrConflictingOptions) {
+		t.Fatalf("want err %v, got %v", ErrConflictingOptions, err)
+	}
+	if cfg.Host != "" {
+		t.Fatalf("want nothing loaded, got %+v", cfg)
+	}
+}
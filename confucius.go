@@ -1,11 +1,17 @@
 package confucius
 
 import (
-	"embed"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -14,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/hcl"
 	"github.com/imdario/mergo"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pelletier/go-toml"
@@ -30,49 +37,137 @@ const (
 	DefaultTag = "conf"
 	// DefaultTimeLayout is the default time layout that confucius uses to parse times.
 	DefaultTimeLayout = time.RFC3339
+	// DefaultSliceDelimiter is the default delimiter that confucius uses to split
+	// slices given as strings.
+	DefaultSliceDelimiter = ","
+	// DefaultFlatKeySeparator is the default separator that confucius uses to
+	// split the keys of flat formats, like ini and properties, into nested keys.
+	DefaultFlatKeySeparator = "."
 	// DefaultProfileLayout represents default profile file layout.
-	// You should use `config` for filename, `test` for profile, `yaml` for extension.
-	// Example; config-test.yaml
-	DefaultProfileLayout = "config.test.yaml"
+	// You should use `{name}` for filename, `{profile}` for profile, `{ext}` for extension,
+	// or the legacy `config`, `test` and `yaml` words.
+	// Example; {name}.{profile}.{ext} searches config.test.yaml for the
+	// profile test of config.yaml.
+	DefaultProfileLayout = "{name}.{profile}.{ext}"
 	// MainFileIndicator is config file type indicator
 	MainFileIndicator = "#main"
 	// MainFileIndicator is config file type indicator
 	ProfileFileIndicator = "#profile"
+	// PartFileIndicator is config file type indicator of the files matching
+	// the patterns given with the Files option.
+	PartFileIndicator = "#part"
 	// FileEmbedLocationIndicator is config file location indicator
 	EmbedLocationIndicator = "#embed"
 	// FileEmbedLocationIndicator is config file location indicator
 	LocalLocationIndicator = "#local"
 )
 
+// The placeholders of a profile layout, e.g. {name}-{profile}.{ext}.
+const (
+	profilePlaceholderName    = "{name}"
+	profilePlaceholderProfile = "{profile}"
+	profilePlaceholderExt     = "{ext}"
+)
+
+// extensionlessFormats are the extensions tried, in order, for a main file
+// named without one, e.g. File("config").
+var extensionlessFormats = []Decoder{DecoderYaml, DecoderYml, DecoderJSON, DecoderToml}
+
+// maxEnvSliceLen bounds the length a slice can be grown to from the
+// environment, so that a stray MYAPP_SERVERS_99999999_HOST does not
+// allocate millions of elements.
+const maxEnvSliceLen = 1024
+
+// secretFilePrefix marks the values read from secret files.
+const secretFilePrefix = "file:"
+
 type decodedObject map[string]interface{}
 
+// readerSource is a reader given with the Reader or String options.
+type readerSource struct {
+	reader  io.Reader
+	decoder Decoder
+	content []byte // the content of reader, once it is read.
+}
+
 func defaultConfucius() *confucius {
 	return &confucius{
-		filename:      DefaultFilename,
-		dirs:          []string{DefaultDir},
-		tag:           DefaultTag,
-		timeLayout:    DefaultTimeLayout,
-		profileLayout: DefaultProfileLayout,
-		logger:        defaultLogger(),
+		filename:       DefaultFilename,
+		dirs:           []string{DefaultDir},
+		tag:            DefaultTag,
+		timeLayouts:    []string{DefaultTimeLayout},
+		sliceDelimiter: DefaultSliceDelimiter,
+		flatKeySep:     DefaultFlatKeySeparator,
+		byteEncoding:   base64.StdEncoding,
+		profileLayout:  DefaultProfileLayout,
+		keyring:        osKeyring{},
+		logger:         defaultLogger(),
+		ctx:            context.Background(),
 	}
 }
 
 type confucius struct {
-	useEnv              bool
-	useReader           bool
-	useEmbedFS          bool
-	dirs                []string
-	profiles            []string
-	expectedConfigFiles []string
-	filename            string
-	tag                 string
-	timeLayout          string
-	envPrefix           string
-	profileLayout       string
-	readerConfig        io.Reader
-	readerDecoder       Decoder
-	embedFS             embed.FS
-	logger              *logger
+	useEnv                bool
+	envOnly               bool
+	useReader             bool
+	requireExportedFields bool
+	firstDirWins          bool
+	unmaskSecrets         bool
+	ignoreBadDefaults     bool
+	searchDepth           int
+	maxFileSize           int64
+	unixTime              bool
+	prefixEnvTags         bool
+	disableEnvExpansion   bool
+	secretFileExpansion   bool
+	transcodeUTF16        bool
+	autoConfD             bool
+	optionalFile          bool
+	diagnostics           bool
+	dirs                  []string
+	profiles              []string
+	expectedConfigFiles   []string
+	filename              string
+	filenameSet           bool
+	filePatterns          []string
+	filePaths             []string
+	section               string
+	disableEnvSliceBind   bool
+	tag                   string
+	timeLayouts           []string
+	sliceDelimiter        string
+	flatKeySep            string
+	byteEncoding          *base64.Encoding
+	binaryBase64          bool
+	mergeSkipEmpty        bool
+	mergeAppendSlice      bool
+	extendedDurations     bool
+	warnUnboundEnv        bool
+	failFast              bool
+	interpolateConfigRefs bool
+	strictEnv             bool
+	envPrefix             string
+	profileLayout         string
+	readers               []*readerSource
+	fsys                  fs.FS
+	decodeHooks           []mapstructure.DecodeHookFunc
+	afterLoadHooks        []func(cfg interface{}) error
+	transforms            []func(map[string]interface{}) (map[string]interface{}, error)
+	keyMatcher            func(key, fieldName string) bool
+	precedence            []Source
+	trackSources          *map[string]string
+	captureRaw            map[string][]byte
+	sources               map[string]string // the origin of each field's value, keyed by the field's path.
+	fileSources           map[string]string // the origin of each decoded value, keyed by its lowercased path.
+	keyOrder              *[]string
+	keys                  []string        // the lowercased paths of the decoded keys in document order.
+	keyIndex              map[string]int  // the position of each path in keys.
+	boundEnv              map[string]bool // the environment variables looked up for the fields.
+	keychainService       string
+	keyring               Keyring
+	prompter              *prompter
+	logger                *logger
+	ctx                   context.Context // cancels the reads of the sources, see LoadContext.
 }
 
 // Load reads a configuration file and loads it into the given struct. The
@@ -99,36 +194,168 @@ type confucius struct {
 //
 // A single field may not be marked as both `required` and `default`.
 func Load(cfg interface{}, options ...Option) error {
+	return New(options...).Load(cfg)
+}
+
+// LoadContext is like Load but stops loading as soon as ctx is cancelled or
+// its deadline passes, e.g. while reading a large or slow config file or
+// reader, and returns ctx.Err().
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	err := confucius.LoadContext(ctx, &cfg, confucius.File("config.yaml"))
+func LoadContext(ctx context.Context, cfg interface{}, options ...Option) error {
+	l := New(options...)
+	l.c.ctx = ctx
+	return l.Load(cfg)
+}
+
+// Check runs the same pipeline as Load, decoding the sources, setting the
+// defaults and validating the fields, and returns the error Load would have
+// returned, without modifying cfg. The configuration is loaded into a deep
+// copy of cfg, so the values already set in cfg are taken into account
+// like Load does.
+//
+//	if err := confucius.Check(&Config{}, confucius.File("config.yaml")); err != nil {
+//	  log.Fatalf("invalid config: %v", err)
+//	}
+func Check(cfg interface{}, options ...Option) error {
+	if !isStructPtr(cfg) {
+		return fmt.Errorf("cfg must be a pointer to a struct")
+	}
+	return Load(deepCopy(reflect.ValueOf(cfg)).Interface(), options...)
+}
+
+// Loader is a reusable configuration loader. It keeps the options it was
+// created with so the same configuration can be loaded repeatedly, e.g. by
+// services that poll their config files for changes.
+type Loader struct {
+	c *confucius
+}
+
+// New returns a Loader configured with the given options. The options are
+// resolved once and reused by every call to Load and Reload.
+//
+//	loader := confucius.New(confucius.File("config.yaml"), confucius.UseEnv("myapp"))
+//	err := loader.Load(&cfg)
+//	...
+//	err = loader.Reload(&cfg)
+func New(options ...Option) *Loader {
 	c := defaultConfucius()
 
 	for _, opt := range options {
 		opt(c)
 	}
 
-	return c.Load(cfg)
+	return &Loader{c: c}
+}
+
+// Load loads the configuration into the given struct. See the package level
+// Load function for details.
+func (l *Loader) Load(cfg interface{}) error {
+	return l.c.Load(cfg)
+}
+
+// Reload loads the configuration again into the given struct. Unlike Load,
+// the configuration is loaded into a fresh value of the same type which then
+// replaces the contents of cfg, so values that were removed from the sources
+// do not linger and defaults are applied again. If loading fails cfg is left
+// untouched.
+func (l *Loader) Reload(cfg interface{}) error {
+	return l.c.Reload(cfg)
+}
+
+// Reload loads the configuration into a fresh value that replaces the
+// contents of cfg.
+func (c *confucius) Reload(cfg interface{}) error {
+	if !isStructPtr(cfg) {
+		return fmt.Errorf("cfg must be a pointer to a struct")
+	}
+
+	fresh := reflect.New(reflect.TypeOf(cfg).Elem())
+	if err := c.Load(fresh.Interface()); err != nil {
+		return err
+	}
+
+	reflect.ValueOf(cfg).Elem().Set(fresh.Elem())
+	return nil
+}
+
+// reset clears the state accumulated while loading so that the same
+// instance can be used to load again. The content of readers is kept
+// since readers can only be consumed once.
+func (c *confucius) reset() {
+	c.expectedConfigFiles = nil
+	c.fileSources = nil
+	c.sources = nil
+	c.keys = nil
+	c.keyIndex = nil
+	c.boundEnv = nil
 }
 
 func (c *confucius) Load(cfg interface{}) (err error) {
 	c.logger.Debug("confucius starting")
 
+	// the state left behind by a previous load of the same instance
+	// must not leak into this one.
+	c.reset()
+
+	if err := c.checkOptions(); err != nil {
+		return err
+	}
+
 	if !isStructPtr(cfg) {
 		return fmt.Errorf("cfg must be a pointer to a struct")
 	}
 
-	vals := make(decodedObject)
-	if c.useReader {
-		vals, err = c.decodeReader(c.readerConfig, c.readerDecoder)
-		if err != nil {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	readerVals, err := c.decodeReaders()
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	if !c.envOnly {
+		files, err = c.findFiles()
+		if err != nil && !(c.useReader || c.useEnv) {
 			return err
 		}
 	}
 
-	files, err := c.findFiles()
-	if err != nil && !(c.useReader || c.useEnv) {
+	var vals decodedObject
+	if c.rank(SourceReader) < c.rank(SourceFile) {
+		c.setFileSources(readerVals, "", "reader")
+		if vals, err = c.decodeFiles(files, readerVals); err != nil {
+			return err
+		}
+	} else {
+		if vals, err = c.decodeFiles(files, make(decodedObject)); err != nil {
+			return err
+		}
+		if err := c.merge(&vals, readerVals); err != nil {
+			return err
+		}
+		c.setFileSources(readerVals, "", "reader")
+	}
+
+	if err := c.interpolateConfig(vals); err != nil {
+		return err
+	}
+
+	if vals, err = c.selectSection(vals); err != nil {
 		return err
 	}
 
-	if vals, err = c.decodeFiles(files, vals); err != nil {
+	if vals, err = c.transformMap(vals); err != nil {
+		return err
+	}
+
+	vals = c.matchKeys(vals, reflect.TypeOf(cfg).Elem())
+
+	if err := c.decodeFormats(vals, cfg); err != nil {
 		return err
 	}
 
@@ -136,10 +363,65 @@ func (c *confucius) Load(cfg interface{}) (err error) {
 		return err
 	}
 
-	return c.processCfg(cfg)
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	err = c.processCfg(cfg)
+	if unboundErr := c.checkUnboundEnv(); err == nil {
+		err = unboundErr
+	}
+	if err == nil {
+		err = c.afterLoad(cfg)
+	}
+	if c.trackSources != nil {
+		*c.trackSources = make(map[string]string, len(c.sources))
+		for path, origin := range c.sources {
+			(*c.trackSources)[path] = origin
+		}
+	}
+	if c.keyOrder != nil {
+		*c.keyOrder = append([]string(nil), c.keys...)
+	}
+	return err
+}
+
+// transformMap runs the transforms given with the TransformMap option in
+// order over vals, the values of the sources once merged.
+func (c *confucius) transformMap(vals decodedObject) (decodedObject, error) {
+	for _, transform := range c.transforms {
+		m, err := transform(vals)
+		if err != nil {
+			return nil, fmt.Errorf("transform: %w", err)
+		}
+		vals = decodedObject(m)
+	}
+	return vals, nil
+}
+
+// afterLoad runs the hooks given with the AfterLoad option in order,
+// stopping at the first one that fails.
+func (c *confucius) afterLoad(cfg interface{}) error {
+	for _, hook := range c.afterLoadHooks {
+		if err := hook(cfg); err != nil {
+			return fmt.Errorf("after load: %w", err)
+		}
+	}
+	return nil
 }
 
 func (c *confucius) findFiles() ([]string, error) {
+	if len(c.filePaths) > 0 {
+		return c.findExplicitFiles()
+	}
+
+	if name, ok := c.resolveFilename(); ok {
+		// the profiles and the conf.d dir are named after the file found.
+		filename := c.filename
+		c.filename = name
+		defer func() { c.filename = filename }()
+	}
+
 	c.initExpectedConfigFiles()
 
 	result := []string{}
@@ -148,29 +430,136 @@ func (c *confucius) findFiles() ([]string, error) {
 		return result, err
 	}
 	result = append(result, files...)
+
+	for _, pattern := range c.filePatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+	}
 	result = append(result, c.findLocalFiles()...)
 
-	if len(c.expectedConfigFiles) > 0 {
-		return nil, fmt.Errorf("\"%s\" file(s) not found: %w",
-			strings.Join(c.expectedConfigFiles, "\", \""),
-			ErrFileNotFound,
-		)
+	if len(c.expectedConfigFiles) > 0 && c.optionalFile {
+		for _, file := range c.expectedConfigFiles {
+			c.logger.Event(DebugLevel, map[string]interface{}{"file": file}, "optional file not found")
+		}
+	} else if len(c.expectedConfigFiles) > 0 {
+		return nil, &MissingFilesError{
+			Files: append([]string(nil), c.expectedConfigFiles...),
+			Dirs:  c.searchDirs(),
+		}
 	}
 
 	sort.StringSlice(result).Sort()
+	for _, file := range result {
+		c.logger.Event(DebugLevel, map[string]interface{}{"file": filePath(file)}, "file found")
+	}
+	return result, nil
+}
+
+// resolveFilename returns the name of the main file when it was given
+// without an extension, e.g. File("config"): the name followed by the
+// first of the extensionlessFormats for which a file exists in the dirs or
+// the file system. ok is false if the name has an extension or no such
+// file exists.
+func (c *confucius) resolveFilename() (name string, ok bool) {
+	if filepath.Ext(c.filename) != "" || len(c.filePatterns) > 0 {
+		return "", false
+	}
+	dirs := c.searchDirs()
+	for _, ext := range extensionlessFormats {
+		name := c.filename + string(ext)
+		for _, dir := range dirs {
+			if fileExists(filepath.Join(dir, name)) {
+				return name, true
+			}
+		}
+		if c.fsys != nil && c.fsysHasFile(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// fsysHasFile reports whether a file named name is in any dir of the file
+// system given with the EmbedFS or FileSystem options.
+func (c *confucius) fsysHasFile(name string) bool {
+	found := false
+	// the errors are ignored, they are reported when the files are searched.
+	_ = fs.WalkDir(c.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if found {
+			return fs.SkipDir
+		}
+		found = err == nil && !d.IsDir() && d.Name() == name
+		return nil
+	})
+	return found
+}
+
+// findExplicitFiles returns the files given with the FilePaths option, in
+// the order they were given, skipping the ones not found if they are
+// optional.
+func (c *confucius) findExplicitFiles() ([]string, error) {
+	result := []string{}
+	var missing []string
+	for i, path := range c.filePaths {
+		if !fileExists(path) {
+			missing = append(missing, path)
+			continue
+		}
+		result = append(result,
+			fmt.Sprintf("%s:%s_%04d=%s", LocalLocationIndicator, PartFileIndicator, i, path),
+		)
+		c.logger.Event(DebugLevel, map[string]interface{}{"file": path}, "file found")
+	}
+
+	if len(missing) > 0 && c.optionalFile {
+		for _, file := range missing {
+			c.logger.Event(DebugLevel, map[string]interface{}{"file": file}, "optional file not found")
+		}
+	} else if len(missing) > 0 {
+		return nil, &MissingFilesError{Files: missing}
+	}
 	return result, nil
 }
 
 func (c *confucius) findLocalFiles() (acc []string) {
 	found := map[string]bool{}
-	for _, dir := range c.dirs {
+	parts := 0
+	for _, dir := range c.localDirs() {
+		for _, pattern := range c.filePatterns {
+			// the error is ignored, the patterns are checked by findFiles.
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			sort.Strings(matches)
+			for _, path := range matches {
+				name, err := filepath.Rel(dir, path)
+				if err != nil || !fileExists(path) || found[name] {
+					continue
+				}
+				found[name] = true
+				c.removeFromExpectedList(pattern)
+				acc = append(acc,
+					fmt.Sprintf("%s:%s_%04d=%s", LocalLocationIndicator, PartFileIndicator, parts, path),
+				)
+				parts++
+			}
+		}
+
 		path := filepath.Join(dir, c.filename)
-		if fileExists(path) && !found[c.filename] {
+		if len(c.filePatterns) == 0 && fileExists(path) && !found[c.filename] {
 			found[c.filename] = true
 			c.removeFromExpectedList(c.filename)
 			acc = append(acc,
 				fmt.Sprintf("%s:%s=%s", LocalLocationIndicator, MainFileIndicator, path),
 			)
+
+			if c.autoConfD {
+				for _, part := range c.confDFiles(dir) {
+					acc = append(acc,
+						fmt.Sprintf("%s:%s_%04d=%s", LocalLocationIndicator, PartFileIndicator, parts, part),
+					)
+					parts++
+				}
+			}
 		}
 
 		for idx, profile := range c.profiles {
@@ -189,9 +578,80 @@ func (c *confucius) findLocalFiles() (acc []string) {
 	return
 }
 
+// localDirs returns the directories that files are loaded from. When
+// the first dir wins only the first dir containing the main file is
+// used, so that its profiles are not mixed with ones from other dirs.
+func (c *confucius) localDirs() []string {
+	dirs := c.searchDirs()
+	if !c.firstDirWins {
+		return dirs
+	}
+	for _, dir := range dirs {
+		if fileExists(filepath.Join(dir, c.filename)) {
+			return []string{dir}
+		}
+	}
+	return dirs
+}
+
+// confDFiles returns the files of the .d directory next to the main file
+// in dir, e.g. config.d/*.yaml for config.yaml, in lexical order.
+func (c *confucius) confDFiles(dir string) []string {
+	ext := filepath.Ext(c.filename)
+	confD := filepath.Join(dir, strings.TrimSuffix(c.filename, ext)+".d")
+
+	// os.ReadDir returns the entries sorted by name, a missing
+	// directory has no files.
+	entries, _ := os.ReadDir(confD)
+
+	var files []string
+	for _, entry := range entries {
+		if path := filepath.Join(confD, entry.Name()); filepath.Ext(path) == ext && fileExists(path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// searchDirs returns the directories that are searched for config files.
+// When searching recursively each dir is followed by its subdirectories,
+// breadth first, so that the shallowest file is found first. Symbolic
+// links to directories are not followed.
+func (c *confucius) searchDirs() []string {
+	if c.searchDepth <= 0 {
+		return c.dirs
+	}
+
+	var acc []string
+	for _, dir := range c.dirs {
+		level := []string{dir}
+		for depth := 0; len(level) > 0; depth++ {
+			acc = append(acc, level...)
+			if depth == c.searchDepth {
+				break
+			}
+
+			var next []string
+			for _, d := range level {
+				entries, err := os.ReadDir(d)
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					if entry.IsDir() {
+						next = append(next, filepath.Join(d, entry.Name()))
+					}
+				}
+			}
+			level = next
+		}
+	}
+	return acc
+}
+
 func (c *confucius) findEmbedFiles() (acc []string, err error) {
 	found := map[string]bool{}
-	if c.useEmbedFS {
+	if c.fsys != nil {
 		err = c.walkEmbedDir(&acc, found, ".")
 		if err != nil {
 			return
@@ -215,8 +675,10 @@ func (c confucius) fileExists(filename string) string {
 	return ""
 }
 
-func (c *confucius) walkEmbedDir(accumulator *[]string, found map[string]bool, path string) error {
-	entries, err := c.embedFS.ReadDir(path)
+// walkEmbedDir searches dir of the file system given with the EmbedFS or
+// FileSystem options, and its sub dirs, for the config files.
+func (c *confucius) walkEmbedDir(accumulator *[]string, found map[string]bool, dir string) error {
+	entries, err := fs.ReadDir(c.fsys, dir)
 	if err != nil {
 		return err
 	}
@@ -225,7 +687,8 @@ func (c *confucius) walkEmbedDir(accumulator *[]string, found map[string]bool, p
 	})
 
 	for _, entry := range entries {
-		fullPath := filepath.Join(path, entry.Name())
+		// the paths of a fs.FS are always separated by slashes.
+		fullPath := path.Join(dir, entry.Name())
 		if entry.IsDir() {
 			if err := c.walkEmbedDir(accumulator, found, fullPath); err != nil {
 				return err
@@ -235,7 +698,7 @@ func (c *confucius) walkEmbedDir(accumulator *[]string, found map[string]bool, p
 			c.removeFromExpectedList(entry.Name())
 			*accumulator = append(*accumulator, fmt.Sprintf("%s:%s=%s", EmbedLocationIndicator, tag, fullPath))
 		} else {
-			c.logger.Debug("file not found: %+v", fullPath)
+			c.logger.Event(DebugLevel, map[string]interface{}{"file": fullPath}, "skipping non-config file")
 		}
 	}
 	return nil
@@ -243,6 +706,9 @@ func (c *confucius) walkEmbedDir(accumulator *[]string, found map[string]bool, p
 
 func (c *confucius) initExpectedConfigFiles() {
 	c.expectedConfigFiles = []string{c.filename}
+	if len(c.filePatterns) > 0 {
+		c.expectedConfigFiles = append([]string(nil), c.filePatterns...)
+	}
 
 	for _, profile := range c.profiles {
 		c.expectedConfigFiles = append(c.expectedConfigFiles, c.profileFileName(profile))
@@ -261,18 +727,47 @@ func (c *confucius) removeFromExpectedList(file string) {
 }
 
 func (c *confucius) decodeEmbedFile(file string) (vals decodedObject, err error) {
-	fd, err := c.embedFS.Open(file)
+	fd, err := c.fsys.Open(file)
 	if err != nil {
 		return nil, err
 	}
 	defer fd.Close()
 
-	return c.decodeReader(fd, Decoder(filepath.Ext(file)))
+	return c.decodeReader(fd, Decoder(filepath.Ext(file)), "embed:"+file)
+}
+
+// decodeReaders decodes the readers given with the Reader and String
+// options, merging each one over the ones given before it.
+func (c *confucius) decodeReaders() (decodedObject, error) {
+	vals := make(decodedObject)
+	for i, src := range c.readers {
+		// readers can only be consumed once, keep their content around
+		// so that the configuration can be reloaded.
+		if src.content == nil {
+			content, err := c.readAll(src.reader)
+			if err != nil {
+				return nil, err
+			}
+			src.content = content
+		}
+
+		readerVals, err := c.decodeReader(bytes.NewReader(src.content), src.decoder, fmt.Sprintf("reader[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		if err := c.merge(&vals, readerVals); err != nil {
+			return nil, err
+		}
+	}
+	return vals, nil
 }
 
 func (c *confucius) decodeFiles(files []string, origin decodedObject) (vals decodedObject, err error) {
 	vals = origin
 	for _, file := range files {
+		if err := c.ctx.Err(); err != nil {
+			return nil, err
+		}
 		fileVals := decodedObject{}
 		sections := strings.Split(file, "=")
 
@@ -290,20 +785,119 @@ func (c *confucius) decodeFiles(files []string, origin decodedObject) (vals deco
 			}
 		}
 
-		if err := mergo.Merge(&vals, fileVals, mergo.WithOverride, mergo.WithTypeCheck); err != nil {
+		if err := c.merge(&vals, fileVals); err != nil {
 			return nil, err
 		}
+		c.logger.Event(DebugLevel, map[string]interface{}{"file": filePath(file), "action": "merge"}, "merge applied")
+		c.setFileSources(fileVals, "", fileSource(file))
 	}
 	return vals, nil
 }
 
+// merge merges the values of src over the ones of dst, as tuned by the
+// MergeSkipEmpty and MergeAppendSlice options.
+func (c *confucius) merge(dst *decodedObject, src decodedObject) error {
+	opts := []func(*mergo.Config){mergo.WithOverride, mergo.WithTypeCheck}
+	if c.mergeSkipEmpty {
+		src = decodedObject(withoutEmpty(src))
+	}
+	if c.mergeAppendSlice {
+		opts = append(opts, mergo.WithAppendSlice)
+	}
+	return mergo.Merge(dst, src, opts...)
+}
+
+// withoutEmpty returns a copy of m without its empty values, nulls, empty
+// strings and empty lists, including the ones of nested maps. Nested maps
+// left empty are removed too.
+func withoutEmpty(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		switch v := val.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				continue
+			}
+		case decodedObject:
+			if val = decodedObject(withoutEmpty(v)); len(val.(decodedObject)) == 0 {
+				continue
+			}
+		case map[string]interface{}:
+			if val = withoutEmpty(v); len(val.(map[string]interface{})) == 0 {
+				continue
+			}
+		}
+		result[key] = val
+	}
+	return result
+}
+
+// setProfiles sets fv to the active profiles. fv must be a []string,
+// or a string in which case the profiles are joined by commas.
+func (c *confucius) setProfiles(fv reflect.Value) error {
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(strings.Join(c.profiles, ","))
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		profiles := reflect.MakeSlice(fv.Type(), len(c.profiles), len(c.profiles))
+		for i, profile := range c.profiles {
+			profiles.Index(i).SetString(profile)
+		}
+		fv.Set(profiles)
+	default:
+		return fmt.Errorf("profile field must be a string or a []string, got %s", fv.Type())
+	}
+	return nil
+}
+
+// filePath returns the path of a file found by findFiles, without
+// its location and type indicators.
+func filePath(file string) string {
+	sections := strings.SplitN(file, "=", 2)
+	return sections[len(sections)-1]
+}
+
+// profileFileName returns the name of the file of profile, the profile
+// layout with the base name of the config file, e.g. config.override for
+// config.override.yaml, in place of {name}, the profile in place of {profile}
+// and the extension of the config file in place of {ext}. Layouts without
+// placeholders use the words config, test and yaml in their place instead.
+// The placeholders are replaced in a single pass so the names replacing
+// them are kept as is.
 func (c *confucius) profileFileName(profile string) string {
-	filename := c.profileLayout
-	parts := strings.Split(c.filename, ".")
-	filename = strings.ReplaceAll(filename, "config", parts[0])
-	filename = strings.ReplaceAll(filename, "test", profile)
-	filename = strings.ReplaceAll(filename, "yaml", parts[1])
-	return filename
+	ext := filepath.Ext(c.filename)
+	base := strings.TrimSuffix(c.filename, ext)
+	ext = strings.TrimPrefix(ext, ".")
+
+	if hasProfilePlaceholders(c.profileLayout) {
+		return strings.NewReplacer(
+			profilePlaceholderName, base,
+			profilePlaceholderProfile, profile,
+			profilePlaceholderExt, ext,
+		).Replace(c.profileLayout)
+	}
+	return strings.NewReplacer(
+		"config", base,
+		"test", profile,
+		"yaml", ext,
+	).Replace(c.profileLayout)
+}
+
+// hasProfilePlaceholders reports whether layout uses the placeholders of
+// profile layouts rather than the legacy config, test and yaml words.
+func hasProfilePlaceholders(layout string) bool {
+	for _, placeholder := range []string{profilePlaceholderName, profilePlaceholderProfile, profilePlaceholderExt} {
+		if strings.Contains(layout, placeholder) {
+			return true
+		}
+	}
+	return false
 }
 
 // decodeFile reads the file and unmarshalls // it using a decoder based on the file extension.
@@ -314,28 +908,86 @@ func (c *confucius) decodeFile(file string) (decodedObject, error) {
 	}
 	defer fd.Close()
 
-	return c.decodeReader(fd, Decoder(filepath.Ext(file)))
+	if info, err := fd.Stat(); err == nil && c.maxFileSize > 0 && info.Size() > c.maxFileSize {
+		return nil, fmt.Errorf("%s: %w: %d bytes exceeds the maximum of %d bytes", file, ErrFileTooLarge, info.Size(), c.maxFileSize)
+	}
+
+	return c.decodeReader(fd, Decoder(filepath.Ext(file)), file)
 }
 
-func (c *confucius) decodeReader(reader io.Reader, decoder Decoder) (decodedObject, error) {
+// decodeReader decodes the content of reader using decoder. name is the
+// name of the source the content is captured under, see CaptureRaw.
+func (c *confucius) decodeReader(reader io.Reader, decoder Decoder, name string) (decodedObject, error) {
 	vals := make(decodedObject)
 
+	data, err := c.readAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if c.captureRaw != nil {
+		c.captureRaw[name] = append([]byte(nil), data...)
+	}
+	if data, err = c.decodeText(data); err != nil {
+		return nil, err
+	}
+	if err := c.recordKeyOrder(data, decoder); err != nil {
+		return nil, err
+	}
+	reader = bytes.NewReader(data)
+
 	switch decoder {
 	case ".yaml", ".yml":
-		if err := yaml.NewDecoder(reader).Decode(&vals); err != nil {
-			return nil, err
+		// each document of the stream is merged over the ones before it.
+		dec := yaml.NewDecoder(reader)
+		for {
+			doc := make(decodedObject)
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			for field, val := range doc {
+				doc[field] = normalizeYAML(val)
+			}
+			if err := c.merge(&vals, doc); err != nil {
+				return nil, err
+			}
 		}
 	case ".json":
 		if err := json.NewDecoder(reader).Decode(&vals); err != nil {
 			return nil, err
 		}
+	case ".jsonc":
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(stripJSONC(data), &vals); err != nil {
+			return nil, err
+		}
+	case ".hcl":
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		var tree map[string]interface{}
+		if err := hcl.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		for field, val := range tree {
+			vals[field] = flattenHCLBlocks(val)
+		}
+	case ".ini":
+		return decodeINI(reader, false, c.flatKeySep)
+	case ".properties":
+		return decodeINI(reader, true, c.flatKeySep)
 	case ".toml":
 		tree, err := toml.LoadReader(reader)
 		if err != nil {
 			return nil, err
 		}
 		for field, val := range tree.ToMap() {
-			vals[field] = val
+			vals[field] = normalizeTOML(val)
 		}
 	default:
 		return nil, fmt.Errorf("unsupported file extension %s", filepath.Ext(c.filename))
@@ -344,17 +996,143 @@ func (c *confucius) decodeReader(reader io.Reader, decoder Decoder) (decodedObje
 	return vals, nil
 }
 
+// readAll reads reader until EOF. If a maximum file size is set then
+// an error is returned once more than that many bytes are read.
+func (c *confucius) readAll(reader io.Reader) ([]byte, error) {
+	reader = &contextReader{ctx: c.ctx, r: reader}
+	if c.maxFileSize <= 0 {
+		return io.ReadAll(reader)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, c.maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > c.maxFileSize {
+		return nil, fmt.Errorf("%w: exceeds the maximum of %d bytes", ErrFileTooLarge, c.maxFileSize)
+	}
+	return data, nil
+}
+
+// contextReader is a reader that fails with the error of ctx once ctx is
+// done, so that long reads stop promptly when loading is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// normalizeYAML converts the map[interface{}]interface{} objects decoded
+// by yaml into map[string]interface{} objects, like the ones decoded from
+// the other formats, so that free-form fields (e.g. []map[string]interface{})
+// hold the same values regardless of the file format.
+func normalizeYAML(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(elem)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = normalizeYAML(elem)
+		}
+		return v
+	}
+	return val
+}
+
+// normalizeTOML converts the local dates, times and datetimes decoded by
+// toml, which carry no offset, into time.Time values in UTC, like the
+// strings parsed with a layout without an offset, so that they populate
+// time.Time fields. A local time is placed on January 1 of year 0.
+func normalizeTOML(val interface{}) interface{} {
+	switch v := val.(type) {
+	case toml.LocalDateTime:
+		return v.In(time.UTC)
+	case toml.LocalDate:
+		return v.In(time.UTC)
+	case toml.LocalTime:
+		return time.Date(0, time.January, 1, v.Hour, v.Minute, v.Second, v.Nanosecond, time.UTC)
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = normalizeTOML(elem)
+		}
+	case []map[string]interface{}:
+		for _, elem := range v {
+			normalizeTOML(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = normalizeTOML(elem)
+		}
+	}
+	return val
+}
+
+// flattenHCLBlocks converts the blocks decoded by hcl, which are always
+// lists of objects, into nested maps. A list with more than one block
+// is kept as a list of maps.
+func flattenHCLBlocks(val interface{}) interface{} {
+	switch v := val.(type) {
+	case []map[string]interface{}:
+		if len(v) == 1 {
+			return flattenHCLBlocks(v[0])
+		}
+		list := make([]interface{}, len(v))
+		for i, block := range v {
+			list[i] = flattenHCLBlocks(block)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[key] = flattenHCLBlocks(elem)
+		}
+		return m
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = flattenHCLBlocks(elem)
+		}
+		return v
+	}
+	return val
+}
+
 // decodeMap decodes a map of va// lues into result using the mapstructure library.
 func (c *confucius) decodeMap(m decodedObject, result interface{}) error {
+	var hooks []mapstructure.DecodeHookFunc
+	if !c.disableEnvExpansion || c.secretFileExpansion {
+		hooks = append(hooks, fromEnvironmentHookFunc(c.expandEnv))
+	}
+	hooks = append(hooks,
+		stringToDurationHookFunc(c.parseDuration),
+		stringToTimeHookFunc(c.parseTime),
+		c.bytesHookFunc(),
+		bigHookFunc(),
+		quantityHookFunc(),
+		byteSizeHookFunc(),
+		c.binaryUnmarshalerHookFunc(),
+	)
+	if c.unixTime {
+		hooks = append(hooks, unixTimeHookFunc())
+	}
+	hooks = append(hooks, c.decodeHooks...)
+	hooks = append(hooks, numberRangeHookFunc())
+	hooks = append(hooks, unmarshalerHookFunc(c.expandEnv), configDecoderHookFunc(c.expandEnv), rawMessageHookFunc(c.expandEnv))
+
 	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		WeaklyTypedInput: true,
 		Result:           result,
 		TagName:          c.tag,
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(
-			fromEnvironmentHookFunc(),
-			mapstructure.StringToTimeDurationHookFunc(),
-			mapstructure.StringToTimeHookFunc(c.timeLayout),
-		),
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(hooks...),
 	})
 	if err != nil {
 		return err
@@ -370,6 +1148,10 @@ func replaceEnvironments(str string) (result string, err error) {
 		if value == "" {
 			return result, fmt.Errorf("environment name is missing")
 		}
+		if configRefPattern.MatchString(whole) {
+			// references to other keys are resolved by InterpolateConfig.
+			continue
+		}
 
 		s := strings.Split(value, ":")
 
@@ -388,7 +1170,38 @@ func replaceEnvironments(str string) (result string, err error) {
 	return result, err
 }
 
-func fromEnvironmentHookFunc() mapstructure.DecodeHookFunc {
+// expandEnv replaces the environment variable references in val, unless
+// env expansion is disabled, and then reads the secret file val refers
+// to when secret file expansion is enabled.
+func (c *confucius) expandEnv(val string) (string, error) {
+	if !c.disableEnvExpansion {
+		var err error
+		if val, err = replaceEnvironments(val); err != nil {
+			return val, err
+		}
+	}
+	if c.secretFileExpansion {
+		return readSecretFile(val)
+	}
+	return val, nil
+}
+
+// readSecretFile returns the content of the file val refers to in the
+// form file:/run/secrets/name, without its trailing newlines. Any other
+// val is returned as is.
+func readSecretFile(val string) (string, error) {
+	if !strings.HasPrefix(val, secretFilePrefix) {
+		return val, nil
+	}
+	path := strings.TrimPrefix(val, secretFilePrefix)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func fromEnvironmentHookFunc(expand func(string) (string, error)) mapstructure.DecodeHookFunc {
 	return func(
 		f reflect.Type,
 		t reflect.Type,
@@ -397,20 +1210,246 @@ func fromEnvironmentHookFunc() mapstructure.DecodeHookFunc {
 			return data, nil
 		}
 
-		return replaceEnvironments(data.(string))
+		return expand(data.(string))
+	}
+}
+
+// parseTime parses val using the configured time layouts, returning the
+// time of the first layout that matches.
+func (c *confucius) parseTime(val string) (time.Time, error) {
+	if c.unixTime {
+		if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return unixTime(i), nil
+		}
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return unixTimeFloat(f), nil
+		}
 	}
+
+	for _, layout := range c.timeLayouts {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q with layouts %q", val, c.timeLayouts)
+}
+
+// unixTime converts a Unix timestamp into a time. The unit of the
+// timestamp (seconds, milliseconds, microseconds or nanoseconds) is
+// detected from its magnitude.
+func unixTime(ts int64) time.Time {
+	return time.Unix(0, ts*int64(unixTimeUnit(float64(ts)))).UTC()
+}
+
+// unixTimeFloat converts a Unix timestamp with a fractional part into
+// a time. Like unixTime the unit is detected from its magnitude.
+func unixTimeFloat(ts float64) time.Time {
+	unit := unixTimeUnit(ts)
+	whole, frac := math.Modf(ts)
+	return time.Unix(0, int64(whole)*int64(unit)+int64(frac*float64(unit))).UTC()
+}
+
+// unixTimeUnit returns the unit of a Unix timestamp based on its magnitude.
+func unixTimeUnit(ts float64) time.Duration {
+	switch abs := math.Abs(ts); {
+	case abs >= 1e17:
+		return time.Nanosecond
+	case abs >= 1e14:
+		return time.Microsecond
+	case abs >= 1e11:
+		return time.Millisecond
+	default:
+		return time.Second
+	}
+}
+
+// unixTimeHookFunc returns a DecodeHookFunc that converts numbers
+// to time.Time by interpreting them as Unix timestamps.
+func unixTimeHookFunc() mapstructure.DecodeHookFunc {
+	return func(
+		f reflect.Type,
+		t reflect.Type,
+		data interface{}) (interface{}, error) {
+		if t != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+
+		v := reflect.ValueOf(data)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return unixTime(v.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return unixTime(int64(v.Uint())), nil
+		case reflect.Float32, reflect.Float64:
+			return unixTimeFloat(v.Float()), nil
+		}
+		return data, nil
+	}
+}
+
+// stringToTimeHookFunc returns a DecodeHookFunc that converts strings
+// to time.Time using parse.
+func stringToTimeHookFunc(parse func(string) (time.Time, error)) mapstructure.DecodeHookFunc {
+	return func(
+		f reflect.Type,
+		t reflect.Type,
+		data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+
+		return parse(data.(string))
+	}
+}
+
+// isByteSlice reports whether t is a []byte holding binary data, given
+// encoded with the ByteEncoding option. json.RawMessage and the types
+// parsing their own text, e.g. net.IP, are not.
+func isByteSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 &&
+		t != rawMessageType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// bytesHookFunc returns a DecodeHookFunc that decodes the strings given to
+// a []byte with the encoding set with the ByteEncoding option, like the
+// values of the environment and the defaults.
+func (c *confucius) bytesHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || !isByteSlice(t) {
+			return data, nil
+		}
+		return c.byteEncoding.DecodeString(data.(string))
+	}
+}
+
+// numberRangeHookFunc returns a hook that rejects the numbers decoded from
+// the config files that are out of the range of the sized int, uint and
+// float fields they are given to, e.g. 300 for an int8 or -1 for a uint,
+// which would otherwise wrap around or become infinite.
+func numberRangeHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		kind := numberKind(f.Kind())
+		if data == nil || kind == reflect.Invalid {
+			return data, nil
+		}
+		v, zero := reflect.ValueOf(data), reflect.Zero(t)
+
+		var overflows bool
+		switch numberKind(t.Kind()) {
+		case reflect.Int:
+			switch kind {
+			case reflect.Int:
+				overflows = zero.OverflowInt(v.Int())
+			case reflect.Uint:
+				overflows = v.Uint() > math.MaxInt64 || zero.OverflowInt(int64(v.Uint()))
+			case reflect.Float64:
+				overflows = v.Float() < math.MinInt64 || v.Float() >= math.MaxInt64 || zero.OverflowInt(int64(v.Float()))
+			}
+		case reflect.Uint:
+			switch kind {
+			case reflect.Int:
+				overflows = v.Int() < 0 || zero.OverflowUint(uint64(v.Int()))
+			case reflect.Uint:
+				overflows = zero.OverflowUint(v.Uint())
+			case reflect.Float64:
+				overflows = v.Float() < 0 || v.Float() >= math.MaxUint64 || zero.OverflowUint(uint64(v.Float()))
+			}
+		case reflect.Float64:
+			if kind == reflect.Float64 {
+				overflows = zero.OverflowFloat(v.Float())
+			}
+		}
+		if overflows {
+			return nil, rangeError(data, t, strconv.ErrRange)
+		}
+		return data, nil
+	}
+}
+
+// rangeError describes err, an error parsing or decoding val into a value
+// of type t, when val is out of the range of t, e.g. "300 is out of range
+// for int8". Other errors are returned as is.
+func rangeError(val interface{}, t reflect.Type, err error) error {
+	if !errors.Is(err, strconv.ErrRange) {
+		return err
+	}
+	return fmt.Errorf("%v is out of range for %v: %w", val, t, strconv.ErrRange)
+}
+
+// numberKind returns reflect.Int, reflect.Uint or reflect.Float64 if k is
+// the kind of an int, uint or float of any size, reflect.Invalid otherwise.
+func numberKind(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return reflect.Invalid
 }
 
 // processCfg processes a cfg struct after it has been loaded from
 // the config file, by validating required fields and setting defaults
 // where applicable.
 func (c *confucius) processCfg(cfg interface{}) error {
+	if c.useEnv && !c.disableEnvSliceBind {
+		c.growEnvSlices(cfg)
+	}
+
 	fields := flattenCfg(cfg, c.tag)
 	errs := make(fieldErrors)
 
 	for _, field := range fields {
+		if origin, ok := c.fileSources[strings.ToLower(field.path())]; ok {
+			c.setSource(field.path(), origin)
+		}
+
+		if field.unexported {
+			if c.requireExportedFields {
+				errs[field.path()] = fmt.Errorf("unexported field cannot be set")
+				if c.failFast {
+					return errs
+				}
+			}
+			continue
+		}
+
 		if err := c.processField(field); err != nil {
+			c.logger.Event(ErrorLevel, map[string]interface{}{"field": field.path(), "error": err.Error()}, "validation failed")
 			errs[field.path()] = err
+			if c.failFast {
+				return errs
+			}
+		}
+	}
+
+	// rules depending on siblings are checked once every field is set.
+	for _, field := range fields {
+		if _, ok := errs[field.path()]; ok || field.unexported {
+			continue
+		}
+		for _, rule := range field.validations {
+			if !isSiblingRule(rule) {
+				continue
+			}
+			if err := validateSiblingRule(field, rule, c.tag); err != nil {
+				c.logger.Event(ErrorLevel, map[string]interface{}{"field": field.path(), "error": err.Error()}, "validation failed")
+				errs[field.path()] = err
+				if c.failFast {
+					return errs
+				}
+				break
+			}
+		}
+		for _, rule := range field.warnings {
+			if !isSiblingRule(rule) {
+				continue
+			}
+			if err := validateSiblingRule(field, rule, c.tag); err != nil {
+				c.warnValidation(field, err)
+			}
 		}
 	}
 
@@ -428,33 +1467,292 @@ func (c *confucius) processField(field *field) error {
 		return fmt.Errorf("field cannot have both a required validation and a default value")
 	}
 
-	if c.useEnv {
-		if err := c.setFromEnv(field.v, field.path()); err != nil {
-			return fmt.Errorf("unable to set from env: %v", err)
+	if field.setDefault && field.setFallback {
+		return fmt.Errorf("field cannot have both a default and a fallback value")
+	}
+
+	if field.profile {
+		return c.setProfiles(field.v)
+	}
+
+	if c.keychainService != "" && field.keychain != "" {
+		if err := c.setFromKeychain(field.v, field.structTag, field.path()); err != nil {
+			return fmt.Errorf("unable to set from keychain: %w", err)
 		}
 	}
 
+	var old string
+	if c.diagnostics {
+		old = c.diagnosedValue(field)
+	}
+
+	if c.useEnv && !(c.disableEnvSliceBind && field.inSlice()) {
+		fv := field.v
+		if field.setEmpty && field.orig.Kind() == reflect.Ptr {
+			// the empty sentinel resets the pointer rather than the value it points to.
+			fv = field.orig
+		}
+		st := field.structTag
+		st.env = field.envName()
+		if err := c.setFromEnv(fv, st, field.path()); err != nil {
+			return fmt.Errorf("unable to set from env: %w", err)
+		}
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			// the steps below see the reset pointer, not its old value.
+			field.v = fv
+		}
+		old = c.logChange(field, "env", old)
+	}
+
+	if field.setFallback && isZero(field.v) {
+		if err := c.setDefaultValue(field.v, field.structTag, field.fallbackVal); err != nil {
+			return fmt.Errorf("unable to set fallback: %w", err)
+		}
+		c.setSource(field.path(), "fallback")
+		old = c.logChange(field, "fallback", old)
+	}
+
+	if field.required && isZero(field.v) && c.prompter != nil {
+		if err := c.promptField(field); err != nil {
+			return fmt.Errorf("unable to prompt: %w", err)
+		}
+		old = c.logChange(field, "prompt", old)
+	}
+
 	if field.required && isZero(field.v) {
-		return fmt.Errorf("required validation failed")
+		return fmt.Errorf("%s validation failed", validateRequired)
+	}
+
+	if !field.setDefault && (isZero(field.v) || (field.v.Kind() == reflect.Struct && field.v.IsZero())) {
+		ok, err := setRegisteredDefault(field.v)
+		if err != nil {
+			return fmt.Errorf("unable to set default: %w", err)
+		}
+		if ok {
+			c.setSource(field.path(), "default")
+			old = c.logChange(field, "default", old)
+		}
 	}
 
 	if field.setDefault && isZero(field.v) {
-		if err := c.setDefaultValue(field.v, field.defaultVal); err != nil {
-			return fmt.Errorf("unable to set default: %v", err)
+		if err := c.setDefaultValue(field.v, field.structTag, field.defaultVal); err != nil {
+			if !c.ignoreBadDefaults {
+				return fmt.Errorf("unable to set default: %w", err)
+			}
+			c.logger.Event(WarningLevel, map[string]interface{}{"field": field.path(), "error": err.Error()}, "bad default ignored")
+			field.v.Set(reflect.Zero(field.v.Type()))
+		} else {
+			c.setSource(field.path(), "default")
+			c.logChange(field, "default", old)
+		}
+	}
+
+	for _, rule := range field.validations {
+		if isSiblingRule(rule) {
+			continue
+		}
+		if err := validateRule(field.v, rule); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range field.warnings {
+		if !isRule(rule) {
+			// a misspelled rule would otherwise only ever be logged.
+			return fmt.Errorf("unknown validation %s", rule)
+		}
+		if isSiblingRule(rule) {
+			continue
+		}
+		var err error
+		switch {
+		case rule != validateRequired:
+			err = validateRule(field.v, rule)
+		case isZero(field.v):
+			err = fmt.Errorf("%s validation failed", validateRequired)
+		}
+		if err != nil {
+			c.warnValidation(field, err)
 		}
 	}
 
 	return nil
 }
 
-func (c *confucius) setFromEnv(fv reflect.Value, key string) error {
-	key = c.formatEnvKey(key)
-	if val, ok := os.LookupEnv(key); ok {
-		return c.setValue(fv, val)
+// warnValidation logs the failure of a rule of the warn key of field, which
+// does not fail Load.
+func (c *confucius) warnValidation(field *field, err error) {
+	c.logger.Event(WarningLevel, map[string]interface{}{"field": field.path(), "error": err.Error()}, "validation warning")
+}
+
+func (c *confucius) setFromEnv(fv reflect.Value, st structTag, path string) error {
+	key := c.envKey(path, st)
+	c.bindEnv(key)
+	if fv.Kind() == reflect.Map {
+		return c.setMapFromEnv(fv, st, path, key)
+	}
+	if val, ok := os.LookupEnv(key); ok && c.envOverrides(path) {
+		val, err := c.expandEnv(val)
+		if err != nil {
+			return err
+		}
+		c.setSource(path, "env:"+key)
+		if st.isEmpty(val) {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		return c.setFormattedValue(fv, st, val)
+	}
+	return nil
+}
+
+// setMapFromEnv sets the entries of the map fv from the environment
+// variables named KEY_<entry>. The entry's key is taken verbatim from
+// the variable's name, keeping its case and underscores:
+//
+//   LABELS_tier=1         --->   map[string]int{"tier": 1}
+//   LABELS_ENV_prod=1     --->   map[string]int{"ENV_prod": 1}
+//
+// Only maps with string keys and values that can be set from a string
+// are supported, other maps are left untouched.
+func (c *confucius) setMapFromEnv(fv reflect.Value, st structTag, path, key string) error {
+	if fv.Type().Key().Kind() != reflect.String || !isEnvSettable(fv.Type().Elem()) {
+		return nil
+	}
+
+	prefix := key + "_"
+	c.bindEnv(prefix)
+	for _, env := range os.Environ() {
+		name, val := env, ""
+		if i := strings.Index(env, "="); i >= 0 {
+			name, val = env[:i], env[i+1:]
+		}
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		if !c.envOverrides(path + "." + name[len(prefix):]) {
+			continue
+		}
+
+		val, err := c.expandEnv(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if st.isEmpty(val) {
+			continue
+		}
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if err := c.setFormattedValue(elem, st, val); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(fv.Type()))
+		}
+		mk := reflect.ValueOf(name[len(prefix):]).Convert(fv.Type().Key())
+		fv.SetMapIndex(mk, elem)
+		c.setSource(path+"."+mk.String(), "env:"+name)
 	}
 	return nil
 }
 
+// growEnvSlices grows the slices of structs of cfg to hold the elements
+// set from the environment, e.g. Servers to 2 elements with MYAPP_SERVERS_1_HOST,
+// so that the fields of elements that are not in the config files can be
+// set too. The slices of the new elements are grown in turn.
+func (c *confucius) growEnvSlices(cfg interface{}) {
+	for grown := true; grown; {
+		grown = false
+		for _, field := range flattenCfg(cfg, c.tag) {
+			if !field.unexported && c.growEnvSlice(field) {
+				grown = true
+			}
+		}
+	}
+}
+
+// growEnvSlice appends zero elements to the slice of structs, or of
+// pointers to structs, of field up to the highest index used by the
+// environment variables of its elements. It reports whether it grew.
+func (c *confucius) growEnvSlice(field *field) bool {
+	if field.v.Kind() != reflect.Slice {
+		return false
+	}
+	elem := field.t.Elem()
+	if elem.Kind() != reflect.Struct && !(elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct) {
+		return false
+	}
+
+	// the elements are named after the slice, e.g. SERVERS_0_HOST.
+	st := field.structTag
+	st.env = ""
+	if field.env == "" {
+		st.env = field.envName()
+	}
+	prefix := c.envKey(field.path(), st) + "_"
+
+	n := field.v.Len()
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, prefix) {
+			continue
+		}
+		rest := env[len(prefix):]
+		end := strings.Index(rest, "_")
+		if end <= 0 {
+			continue
+		}
+		idx, err := strconv.Atoi(rest[:end])
+		if err != nil || idx < 0 || idx >= maxEnvSliceLen {
+			continue
+		}
+		if idx >= n {
+			n = idx + 1
+		}
+	}
+	if n == field.v.Len() {
+		return false
+	}
+
+	grown := reflect.MakeSlice(field.t, n, n)
+	reflect.Copy(grown, field.v)
+	if elem.Kind() == reflect.Ptr {
+		for i := field.v.Len(); i < n; i++ {
+			grown.Index(i).Set(reflect.New(elem.Elem()))
+		}
+	}
+	field.v.Set(grown)
+	return true
+}
+
+// isEnvSettable reports whether a value of type t can be set from
+// the string value of an environment variable.
+func isEnvSettable(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Kind() == reflect.Slice && isEnvSettable(t.Elem())
+	case reflect.Struct:
+		return t == reflect.TypeOf(time.Time{}) || isBig(t) || t == quantityType || isBinaryUnmarshaler(t)
+	case reflect.Map, reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// envKey returns the name of the environment variable of the field at
+// path. The name pinned with the env tag is used as is, prefixed only
+// when the PrefixEnvTags option is used.
+func (c *confucius) envKey(path string, st structTag) string {
+	if st.env == "" {
+		return c.formatEnvKey(path)
+	}
+	if c.prefixEnvTags && c.envPrefix != "" {
+		return strings.ToUpper(c.envPrefix) + "_" + st.env
+	}
+	return st.env
+}
+
 func (c *confucius) formatEnvKey(key string) string {
 	// loggers[0].level --> loggers_0_level
 	key = strings.NewReplacer(".", "_", "[", "_", "]", "").Replace(key)
@@ -465,12 +1763,17 @@ func (c *confucius) formatEnvKey(key string) string {
 }
 
 // setDefaultValue calls setValue but disallows booleans from
-// being set.
-func (c *confucius) setDefaultValue(fv reflect.Value, val string) error {
+// being set. The environment variables referenced by val, e.g.
+// ${HOME}/config, are expanded first like in the config files.
+func (c *confucius) setDefaultValue(fv reflect.Value, st structTag, val string) error {
 	if fv.Kind() == reflect.Bool {
 		return fmt.Errorf("unsupported type: %v", fv.Kind())
 	}
-	return c.setValue(fv, val)
+	val, err := c.expandEnv(val)
+	if err != nil {
+		return err
+	}
+	return c.setFormattedValue(fv, st, val)
 }
 
 // setValue sets fv to val. it attempts to convert val to the correct
@@ -478,6 +1781,10 @@ func (c *confucius) setDefaultValue(fv reflect.Value, val string) error {
 // returned.
 // fv must be settable else this panics.
 func (c *confucius) setValue(fv reflect.Value, val string) error {
+	if fv.CanAddr() && isBinaryUnmarshaler(fv.Type()) {
+		return c.setBinary(fv, val)
+	}
+
 	switch fv.Kind() {
 	case reflect.Ptr:
 		if fv.IsNil() {
@@ -485,6 +1792,22 @@ func (c *confucius) setValue(fv reflect.Value, val string) error {
 		}
 		return c.setValue(fv.Elem(), val)
 	case reflect.Slice:
+		if fv.Type() == rawMessageType {
+			if !json.Valid([]byte(val)) {
+				return fmt.Errorf("invalid json %q", val)
+			}
+			fv.SetBytes([]byte(val))
+			return nil
+		}
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			// binary data such as keys is given base64 encoded.
+			b, err := c.byteEncoding.DecodeString(val)
+			if err != nil {
+				return err
+			}
+			fv.SetBytes(b)
+			return nil
+		}
 		if err := c.setSlice(fv, val); err != nil {
 			return err
 		}
@@ -496,35 +1819,52 @@ func (c *confucius) setValue(fv reflect.Value, val string) error {
 		fv.SetBool(b)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if _, ok := fv.Interface().(time.Duration); ok {
-			d, err := time.ParseDuration(val)
+			d, err := c.parseDuration(val)
 			if err != nil {
 				return err
 			}
 			fv.Set(reflect.ValueOf(d))
 		} else {
-			i, err := strconv.ParseInt(val, 10, 64)
+			i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
 			if err != nil {
-				return err
+				return rangeError(val, fv.Type(), err)
 			}
 			fv.SetInt(i)
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		i, err := strconv.ParseUint(val, 10, 64)
+		if fv.Type() == byteSizeType {
+			return setByteSize(fv, val)
+		}
+		i, err := strconv.ParseUint(val, 10, fv.Type().Bits())
 		if err != nil {
-			return err
+			return rangeError(val, fv.Type(), err)
 		}
 		fv.SetUint(i)
 	case reflect.Float32, reflect.Float64:
-		f, err := strconv.ParseFloat(val, 64)
+		// parse using the size of the field, rounding to a float64 first
+		// may round a float32 differently and hides values out of its range.
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
 		if err != nil {
-			return err
+			return rangeError(val, fv.Type(), err)
 		}
 		fv.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		n, err := strconv.ParseComplex(val, fv.Type().Bits())
+		if err != nil {
+			return rangeError(val, fv.Type(), err)
+		}
+		fv.SetComplex(n)
 	case reflect.String:
 		fv.SetString(val)
-	case reflect.Struct: // struct is only allowed a default in the special case where it's a time.Time
+	case reflect.Struct: // struct is only allowed a default in the special case where it's a time.Time, a big number or a quantity
+		if isBig(fv.Type()) {
+			return setBig(fv, val)
+		}
+		if fv.Type() == quantityType {
+			return setQuantity(fv, val)
+		}
 		if _, ok := fv.Interface().(time.Time); ok {
-			t, err := time.Parse(c.timeLayout, val)
+			t, err := c.parseTime(val)
 			if err != nil {
 				return err
 			}
@@ -543,7 +1883,7 @@ func (c *confucius) setValue(fv reflect.Value, val string) error {
 // to a slice fails then an error is returned.
 // sv must be settable else this panics.
 func (c *confucius) setSlice(sv reflect.Value, val string) error {
-	ss := stringSlice(val)
+	ss := stringSlice(val, c.sliceDelimiter)
 	slice := reflect.MakeSlice(sv.Type(), len(ss), cap(ss))
 	for i, s := range ss {
 		if err := c.setValue(slice.Index(i), s); err != nil {
@@ -2,14 +2,23 @@ package confucius
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -142,7 +151,7 @@ func validPodConfig() Pod {
 var embedFS embed.FS
 
 func Test_confucius_Load(t *testing.T) {
-	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
+	for _, f := range []string{"pod.yaml", "pod.json", "pod.jsonc", "pod.toml", "pod.hcl"} {
 		t.Run(f, func(t *testing.T) {
 			var cfg Pod
 			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
@@ -159,6 +168,51 @@ func Test_confucius_Load(t *testing.T) {
 	}
 }
 
+func Test_confucius_Load_TOML(t *testing.T) {
+	var cfg struct {
+		Created    time.Time   `conf:"created"`
+		Updated    *time.Time  `conf:"updated"`
+		Release    time.Time   `conf:"release"`
+		Window     time.Time   `conf:"window"`
+		Containers []Container `conf:"containers"`
+	}
+	err := Load(&cfg, File("containers.toml"), Dirs(filepath.Join("testdata", "valid")))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		got, want time.Time
+	}{
+		"created": {cfg.Created, time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60))},
+		"updated": {*cfg.Updated, time.Date(1979, 5, 27, 7, 32, 0, 5e8, time.UTC)},
+		"release": {cfg.Release, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)},
+		"window":  {cfg.Window, time.Date(0, 1, 1, 7, 30, 0, 0, time.UTC)},
+	} {
+		if !tc.got.Equal(tc.want) {
+			t.Errorf("%s: want %v, got %v", name, tc.want, tc.got)
+		}
+	}
+
+	want := []Container{
+		{
+			Name:    "redis",
+			Image:   "redis:5.0.4",
+			Command: []string{"redis-server", "/redis-master/redis.conf"},
+			Env:     []Env{{Name: "MASTER", Value: "true"}},
+			Ports:   []Port{{ContainerPort: 6379}},
+		},
+		{
+			Name:  "sidecar",
+			Image: "envoy:1.17",
+			Ports: []Port{{ContainerPort: 9901}, {ContainerPort: 10000}},
+		},
+	}
+	if !reflect.DeepEqual(want, cfg.Containers) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg.Containers)
+	}
+}
+
 func Test_confucius_findFiles(t *testing.T) {
 	t.Run("happy path", func(t *testing.T) {
 		var cfg Pod
@@ -225,216 +279,1428 @@ func Test_confucius_findLocalFiles(t *testing.T) {
 	}
 }
 
-func Test_confucius_findEmbedFiles(t *testing.T) {
-	conf := defaultConfucius()
-	conf.useEmbedFS = true
-	conf.embedFS = embedFS
-	conf.filename = "pod.yaml"
-
-	if acc, err := conf.findEmbedFiles(); err != nil {
-		t.Fatalf("expected err: %+v", err)
-	} else if !reflect.DeepEqual(acc, []string{"#embed:#main=testdata/embed/pod.yaml"}) {
-		t.Fatalf("inconsistent: %+v", acc)
+func Test_confucius_Load_ProfileField(t *testing.T) {
+	type Server struct {
+		Host     string   `conf:"host"`
+		Profiles []string `conf:",profile"`
+		Profile  string   `conf:"active,profile"`
 	}
-}
 
-func Test_confucius_fileExists(t *testing.T) {
-	conf := defaultConfucius()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: localhost\nactive: from-file")
+	writeFile(t, filepath.Join(dir, "config.dev.yaml"), "host: dev")
+	writeFile(t, filepath.Join(dir, "config.eu.yaml"), "host: eu")
 
-	t.Run("main", func(t *testing.T) {
-		if tag := conf.fileExists("config.yaml"); tag != "#main" {
-			t.Fatal("main not found")
+	t.Run("single profile", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir), Profiles("dev")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Server{Host: "dev", Profiles: []string{"dev"}, Profile: "dev"}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 
-	t.Run("profiles", func(t *testing.T) {
-		conf.profiles = []string{"e2e"}
-
-		if tag := conf.fileExists("config.e2e.yaml"); tag != "#profile_00_e2e" {
-			t.Fatal("not found in profiles")
+	t.Run("multiple profiles", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir), Profiles("dev", "eu")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Server{Host: "eu", Profiles: []string{"dev", "eu"}, Profile: "dev,eu"}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 
-	t.Run("profiles with order", func(t *testing.T) {
-		conf.profiles = []string{"e2e", "uat"}
-
-		if tag := conf.fileExists("config.e2e.yaml"); tag != "#profile_00_e2e" {
-			t.Fatal("not found in profiles")
+	t.Run("no profiles", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
 		}
-
-		if tag := conf.fileExists("config.uat.yaml"); tag != "#profile_01_uat" {
-			t.Fatal("not found in profiles")
+		want := Server{Host: "localhost", Profiles: []string{}, Profile: ""}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 
-	t.Run("not found in main and profiles", func(t *testing.T) {
-		if tag := conf.fileExists("pod.yaml"); tag != "" {
-			t.Fatal("file not found")
+	t.Run("unsupported type", func(t *testing.T) {
+		var cfg struct {
+			Profile int `conf:",profile"`
+		}
+		err := Load(&cfg, Dirs(dir), Profiles("dev"))
+		if _, ok := err.(fieldErrors)["Profile"]; !ok {
+			t.Fatalf("want Profile in fieldErrors, got %+v", err)
 		}
 	})
 }
 
-func Test_confucius_walkEmbedDir(t *testing.T) {
-	conf := defaultConfucius()
-	conf.useEmbedFS = true
-	conf.embedFS = embedFS
-	conf.filename = "pod.yaml"
-	conf.profiles = []string{"dev", "e2e"}
-
-	accumulator := []string{}
-	found := map[string]bool{}
+func Test_confucius_Load_FirstDirWins(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
 
-	t.Run("success", func(t *testing.T) {
-		err := conf.walkEmbedDir(&accumulator, found, ".")
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(dir1, "config.yaml"), "host: dir1\nport: 80")
+	writeFile(t, filepath.Join(dir2, "config.yaml"), "host: dir2\nport: 81")
+	writeFile(t, filepath.Join(dir2, "config.test.yaml"), "port: 8080")
 
-		if err != nil {
-			t.Fatalf("not expected: %+v", err)
+	t.Run("profiles are found across dirs by default", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir1, dir2), Profiles("test")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Server{Host: "dir1", Port: 8080}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
+	})
 
-		expected := []string{
-			"#embed:#main=testdata/embed/pod.yaml",
-			"#embed:#profile_01_e2e=testdata/embed/pod.e2e.yaml",
+	t.Run("stray profile is ignored", func(t *testing.T) {
+		var cfg Server
+		err := Load(&cfg, Dirs(dir1, dir2), Profiles("test"), FirstDirWins())
+		if !errors.Is(err, ErrFileNotFound) {
+			t.Fatalf("want err %v, got %v", ErrFileNotFound, err)
 		}
+		if !strings.Contains(err.Error(), "config.test.yaml") {
+			t.Fatalf("want config.test.yaml reported, got %v", err)
+		}
+	})
 
-		if !reflect.DeepEqual(accumulator, expected) {
-			t.Fatal("accumulator not match")
+	t.Run("profile of first dir is used", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir1, "config.dev.yaml"), "port: 8000")
+		writeFile(t, filepath.Join(dir2, "config.dev.yaml"), "host: stray")
+
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir1, dir2), Profiles("dev"), FirstDirWins()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Server{Host: "dir1", Port: 8000}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 
-	t.Run("when embed file is not read", func(t *testing.T) {
-		err := conf.walkEmbedDir(&accumulator, found, "bad-path")
-		if err == nil {
-			t.Fatal("not expected")
+	t.Run("main file in later dir", func(t *testing.T) {
+		empty := t.TempDir()
+		writeFile(t, filepath.Join(empty, "config.test.yaml"), "port: 9090")
+
+		var cfg Server
+		if err := Load(&cfg, Dirs(empty, dir2), Profiles("test"), FirstDirWins()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Server{Host: "dir2", Port: 8080}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 }
 
-func Test_confucius_initExpectedConfigFiles(t *testing.T) {
-	conf := defaultConfucius()
-	conf.profiles = []string{"e2e", "dev", "uat"}
-
-	if len(conf.expectedConfigFiles) != 0 {
-		t.Fatal("it should empty")
+func Test_confucius_Load_MultipleReaders(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		} `conf:"server"`
+		Level string   `conf:"level"`
+		Tags  []string `conf:"tags"`
 	}
 
-	conf.initExpectedConfigFiles()
+	base := `{server: {host: base, port: 80}, level: info, tags: [a, b]}`
+	overlay := `{"server": {"port": 8080}, "tags": ["c"]}`
 
-	if len(conf.expectedConfigFiles) != 4 {
-		t.Fatal("unexpected file count")
-	}
+	t.Run("second overlays first", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(base, DecoderYaml), Reader(strings.NewReader(overlay), DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Server.Host != "base" || cfg.Server.Port != 8080 || cfg.Level != "info" || !reflect.DeepEqual([]string{"c"}, cfg.Tags) {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
 
-	if !reflect.DeepEqual(conf.expectedConfigFiles, []string{
-		"config.yaml", "config.e2e.yaml", "config.dev.yaml", "config.uat.yaml",
-	}) {
-		t.Fatalf("inconsistent config files %+v", conf.expectedConfigFiles)
-	}
-}
+	t.Run("files override readers", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.yaml"), "server: {host: file}")
 
-func Test_confucius_removeFromExpectedList(t *testing.T) {
-	conf := defaultConfucius()
-	conf.initExpectedConfigFiles()
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), String(base, DecoderYaml), String(overlay, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Server.Host != "file" || cfg.Server.Port != 8080 || cfg.Level != "info" {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
 
-	conf.removeFromExpectedList("config.yaml")
+	t.Run("reload", func(t *testing.T) {
+		loader := New(String(base, DecoderYaml), String(overlay, DecoderJSON))
+		for i := 0; i < 2; i++ {
+			var cfg Config
+			if err := loader.Reload(&cfg); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if cfg.Server.Host != "base" || cfg.Server.Port != 8080 {
+				t.Fatalf("unexpected config %+v", cfg)
+			}
+		}
+	})
+}
 
-	if !reflect.DeepEqual(conf.expectedConfigFiles, []string{}) {
-		t.Fatalf("inconsistent config files %+v", conf.expectedConfigFiles)
+func Test_confucius_Load_Bytes(t *testing.T) {
+	type Config struct {
+		Key  []byte `conf:"key" default:"c2VjcmV0"`
+		Cert []byte `conf:"cert"`
 	}
-}
 
-func Test_confucius_decodeEmbedFile(t *testing.T) {
-	conf := defaultConfucius()
-	conf.filename = "pod.yaml"
-	conf.useEmbedFS = true
-	conf.embedFS = embedFS
+	os.Clearenv()
 
-	t.Run("when not found", func(t *testing.T) {
-		if _, err := conf.decodeEmbedFile("testdata/embed/pod.yaml"); err != nil {
-			t.Fatalf("unexpected error, %+v", err)
+	t.Run("valid", func(t *testing.T) {
+		setenv(t, "CERT", "LS0tLS1CRUdJTg==")
+
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if string(cfg.Key) != "secret" || string(cfg.Cert) != "-----BEGIN" {
+			t.Fatalf("unexpected config %q %q", cfg.Key, cfg.Cert)
 		}
 	})
 
-	t.Run("when not found", func(t *testing.T) {
-		if _, err := conf.decodeEmbedFile("abc.yaml"); err == nil {
-			t.Fatal("unexpected error")
+	t.Run("file", func(t *testing.T) {
+		os.Unsetenv("CERT")
+
+		var cfg Config
+		if err := Load(&cfg, String(`{key: c2VjcmV0, cert: "LS0tLS1CRUdJTg=="}`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if string(cfg.Key) != "secret" || string(cfg.Cert) != "-----BEGIN" {
+			t.Fatalf("unexpected config %q %q", cfg.Key, cfg.Cert)
 		}
 	})
-}
 
-func Test_confucius_decodeFiles(t *testing.T) {
-	// conf := defaultConfucius()
-	// t.Run("when embedded file not found", func(t *testing.T) {
-	// 	// conf.decodeFiles([]string{"embed:"})
+	t.Run("invalid file value", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{cert: "not base64!"}`, DecoderYaml)); err == nil {
+			t.Fatal("expected err")
+		}
+	})
 
-	// })
+	t.Run("invalid", func(t *testing.T) {
+		setenv(t, "CERT", "not base64!")
+
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv(""))
+		if _, ok := err.(fieldErrors)["cert"]; !ok {
+			t.Fatalf("want cert in fieldErrors, got %+v", err)
+		}
+	})
 }
 
-func Test_confucius_replaceEnvironments(t *testing.T) {
-	os.Setenv("FOO", "XXX")
-	os.Setenv("BAR", "YYY")
+func Test_confucius_Load_ComplexDefaults(t *testing.T) {
+	type Config struct {
+		Impedance complex128  `conf:"impedance" default:"(1+2i)"`
+		Poles     []complex64 `conf:"poles" default:"[(0.5+0.5i),(0.5-0.5i)]"`
+		Gain      complex64   `conf:"gain"`
+	}
 
-	tests := []struct {
-		name     string
-		text     string
-		want     string
-		hasError bool
-	}{
-		{name: "environment with default value", text: "/x/y/${BAZ:a}", want: "/x/y/a"},
-		{name: "environment with default value when contains column", text: "/x/y/${BAZ:http://localhost:9000}", want: "/x/y/http://localhost:9000"},
-		{name: "no environment tag", text: "/x/y/z", want: "/x/y/z"},
-		{name: "from environment", text: "/x/y/${FOO}", want: "/x/y/XXX"},
-		{name: "environment when is not set", text: "/x/y/${BAZ}", want: "/x/y/"},
-		{name: "environment when is not set and default value is missing", text: "/x/y/${BAZ:}", want: "/x/y/"},
-		{name: "environment name is missing", text: "/x/y/${}", hasError: true},
-		{name: "multiple environment names", text: "/x/y/${FOO}/z/${BAR}", want: "/x/y/XXX/z/YYY"},
+	os.Clearenv()
+	setenv(t, "APP_GAIN", "2.5e-3-1i")
+
+	var cfg Config
+	if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("app")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
 	}
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			if result, err := replaceEnvironments(test.text); err != nil {
-				if test.hasError && err == nil {
-					t.Error("not expected")
-				}
-			} else if test.want != result {
-				t.Error("not expected")
-			}
-		})
+	want := Config{
+		Impedance: complex(1, 2),
+		Poles:     []complex64{complex(0.5, 0.5), complex(0.5, -0.5)},
+		Gain:      complex(2.5e-3, -1),
 	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot  %+v", want, cfg)
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		var cfg struct {
+			Impedance complex128 `conf:"impedance" default:"1+2"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["impedance"]; !ok {
+			t.Fatalf("want impedance in fieldErrors, got %+v", err)
+		}
+	})
 }
 
-func Test_confucius_Load_If_Env_Set_In_Conf_File(t *testing.T) {
-	os.Setenv("POD_NAME", "ehcache")
-	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
-		t.Run(f, func(t *testing.T) {
-			var cfg Pod
-			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
-			if err != nil {
-				t.Fatalf("unexpected err: %v", err)
+func Test_confucius_Load_IntOverflow(t *testing.T) {
+	os.Clearenv()
+
+	t.Run("overflow", func(t *testing.T) {
+		setenv(t, "WORKERS", "256")
+
+		var cfg struct {
+			Level   int8          `conf:"level" default:"300"`
+			Offset  int16         `conf:"offset" default:"-32769"`
+			Workers uint8         `conf:"workers"`
+			Timeout time.Duration `conf:"timeout" default:"2562048h"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv(""))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+
+		fieldErrs, ok := err.(fieldErrors)
+		if !ok {
+			t.Fatalf("want fieldErrors, got %T: %v", err, err)
+		}
+		for field, want := range map[string]string{
+			"level":   "300 is out of range for int8",
+			"offset":  "-32769 is out of range for int16",
+			"workers": "256 is out of range for uint8",
+		} {
+			if fieldErrs[field] == nil || !strings.Contains(fieldErrs[field].Error(), want) {
+				t.Errorf("want %s error to contain %q, got %v", field, want, fieldErrs[field])
+			}
+			if !errors.Is(fieldErrs[field], strconv.ErrRange) {
+				t.Errorf("want %s error to wrap %v, got %v", field, strconv.ErrRange, fieldErrs[field])
 			}
+		}
+		if fieldErrs["timeout"] == nil {
+			t.Errorf("want timeout error, got nil")
+		}
+	})
 
-			want := validPodConfig()
-			want.Metadata.Name = "ehcache"
+	t.Run("bounds", func(t *testing.T) {
+		setenv(t, "WORKERS", "255")
 
-			if !reflect.DeepEqual(want, cfg) {
-				t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+		var cfg struct {
+			Level   int8          `conf:"level" default:"-128"`
+			Offset  int16         `conf:"offset" default:"32767"`
+			Workers uint8         `conf:"workers"`
+			Timeout time.Duration `conf:"timeout" default:"2562047h"`
+		}
+		if err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Level != -128 || cfg.Offset != 32767 || cfg.Workers != 255 || cfg.Timeout != 2562047*time.Hour {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_NumberRange(t *testing.T) {
+	type Config struct {
+		Level   int8          `conf:"level"`
+		Port    uint16        `conf:"port"`
+		Workers uint8         `conf:"workers"`
+		Ratio   float32       `conf:"ratio"`
+		Offset  int16         `conf:"offset"`
+		Timeout time.Duration `conf:"timeout"`
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		field   string
+	}{
+		{name: "int8 overflow", content: `{"level": 300}`, field: "level"},
+		{name: "int8 underflow", content: `{"level": -129}`, field: "level"},
+		{name: "uint16 overflow", content: `{"port": 70000}`, field: "port"},
+		{name: "uint8 negative", content: `{"workers": -1}`, field: "workers"},
+		{name: "float32 overflow", content: `{"ratio": 1e40}`, field: "ratio"},
+		{name: "int16 from float", content: `{"offset": 40000.0}`, field: "offset"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.content, DecoderJSON))
+			if err == nil {
+				t.Fatalf("expected err")
+			}
+			if !strings.Contains(err.Error(), tc.field) || !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("want out of range err for %s, got %v", tc.field, err)
 			}
 		})
 	}
+
+	t.Run("bounds", func(t *testing.T) {
+		var cfg Config
+		content := "level: -128\nport: 65535\nworkers: 255\nratio: 3.4e38\noffset: 32767\ntimeout: 9223372036854775807\n"
+		if err := Load(&cfg, String(content, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Level: -128, Port: 65535, Workers: 255, Ratio: 3.4e38, Offset: 32767, Timeout: math.MaxInt64}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+		}
+	})
 }
 
-func Test_confucius_Load_FileNotFound(t *testing.T) {
-	confucius := defaultConfucius()
-	confucius.filename = "abrakadabra"
-	var cfg Pod
-	err := confucius.Load(&cfg)
-	if err == nil {
-		t.Fatalf("expected err")
+func Test_confucius_Load_FloatDefaults(t *testing.T) {
+	type Config struct {
+		Ratio32   float32   `conf:"ratio32" default:"0.1"`
+		Ratio64   float64   `conf:"ratio64" default:"0.1"`
+		Limit32   *float32  `conf:"limit32" default:"3.4028234663852886e+38"`
+		Weights32 []float32 `conf:"weights32" default:"[0.1,0.2,0.3]"`
+		Weights64 []float64 `conf:"weights64" default:"[0.1,0.2,0.3]"`
 	}
-	if !errors.Is(err, ErrFileNotFound) {
-		t.Errorf("expected err %v, got %v", ErrFileNotFound, err)
+
+	var cfg Config
+	if err := Load(&cfg, String(`{}`, DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
 	}
-}
 
-func Test_confucius_Load_NonStructPtr(t *testing.T) {
+	// defaults are the values nearest to the decimal in the field's size,
+	// the same float32 or float64 the Go constant would have.
+	if cfg.Ratio32 != float32(0.1) || cfg.Ratio64 != 0.1 {
+		t.Errorf("unexpected ratios %v %v", cfg.Ratio32, cfg.Ratio64)
+	}
+	if cfg.Limit32 == nil || *cfg.Limit32 != math.MaxFloat32 {
+		t.Errorf("unexpected limit %v", cfg.Limit32)
+	}
+	if !reflect.DeepEqual([]float32{0.1, 0.2, 0.3}, cfg.Weights32) || !reflect.DeepEqual([]float64{0.1, 0.2, 0.3}, cfg.Weights64) {
+		t.Errorf("unexpected weights %v %v", cfg.Weights32, cfg.Weights64)
+	}
+	if got := strconv.FormatFloat(float64(cfg.Ratio32), 'g', -1, 32); got != "0.1" {
+		t.Errorf("want float32 default to format as 0.1, got %s", got)
+	}
+
+	t.Run("out of range", func(t *testing.T) {
+		var cfg struct {
+			Ratio float32 `conf:"ratio" default:"1e39"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["ratio"]; !ok {
+			t.Fatalf("want ratio in fieldErrors, got %+v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_DuplicateProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: localhost\nport: 80")
+	writeFile(t, filepath.Join(dir, "config.e2e.yaml"), "port: 8080")
+	writeFile(t, filepath.Join(dir, "config.eu.yaml"), "port: 9090")
+
+	var cfg struct {
+		Host     string   `conf:"host"`
+		Port     int      `conf:"port"`
+		Profiles []string `conf:",profile"`
+	}
+
+	conf := New(Dirs(dir), Profiles("e2e", "eu", "e2e")).c
+	conf.initExpectedConfigFiles()
+	if want := []string{"config.yaml", "config.e2e.yaml", "config.eu.yaml"}; !reflect.DeepEqual(want, conf.expectedConfigFiles) {
+		t.Fatalf("want expected files %v, got %v", want, conf.expectedConfigFiles)
+	}
+
+	if err := conf.Load(&cfg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("want the first position of e2e kept, port 9090, got %d", cfg.Port)
+	}
+	if want := []string{"e2e", "eu"}; !reflect.DeepEqual(want, cfg.Profiles) {
+		t.Errorf("want profiles %v, got %v", want, cfg.Profiles)
+	}
+}
+
+func Test_confucius_Load_MergeOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: localhost\nport: 80\nhosts: [a, b]\nlabels: {tier: web}\nzones: [eu]")
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), "host: \"\"\nport: 8080\nhosts: [c]\nlabels: {tier: null}\nzones: []")
+
+	type Config struct {
+		Host   string            `conf:"host"`
+		Port   int               `conf:"port"`
+		Hosts  []string          `conf:"hosts"`
+		Labels map[string]string `conf:"labels"`
+		Zones  []string          `conf:"zones"`
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want Config
+	}{
+		{
+			name: "empty values override",
+			want: Config{Port: 8080, Hosts: []string{"c"}, Labels: map[string]string{"tier": ""}},
+		},
+		{
+			name: "skip empty",
+			opts: []Option{MergeSkipEmpty()},
+			want: Config{Host: "localhost", Port: 8080, Hosts: []string{"c"}, Labels: map[string]string{"tier": "web"}, Zones: []string{"eu"}},
+		},
+		{
+			name: "append slice",
+			opts: []Option{MergeSkipEmpty(), MergeAppendSlice()},
+			want: Config{Host: "localhost", Port: 8080, Hosts: []string{"a", "b", "c"}, Labels: map[string]string{"tier": "web"}, Zones: []string{"eu"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			opts := append([]Option{Dirs(dir), Profiles("test")}, tc.opts...)
+			if err := Load(&cfg, opts...); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(tc.want, cfg) {
+				t.Errorf("\nwant %+v\ngot %+v", tc.want, cfg)
+			}
+		})
+	}
+
+	t.Run("readers", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{"host": "localhost", "hosts": ["a"]}`, DecoderJSON), String(`{"host": "", "hosts": ["b"]}`, DecoderJSON),
+			MergeSkipEmpty(), MergeAppendSlice())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "localhost" || !reflect.DeepEqual(cfg.Hosts, []string{"a", "b"}) {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_OptionalFile(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host" default:"localhost"`
+		Port int    `conf:"port" default:"8080"`
+		Env  string `conf:"env" validate:"required"`
+	}
+
+	t.Run("defaults without the file", func(t *testing.T) {
+		var cfg struct {
+			Host string `conf:"host" default:"localhost"`
+			Port int    `conf:"port" default:"8080"`
+		}
+		err := Load(&cfg, File("missing.yaml"), Dirs(t.TempDir()), OptionalFile())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "localhost" || cfg.Port != 8080 {
+			t.Fatalf("want defaults, got %+v", cfg)
+		}
+	})
+
+	t.Run("fields are still validated", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, File("missing.yaml"), Dirs(t.TempDir()), OptionalFile())
+		if _, ok := err.(fieldErrors)["env"]; !ok {
+			t.Fatalf("want env in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("found files are loaded", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.yaml"), "env: prod\nport: 80")
+
+		var cfg Config
+		err := Load(&cfg, Dirs(dir), Profiles("test"), OptionalFile())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 80, Env: "prod"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("required without the option", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, File("missing.yaml"), Dirs(t.TempDir()))
+		if !errors.Is(err, ErrFileNotFound) {
+			t.Fatalf("want err %v, got %v", ErrFileNotFound, err)
+		}
+	})
+}
+
+func Test_confucius_Load_CaptureRaw(t *testing.T) {
+	dir := t.TempDir()
+	main := "\xef\xbb\xbfhost: localhost\nport: 80\n"
+	profile := "port: 8080\n"
+	writeFile(t, filepath.Join(dir, "config.yaml"), main)
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), profile)
+
+	var cfg struct {
+		Host  string `conf:"host"`
+		Port  int    `conf:"port"`
+		Level string `conf:"level"`
+	}
+	raw := map[string][]byte{}
+	err := Load(&cfg, Dirs(dir), Profiles("test"), String(`{"level": "debug"}`, DecoderJSON), CaptureRaw(raw))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := map[string][]byte{
+		filepath.Join(dir, "config.yaml"):      []byte(main),
+		filepath.Join(dir, "config.test.yaml"): []byte(profile),
+		"reader[0]":                            []byte(`{"level": "debug"}`),
+	}
+	if !reflect.DeepEqual(want, raw) {
+		t.Errorf("\nwant %q\ngot  %q", want, raw)
+	}
+
+	t.Run("embed", func(t *testing.T) {
+		raw := map[string][]byte{}
+		var cfg Pod
+		err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "embed")), EmbedFS(embedFS), CaptureRaw(raw))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		content, err := embedFS.ReadFile("testdata/embed/pod.yaml")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if got := raw["embed:testdata/embed/pod.yaml"]; !bytes.Equal(content, got) {
+			t.Errorf("want embed file captured, got %q", raw)
+		}
+	})
+}
+
+func Test_confucius_Load_SecretFileExpansion(t *testing.T) {
+	type Config struct {
+		Username string `conf:"username"`
+		Password string `conf:"password" secret:"true"`
+		APIKey   string `conf:"api_key"`
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db_password"), "s3cr3t\n")
+	writeFile(t, filepath.Join(dir, "api_key"), "abc123")
+
+	os.Clearenv()
+	setenv(t, "SECRETS_DIR", dir)
+	setenv(t, "APP_PASSWORD", "file:"+filepath.Join(dir, "db_password"))
+
+	t.Run("env and file values", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg,
+			String("username: admin\napi_key: file:${SECRETS_DIR}/api_key", DecoderYaml),
+			UseEnv("app"),
+			SecretFileExpansion(),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Username: "admin", Password: "s3cr3t", APIKey: "abc123"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		setenv(t, "APP_PASSWORD", "file:"+filepath.Join(dir, "missing"))
+		defer setenv(t, "APP_PASSWORD", "file:"+filepath.Join(dir, "db_password"))
+
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("app"), SecretFileExpansion())
+		perr, ok := err.(fieldErrors)["password"]
+		if !ok {
+			t.Fatalf("want password in fieldErrors, got %+v", err)
+		}
+		if !errors.Is(perr, os.ErrNotExist) || !strings.Contains(perr.Error(), "secret file") {
+			t.Fatalf("want secret file not found err, got %v", perr)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("app")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if want := "file:" + filepath.Join(dir, "db_password"); cfg.Password != want {
+			t.Fatalf("want %s, got %s", want, cfg.Password)
+		}
+	})
+}
+
+func Test_confucius_Load_Squash(t *testing.T) {
+	type Meta struct {
+		Name    string        `conf:"name" validate:"required"`
+		Version string        `conf:"version" validate:"required_with=image"`
+		Retry   time.Duration `conf:"retry" unit:"s"`
+	}
+	type Config struct {
+		Meta  `conf:",squash"`
+		Image string `conf:"image"`
+		Port  int    `conf:"port" default:"80"`
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		decoder Decoder
+	}{
+		{name: "yaml", content: "name: api\nversion: v1\nretry: 5\nimage: api:v1", decoder: DecoderYaml},
+		{name: "json", content: `{"name": "api", "version": "v1", "retry": 5, "image": "api:v1"}`, decoder: DecoderJSON},
+		{name: "toml", content: "name = \"api\"\nversion = \"v1\"\nretry = 5\nimage = \"api:v1\"", decoder: DecoderToml},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			if err := Load(&cfg, String(tc.content, tc.decoder)); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			want := Config{Meta: Meta{Name: "api", Version: "v1", Retry: 5 * time.Second}, Image: "api:v1", Port: 80}
+			if cfg != want {
+				t.Fatalf("want %+v, got %+v", want, cfg)
+			}
+		})
+	}
+
+	t.Run("env", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "APP_NAME", "web")
+
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("app")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Name != "web" {
+			t.Fatalf("want name web, got %s", cfg.Name)
+		}
+	})
+
+	t.Run("errors are named at the parent level", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`image: api:v1`, DecoderYaml))
+		fe, ok := err.(fieldErrors)
+		if !ok {
+			t.Fatalf("want fieldErrors, got %T: %v", err, err)
+		}
+		for _, path := range []string{"name", "version"} {
+			if _, ok := fe[path]; !ok {
+				t.Errorf("want %s in fieldErrors, got %v", path, fe)
+			}
+		}
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		cfg := Config{Meta: Meta{Name: "api"}, Port: 80}
+		out, err := Dump(&cfg)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := "name: api\nversion: \"\"\nretry: 0s\nimage: \"\"\nport: 80\n"
+		if string(out) != want {
+			t.Fatalf("\nwant %q\ngot  %q", want, out)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		changes, err := Diff(Config{Meta: Meta{Name: "api"}}, Config{Meta: Meta{Name: "web"}})
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if len(changes) != 1 || changes[0].Path != "name" {
+			t.Fatalf("want a change of name, got %v", changes)
+		}
+	})
+}
+
+func Test_confucius_Load_Files(t *testing.T) {
+	type Config struct {
+		Host  string `conf:"host"`
+		Port  int    `conf:"port"`
+		Level string `conf:"level"`
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config.d"), 0o700); err != nil {
+		t.Fatalf("os.MkdirAll() unexpected error: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "config.d", "20-override.yaml"), "port: 9090\nlevel: warn")
+	writeFile(t, filepath.Join(dir, "config.d", "10-base.yaml"), "host: localhost\nport: 8080\nlevel: info")
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), "level: debug")
+
+	t.Run("later fragment overrides earlier", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), Files("config.d/*.yaml")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 9090, Level: "warn"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("patterns in order", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), Files("config.d/2*.yaml", "config.d/1*.yaml")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 8080, Level: "info"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("profiles merged last", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), Files("config.d/*.yaml"), Profiles("test")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 9090, Level: "debug"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("pattern without match", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, Dirs(dir), Files("config.d/*.yaml", "*.toml"))
+
+		var mfe *MissingFilesError
+		if !errors.As(err, &mfe) || !reflect.DeepEqual([]string{"*.toml"}, mfe.Files) {
+			t.Fatalf("want missing *.toml, got %v", err)
+		}
+	})
+
+	t.Run("bad pattern", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), Files("config.d/[.yaml")); !errors.Is(err, filepath.ErrBadPattern) {
+			t.Fatalf("expected err %v, got %v", filepath.ErrBadPattern, err)
+		}
+	})
+}
+
+func Test_confucius_Load_FilePaths(t *testing.T) {
+	type Config struct {
+		Host  string `conf:"host"`
+		Port  int    `conf:"port"`
+		Level string `conf:"level"`
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	override := filepath.Join(dir, "override.json")
+	writeFile(t, base, "host: localhost\nport: 8080\nlevel: info")
+	writeFile(t, override, `{"port": 9090, "level": "warn"}`)
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: ignored")
+
+	t.Run("later path overrides earlier", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), FilePaths(base, override)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 9090, Level: "warn"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("paths in order", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, FilePaths(override, base)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 8080, Level: "info"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing.yaml")
+		var cfg Config
+		err := Load(&cfg, FilePaths(base, missing))
+		var mfe *MissingFilesError
+		if !errors.As(err, &mfe) {
+			t.Fatalf("want MissingFilesError, got %v", err)
+		}
+		if len(mfe.Files) != 1 || mfe.Files[0] != missing {
+			t.Fatalf("want missing file %s, got %v", missing, mfe.Files)
+		}
+	})
+
+	t.Run("optional missing path", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, FilePaths(base, filepath.Join(dir, "missing.yaml")), OptionalFile()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "localhost" || cfg.Port != 8080 {
+			t.Fatalf("want values of base, got %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_AutoConfD(t *testing.T) {
+	type Config struct {
+		Host  string `conf:"host"`
+		Port  int    `conf:"port"`
+		Level string `conf:"level"`
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "config.d", "nested.yaml"), 0o700); err != nil {
+		t.Fatalf("os.MkdirAll() unexpected error: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: localhost\nport: 80\nlevel: info")
+	writeFile(t, filepath.Join(dir, "config.d", "10-override.yaml"), "port: 8080\nlevel: warn")
+	writeFile(t, filepath.Join(dir, "config.d", "20-override.yaml"), "level: error")
+	writeFile(t, filepath.Join(dir, "config.d", "30-ignored.json"), `{"host": "json"}`)
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), "level: debug")
+
+	t.Run("overrides main file", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), AutoConfD()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 8080, Level: "error"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("before profiles", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), AutoConfD(), Profiles("test")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 8080, Level: "debug"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 80, Level: "info"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+}
+
+func Test_confucius_searchDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/b/c", "d"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			t.Fatalf("os.MkdirAll() unexpected error: %v", err)
+		}
+	}
+	writeFile(t, filepath.Join(root, "a", "b", "config.yaml"), `host: "b"`)
+	writeFile(t, filepath.Join(root, "a", "b", "c", "config.yaml"), `host: "c"`)
+
+	t.Run("breadth first up to depth", func(t *testing.T) {
+		conf := defaultConfucius()
+		conf.dirs = []string{root}
+		conf.searchDepth = 2
+
+		want := []string{
+			root,
+			filepath.Join(root, "a"),
+			filepath.Join(root, "d"),
+			filepath.Join(root, "a", "b"),
+		}
+		if got := conf.searchDirs(); !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("not recursive", func(t *testing.T) {
+		conf := defaultConfucius()
+		conf.dirs = []string{root}
+
+		if got := conf.searchDirs(); !reflect.DeepEqual([]string{root}, got) {
+			t.Fatalf("want %+v, got %+v", []string{root}, got)
+		}
+	})
+
+	t.Run("shallowest file wins", func(t *testing.T) {
+		var cfg struct {
+			Host string `conf:"host"`
+		}
+		if err := Load(&cfg, Dirs(root), Recursive(5)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "b" {
+			t.Fatalf("cfg.Host == %s, expected %s", cfg.Host, "b")
+		}
+	})
+
+	t.Run("file deeper than max depth", func(t *testing.T) {
+		var cfg struct {
+			Host string `conf:"host"`
+		}
+		err := Load(&cfg, Dirs(root), Recursive(1))
+		if !errors.Is(err, ErrFileNotFound) {
+			t.Fatalf("expected err %v, got %v", ErrFileNotFound, err)
+		}
+	})
+
+	t.Run("search recursive", func(t *testing.T) {
+		writeFile(t, filepath.Join(root, "a", "b", "c", "config.test.yaml"), `port: 9090`)
+		defer os.Remove(filepath.Join(root, "a", "b", "c", "config.test.yaml"))
+
+		var cfg struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		}
+		if err := Load(&cfg, Dirs(root), SearchRecursive(), Profiles("test")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "b" || cfg.Port != 9090 {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
+
+	t.Run("symlinks are not followed", func(t *testing.T) {
+		if err := os.Symlink(root, filepath.Join(root, "d", "loop")); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+
+		conf := defaultConfucius()
+		conf.dirs = []string{root}
+		conf.searchDepth = 10
+
+		if got := conf.searchDirs(); len(got) != 5 {
+			t.Fatalf("len(searchDirs()) == %d, expected %d: %+v", len(got), 5, got)
+		}
+	})
+}
+
+func Test_confucius_findEmbedFiles(t *testing.T) {
+	conf := defaultConfucius()
+	conf.fsys = embedFS
+	conf.filename = "pod.yaml"
+
+	if acc, err := conf.findEmbedFiles(); err != nil {
+		t.Fatalf("expected err: %+v", err)
+	} else if !reflect.DeepEqual(acc, []string{"#embed:#main=testdata/embed/pod.yaml"}) {
+		t.Fatalf("inconsistent: %+v", acc)
+	}
+}
+
+func Test_confucius_Load_ExtensionlessFile(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+
+	t.Run("only toml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.toml"), "host = \"localhost\"\nport = 8080")
+		writeFile(t, filepath.Join(dir, "config.prod.toml"), "port = 443")
+
+		var cfg Config
+		if err := Load(&cfg, File("config"), Dirs(dir), Profiles("prod")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 443}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("first format found", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "config.toml"), "host = \"toml\"")
+		writeFile(t, filepath.Join(dir, "config.json"), `{"host": "json"}`)
+
+		var cfg Config
+		if err := Load(&cfg, File("config"), Dirs(dir)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "json" {
+			t.Fatalf("want host json, got %q", cfg.Host)
+		}
+	})
+
+	t.Run("file system", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"etc/app.yml": {Data: []byte("host: embedded")},
+		}
+		var cfg Config
+		if err := Load(&cfg, File("app"), FileSystem(fsys), Dirs(t.TempDir())); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "embedded" {
+			t.Fatalf("want host embedded, got %q", cfg.Host)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, File("config"), Dirs(t.TempDir()))
+		if !errors.Is(err, ErrFileNotFound) {
+			t.Fatalf("want ErrFileNotFound, got %v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_FileSystem(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port" default:"80"`
+		Env  string `conf:"env" validate:"required"`
+	}
+
+	fsys := fstest.MapFS{
+		"etc/myapp/config.yaml":      {Data: []byte("host: localhost\nenv: dev")},
+		"etc/myapp/config.prod.yaml": {Data: []byte("env: prod\nport: 443")},
+		"etc/other/app.yaml":         {Data: []byte("host: other")},
+	}
+
+	t.Run("main and profile", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, FileSystem(fsys), Profiles("prod"), Dirs(t.TempDir()))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Host: "localhost", Port: 443, Env: "prod"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("sub file system", func(t *testing.T) {
+		sub, err := fs.Sub(fsys, "etc/myapp")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		raw := map[string][]byte{}
+		var cfg Config
+		if err := Load(&cfg, FileSystem(sub), Dirs(t.TempDir()), CaptureRaw(raw)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Env != "dev" {
+			t.Fatalf("want env dev, got %+v", cfg)
+		}
+		if _, ok := raw["embed:config.yaml"]; !ok {
+			t.Fatalf("want config.yaml read from the root of the sub fs, got %q", raw)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, FileSystem(fsys), File("missing.yaml"), Dirs(t.TempDir()))
+		if !errors.Is(err, ErrFileNotFound) {
+			t.Fatalf("want err %v, got %v", ErrFileNotFound, err)
+		}
+	})
+}
+
+func Test_confucius_profileFileName(t *testing.T) {
+	for _, tc := range []struct {
+		filename string
+		layout   string
+		want     string
+	}{
+		{filename: "config.yaml", layout: DefaultProfileLayout, want: "config.e2e.yaml"},
+		{filename: "config.override.yaml", layout: DefaultProfileLayout, want: "config.override.e2e.yaml"},
+		{filename: "config.local.yaml", layout: "config-test.yaml", want: "config.local-e2e.yaml"},
+		{filename: "app.v2.json", layout: DefaultProfileLayout, want: "app.v2.e2e.json"},
+		{filename: "app.v2.json", layout: "config-test.yaml", want: "app.v2-e2e.json"},
+		{filename: "server.prod.eu.toml", layout: DefaultProfileLayout, want: "server.prod.eu.e2e.toml"},
+		{filename: "contest.yaml", layout: DefaultProfileLayout, want: "contest.e2e.yaml"},
+		{filename: "deploy/app.conf.yml", layout: DefaultProfileLayout, want: "deploy/app.conf.e2e.yml"},
+		{filename: "config.yaml", layout: "{name}-{profile}.{ext}", want: "config-e2e.yaml"},
+		{filename: "test.config.yaml", layout: "{name}.{profile}.{ext}", want: "test.config.e2e.yaml"},
+		{filename: "app.v2.json", layout: "profiles/{profile}/{name}.{ext}", want: "profiles/e2e/app.v2.json"},
+		{filename: "app.toml", layout: "{name}_config_test.{ext}", want: "app_config_test.toml"},
+		{filename: "test.yaml", layout: "config-test.yaml", want: "test-e2e.yaml"},
+	} {
+		t.Run(tc.filename+"/"+tc.layout, func(t *testing.T) {
+			conf := defaultConfucius()
+			conf.filename = tc.filename
+			conf.profileLayout = tc.layout
+			if got := conf.profileFileName("e2e"); got != tc.want {
+				t.Errorf("profileFileName() == %s, expected %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_ProfileLayoutPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "test-config.json"), `{"host": "localhost", "port": 80}`)
+	writeFile(t, filepath.Join(dir, "test-config_test.json"), `{"port": 8080}`)
+
+	var cfg struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+	err := Load(&cfg, File("test-config.json"), Dirs(dir), Profiles("test"), ProfileLayout("{name}_{profile}.{ext}"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 {
+		t.Fatalf("want localhost:8080, got %+v", cfg)
+	}
+}
+
+func Test_confucius_Load_MultiDotFilename(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.override.yaml"), "host: localhost\nport: 80")
+	writeFile(t, filepath.Join(dir, "config.override.test.yaml"), "port: 8080")
+
+	var cfg struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port"`
+	}
+	if err := Load(&cfg, File("config.override.yaml"), Dirs(dir), Profiles("test")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 8080 {
+		t.Fatalf("want localhost:8080, got %+v", cfg)
+	}
+}
+
+func Test_confucius_fileExists(t *testing.T) {
+	conf := defaultConfucius()
+
+	t.Run("main", func(t *testing.T) {
+		if tag := conf.fileExists("config.yaml"); tag != "#main" {
+			t.Fatal("main not found")
+		}
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		conf.profiles = []string{"e2e"}
+
+		if tag := conf.fileExists("config.e2e.yaml"); tag != "#profile_00_e2e" {
+			t.Fatal("not found in profiles")
+		}
+	})
+
+	t.Run("profiles with order", func(t *testing.T) {
+		conf.profiles = []string{"e2e", "uat"}
+
+		if tag := conf.fileExists("config.e2e.yaml"); tag != "#profile_00_e2e" {
+			t.Fatal("not found in profiles")
+		}
+
+		if tag := conf.fileExists("config.uat.yaml"); tag != "#profile_01_uat" {
+			t.Fatal("not found in profiles")
+		}
+	})
+
+	t.Run("not found in main and profiles", func(t *testing.T) {
+		if tag := conf.fileExists("pod.yaml"); tag != "" {
+			t.Fatal("file not found")
+		}
+	})
+}
+
+func Test_confucius_walkEmbedDir(t *testing.T) {
+	conf := defaultConfucius()
+	conf.fsys = embedFS
+	conf.filename = "pod.yaml"
+	conf.profiles = []string{"dev", "e2e"}
+
+	accumulator := []string{}
+	found := map[string]bool{}
+
+	t.Run("success", func(t *testing.T) {
+		err := conf.walkEmbedDir(&accumulator, found, ".")
+
+		if err != nil {
+			t.Fatalf("not expected: %+v", err)
+		}
+
+		expected := []string{
+			"#embed:#main=testdata/embed/pod.yaml",
+			"#embed:#profile_01_e2e=testdata/embed/pod.e2e.yaml",
+		}
+
+		if !reflect.DeepEqual(accumulator, expected) {
+			t.Fatal("accumulator not match")
+		}
+	})
+
+	t.Run("when embed file is not read", func(t *testing.T) {
+		err := conf.walkEmbedDir(&accumulator, found, "bad-path")
+		if err == nil {
+			t.Fatal("not expected")
+		}
+	})
+}
+
+func Test_confucius_walkEmbedDir_Logging(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config.yaml": {Data: []byte("host: localhost")},
+		"README.md":   {Data: []byte("# app")},
+		"app.json":    {Data: []byte("{}")},
+	}
+
+	t.Run("default logger is silent", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		stdout, stderr, logOut := os.Stdout, os.Stderr, log.Writer()
+		os.Stdout, os.Stderr = w, w
+		log.SetOutput(w)
+
+		var cfg struct {
+			Host string `conf:"host"`
+		}
+		loadErr := Load(&cfg, FileSystem(fsys), Dirs(t.TempDir()))
+
+		os.Stdout, os.Stderr = stdout, stderr
+		log.SetOutput(logOut)
+		w.Close()
+		out, _ := io.ReadAll(r)
+
+		if loadErr != nil {
+			t.Fatalf("unexpected err: %v", loadErr)
+		}
+		if len(out) > 0 {
+			t.Fatalf("want no output, got %q", out)
+		}
+	})
+
+	t.Run("skipped files are logged at debug level", func(t *testing.T) {
+		var skipped []string
+		conf := New(Logger(StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+			if message == "skipping non-config file" && level == DebugLevel {
+				skipped = append(skipped, fields["file"].(string))
+			}
+		}))).c
+		conf.fsys = fsys
+
+		var acc []string
+		if err := conf.walkEmbedDir(&acc, map[string]bool{}, "."); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		sort.Strings(skipped)
+		if want := []string{"README.md", "app.json"}; !reflect.DeepEqual(want, skipped) {
+			t.Fatalf("want %v skipped, got %v", want, skipped)
+		}
+	})
+}
+
+func Test_confucius_initExpectedConfigFiles(t *testing.T) {
+	conf := defaultConfucius()
+	conf.profiles = []string{"e2e", "dev", "uat"}
+
+	if len(conf.expectedConfigFiles) != 0 {
+		t.Fatal("it should empty")
+	}
+
+	conf.initExpectedConfigFiles()
+
+	if len(conf.expectedConfigFiles) != 4 {
+		t.Fatal("unexpected file count")
+	}
+
+	if !reflect.DeepEqual(conf.expectedConfigFiles, []string{
+		"config.yaml", "config.e2e.yaml", "config.dev.yaml", "config.uat.yaml",
+	}) {
+		t.Fatalf("inconsistent config files %+v", conf.expectedConfigFiles)
+	}
+}
+
+func Test_confucius_removeFromExpectedList(t *testing.T) {
+	conf := defaultConfucius()
+	conf.initExpectedConfigFiles()
+
+	conf.removeFromExpectedList("config.yaml")
+
+	if !reflect.DeepEqual(conf.expectedConfigFiles, []string{}) {
+		t.Fatalf("inconsistent config files %+v", conf.expectedConfigFiles)
+	}
+}
+
+func Test_confucius_decodeEmbedFile(t *testing.T) {
+	conf := defaultConfucius()
+	conf.filename = "pod.yaml"
+	conf.fsys = embedFS
+
+	t.Run("when not found", func(t *testing.T) {
+		if _, err := conf.decodeEmbedFile("testdata/embed/pod.yaml"); err != nil {
+			t.Fatalf("unexpected error, %+v", err)
+		}
+	})
+
+	t.Run("when not found", func(t *testing.T) {
+		if _, err := conf.decodeEmbedFile("abc.yaml"); err == nil {
+			t.Fatal("unexpected error")
+		}
+	})
+}
+
+func Test_confucius_decodeFiles(t *testing.T) {
+	// conf := defaultConfucius()
+	// t.Run("when embedded file not found", func(t *testing.T) {
+	// 	// conf.decodeFiles([]string{"embed:"})
+
+	// })
+}
+
+func Test_confucius_replaceEnvironments(t *testing.T) {
+	os.Setenv("FOO", "XXX")
+	os.Setenv("BAR", "YYY")
+
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		hasError bool
+	}{
+		{name: "environment with default value", text: "/x/y/${BAZ:a}", want: "/x/y/a"},
+		{name: "environment with default value when contains column", text: "/x/y/${BAZ:http://localhost:9000}", want: "/x/y/http://localhost:9000"},
+		{name: "no environment tag", text: "/x/y/z", want: "/x/y/z"},
+		{name: "from environment", text: "/x/y/${FOO}", want: "/x/y/XXX"},
+		{name: "environment when is not set", text: "/x/y/${BAZ}", want: "/x/y/"},
+		{name: "environment when is not set and default value is missing", text: "/x/y/${BAZ:}", want: "/x/y/"},
+		{name: "environment name is missing", text: "/x/y/${}", hasError: true},
+		{name: "multiple environment names", text: "/x/y/${FOO}/z/${BAR}", want: "/x/y/XXX/z/YYY"},
+		{name: "config reference", text: "http://${config:host}/${FOO}", want: "http://${config:host}/XXX"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result, err := replaceEnvironments(test.text); err != nil {
+				if test.hasError && err == nil {
+					t.Error("not expected")
+				}
+			} else if test.want != result {
+				t.Error("not expected")
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_If_Env_Set_In_Conf_File(t *testing.T) {
+	os.Setenv("POD_NAME", "ehcache")
+	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
+		t.Run(f, func(t *testing.T) {
+			var cfg Pod
+			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			want := validPodConfig()
+			want.Metadata.Name = "ehcache"
+
+			if !reflect.DeepEqual(want, cfg) {
+				t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_FileNotFound(t *testing.T) {
+	confucius := defaultConfucius()
+	confucius.filename = "abrakadabra"
+	var cfg Pod
+	err := confucius.Load(&cfg)
+	if err == nil {
+		t.Fatalf("expected err")
+	}
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("expected err %v, got %v", ErrFileNotFound, err)
+	}
+}
+
+func Test_confucius_Load_NonStructPtr(t *testing.T) {
 	cfg := struct {
 		X int
 	}{}
@@ -443,46 +1709,403 @@ func Test_confucius_Load_NonStructPtr(t *testing.T) {
 	if err == nil {
 		t.Fatalf("confucius.Load() returned nil error")
 	}
-	if !strings.Contains(err.Error(), "pointer") {
-		t.Errorf("expected struct pointer err, got %v", err)
+	if !strings.Contains(err.Error(), "pointer") {
+		t.Errorf("expected struct pointer err, got %v", err)
+	}
+}
+
+func Test_confucius_Load_Required(t *testing.T) {
+	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
+		t.Run(f, func(t *testing.T) {
+			var cfg Pod
+			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "invalid")))
+			if err == nil {
+				t.Fatalf("expected err")
+			}
+
+			want := []string{
+				"kind",
+				"metadata.master",
+				"spec.containers[0].image",
+				"spec.volumes[0].configMap.items",
+				"spec.volumes[1].name",
+			}
+
+			fieldErrs := err.(fieldErrors)
+
+			if len(want) != len(fieldErrs) {
+				t.Fatalf("\nwant len(fieldErrs) == %d, got %d\nerrs: %+v\n", len(want), len(fieldErrs), fieldErrs)
+			}
+
+			for _, field := range want {
+				if _, ok := fieldErrs[field]; !ok {
+					t.Errorf("want %s in fieldErrs, got %+v", field, fieldErrs)
+				}
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_FailFast(t *testing.T) {
+	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
+		t.Run(f, func(t *testing.T) {
+			var cfg Pod
+			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "invalid")), FailFast())
+			if err == nil {
+				t.Fatalf("expected err")
+			}
+
+			fieldErrs := err.(fieldErrors)
+			if len(fieldErrs) != 1 {
+				t.Fatalf("want len(fieldErrs) == 1, got %d\nerrs: %+v\n", len(fieldErrs), fieldErrs)
+			}
+			if _, ok := fieldErrs["kind"]; !ok {
+				t.Errorf("want kind in fieldErrs, got %+v", fieldErrs)
+			}
+		})
+	}
+}
+
+// cancelReader cancels its context after returning its first chunk.
+type cancelReader struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.reads++
+	r.cancel()
+	return copy(p, "a: 1\n"), nil
+}
+
+func Test_LoadContext(t *testing.T) {
+	os.Clearenv()
+
+	t.Run("file", func(t *testing.T) {
+		var cfg Pod
+		err := LoadContext(context.Background(), &cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if want := validPodConfig(); !reflect.DeepEqual(want, cfg) {
+			t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var cfg Pod
+		err := LoadContext(ctx, &cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want context.Canceled, got %v", err)
+		}
+		if !reflect.DeepEqual(cfg, Pod{}) {
+			t.Errorf("cfg was modified: %+v", cfg)
+		}
+	})
+
+	t.Run("cancelled while reading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r := &cancelReader{cancel: cancel}
+		var cfg struct {
+			A int `conf:"a"`
+		}
+		err := LoadContext(ctx, &cfg, Reader(r, DecoderYaml))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want context.Canceled, got %v", err)
+		}
+		if r.reads != 1 {
+			t.Errorf("want reading to stop after 1 read, got %d", r.reads)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+
+		var cfg Pod
+		err := LoadContext(ctx, &cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_RequiredAggregates(t *testing.T) {
+	type Config struct {
+		Tags   []string          `conf:"tags" validate:"required"`
+		Labels map[string]string `conf:"labels" validate:"required"`
+	}
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "nil", content: `{}`, want: []string{"labels", "tags"}},
+		{name: "null", content: `{"tags": null, "labels": null}`, want: []string{"labels", "tags"}},
+		{name: "empty", content: `{"tags": [], "labels": {}}`, want: []string{"labels", "tags"}},
+		{name: "populated", content: `{"tags": ["a"], "labels": {"tier": "web"}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.content, DecoderJSON))
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+
+			fe, ok := FieldErrors(err)
+			if !ok {
+				t.Fatalf("want field errors, got %v", err)
+			}
+			if !reflect.DeepEqual(tc.want, fe.Fields()) {
+				t.Errorf("want errors for %v, got %v", tc.want, fe)
+			}
+		})
+	}
+
+	t.Run("empty in file, set from env", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "TAGS", "[a,b]")
+		setenv(t, "LABELS_tier", "web")
+
+		var cfg Config
+		if err := Load(&cfg, String(`{"tags": [], "labels": {}}`, DecoderJSON), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !reflect.DeepEqual(cfg.Tags, []string{"a", "b"}) || cfg.Labels["tier"] != "web" {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_TransformMap(t *testing.T) {
+	type Config struct {
+		Logger struct {
+			LogLevel string `conf:"log_level" validate:"required"`
+		} `conf:"logger"`
+		Version int `conf:"version"`
+	}
+
+	renameLegacy := TransformMap(func(m map[string]interface{}) (map[string]interface{}, error) {
+		logger, ok := m["logger"].(map[string]interface{})
+		if !ok {
+			return m, nil
+		}
+		if v, ok := logger["log-level"]; ok {
+			logger["log_level"] = v
+			delete(logger, "log-level")
+		}
+		return m, nil
+	})
+
+	for _, f := range []string{"config.yaml", "config.json", "config.toml"} {
+		t.Run(f, func(t *testing.T) {
+			dir := t.TempDir()
+			content := map[string]string{
+				"config.yaml": "logger:\n  log-level: debug",
+				"config.json": `{"logger": {"log-level": "debug"}}`,
+				"config.toml": "[logger]\nlog-level = \"debug\"",
+			}[f]
+			writeFile(t, filepath.Join(dir, f), content)
+
+			var cfg Config
+			if err := Load(&cfg, Dirs(dir), File(f)); err == nil {
+				t.Fatalf("want the legacy key to be ignored without the transform")
+			}
+
+			inject := TransformMap(func(m map[string]interface{}) (map[string]interface{}, error) {
+				return map[string]interface{}{"logger": m["logger"], "version": 2}, nil
+			})
+			if err := Load(&cfg, Dirs(dir), File(f), renameLegacy, inject); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if cfg.Logger.LogLevel != "debug" || cfg.Version != 2 {
+				t.Errorf("unexpected config %+v", cfg)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		errLayout := errors.New("unknown layout")
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderJSON), TransformMap(func(map[string]interface{}) (map[string]interface{}, error) {
+			return nil, errLayout
+		}))
+		if !errors.Is(err, errLayout) {
+			t.Fatalf("want err %v, got %v", errLayout, err)
+		}
+	})
+}
+
+func Test_confucius_Load_AfterLoad(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host" default:"localhost"`
+		Port int    `conf:"port" validate:"required"`
+		Addr string `conf:"-"`
 	}
+
+	deriveAddr := AfterLoad(func(cfg interface{}) error {
+		c := cfg.(*Config)
+		c.Addr = fmt.Sprintf("%s:%d", c.Host, c.Port)
+		return nil
+	})
+	errPrivileged := errors.New("privileged port")
+	checkPort := AfterLoad(func(cfg interface{}) error {
+		if cfg.(*Config).Port < 1024 {
+			return errPrivileged
+		}
+		return nil
+	})
+
+	t.Run("derived field", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{"port": 8080}`, DecoderJSON), deriveAddr, checkPort); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Addr != "localhost:8080" {
+			t.Errorf("want addr localhost:8080, got %s", cfg.Addr)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var order []string
+		record := func(name string) Option {
+			return AfterLoad(func(interface{}) error {
+				order = append(order, name)
+				return nil
+			})
+		}
+
+		var cfg Config
+		err := Load(&cfg, String(`{"port": 80}`, DecoderJSON), record("first"), deriveAddr, checkPort, record("last"))
+		if !errors.Is(err, errPrivileged) {
+			t.Fatalf("want err %v, got %v", errPrivileged, err)
+		}
+		if cfg.Addr != "localhost:80" {
+			t.Errorf("want addr localhost:80, got %s", cfg.Addr)
+		}
+		if !reflect.DeepEqual(order, []string{"first"}) {
+			t.Errorf("want only the hooks before the error to run, got %v", order)
+		}
+	})
+
+	t.Run("not called when loading fails", func(t *testing.T) {
+		called := false
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderJSON), AfterLoad(func(interface{}) error {
+			called = true
+			return nil
+		}))
+		if _, ok := FieldErrors(err); !ok {
+			t.Fatalf("want field errors, got %v", err)
+		}
+		if called {
+			t.Errorf("want hook not called")
+		}
+	})
 }
 
-func Test_confucius_Load_Required(t *testing.T) {
+func Test_Check(t *testing.T) {
 	for _, f := range []string{"pod.yaml", "pod.json", "pod.toml"} {
 		t.Run(f, func(t *testing.T) {
+			var loaded Pod
+			loadErr := Load(&loaded, File(f), Dirs(filepath.Join("testdata", "invalid")))
+			if loadErr == nil {
+				t.Fatalf("expected err")
+			}
+
 			var cfg Pod
-			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "invalid")))
+			err := Check(&cfg, File(f), Dirs(filepath.Join("testdata", "invalid")))
 			if err == nil {
 				t.Fatalf("expected err")
 			}
-
-			want := []string{
-				"kind",
-				"metadata.master",
-				"spec.containers[0].image",
-				"spec.volumes[0].configMap.items",
-				"spec.volumes[1].name",
+			if err.Error() != loadErr.Error() {
+				t.Errorf("\nwant %v\ngot %v", loadErr, err)
 			}
-
-			fieldErrs := err.(fieldErrors)
-
-			if len(want) != len(fieldErrs) {
-				t.Fatalf("\nwant len(fieldErrs) == %d, got %d\nerrs: %+v\n", len(want), len(fieldErrs), fieldErrs)
+			if _, ok := err.(fieldErrors); !ok {
+				t.Errorf("want fieldErrors, got %T", err)
 			}
-
-			for _, field := range want {
-				if _, ok := fieldErrs[field]; !ok {
-					t.Errorf("want %s in fieldErrs, got %+v", field, fieldErrs)
-				}
+			if !reflect.DeepEqual(cfg, Pod{}) {
+				t.Errorf("cfg was modified: %+v", cfg)
 			}
 		})
 	}
+
+	t.Run("valid", func(t *testing.T) {
+		var cfg Pod
+		if err := Check(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid"))); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !reflect.DeepEqual(cfg, Pod{}) {
+			t.Errorf("cfg was modified: %+v", cfg)
+		}
+	})
+
+	t.Run("preset values", func(t *testing.T) {
+		type Config struct {
+			Name string   `conf:"name" validate:"required"`
+			Tags []string `conf:"tags"`
+		}
+
+		cfg := Config{Name: "preset", Tags: []string{"a"}}
+		if err := Check(&cfg, String(`tags: [b]`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if want := (Config{Name: "preset", Tags: []string{"a"}}); !reflect.DeepEqual(want, cfg) {
+			t.Errorf("cfg was modified: %+v", cfg)
+		}
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		if err := Check(Pod{}); err == nil || !strings.Contains(err.Error(), "pointer") {
+			t.Errorf("expected struct pointer err, got %v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_MultiDocumentYAML(t *testing.T) {
+	type Server struct {
+		Host   string `conf:"host"`
+		Logger struct {
+			LogLevel string `conf:"log_level"`
+			Appender string `conf:"appender"`
+		} `conf:"logger"`
+		Replicas []string `conf:"replicas"`
+	}
+
+	var want Server
+	want.Host = "0.0.0.0"
+	want.Logger.LogLevel = "debug"
+	want.Logger.Appender = "file"
+	want.Replicas = []string{"def"}
+
+	var cfg Server
+	if err := Load(&cfg, File("layered.yaml"), Dirs(filepath.Join("testdata", "valid"))); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	t.Run("invalid document", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, String("host: a\n---\nhost: [b", DecoderYaml)); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
 }
 
 func Test_confucius_Load_Defaults(t *testing.T) {
 	t.Run("non-zero values are not overridden", func(t *testing.T) {
-		for _, f := range []string{"server.yaml", "server.json", "server.toml"} {
+		for _, f := range []string{"server.yaml", "server.json", "server.toml", "server.ini", "server.properties"} {
 			t.Run(f, func(t *testing.T) {
 				type Server struct {
 					Host   string `conf:"host" default:"127.0.0.1"`
@@ -499,72 +2122,416 @@ func Test_confucius_Load_Defaults(t *testing.T) {
 					}
 				}
 
-				var want Server
-				want.Host = "0.0.0.0"
-				want.Ports = []int{80, 443}
-				want.Logger.LogLevel = "debug"
-				want.Logger.Production = false
-				want.Logger.Metadata.Keys = []string{"ts"}
-				want.Application.BuildDate = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+				var want Server
+				want.Host = "0.0.0.0"
+				want.Ports = []int{80, 443}
+				want.Logger.LogLevel = "debug"
+				want.Logger.Production = false
+				want.Logger.Metadata.Keys = []string{"ts"}
+				want.Application.BuildDate = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+				var cfg Server
+				err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+
+				if !reflect.DeepEqual(want, cfg) {
+					t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+				}
+			})
+		}
+	})
+
+	t.Run("bad defaults reported as errors", func(t *testing.T) {
+		for _, f := range []string{"server.yaml", "server.json", "server.toml"} {
+			t.Run(f, func(t *testing.T) {
+				type Server struct {
+					Host   string `conf:"host" default:"127.0.0.1"`
+					Ports  []int  `conf:"ports" default:"[80,not-a-port]"`
+					Logger struct {
+						LogLevel string `conf:"log_level" default:"info"`
+						Metadata struct {
+							Keys []string `conf:"keys" validate:"required"`
+						}
+					}
+					Application struct {
+						BuildDate time.Time `conf:"build_date" default:"not-a-time"`
+					}
+				}
+
+				var cfg Server
+				err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
+				if err == nil {
+					t.Fatalf("expected err")
+				}
+
+				want := []string{
+					"ports",
+					"Logger.Metadata.keys",
+					"Application.build_date",
+				}
+
+				fieldErrs := err.(fieldErrors)
+
+				if len(want) != len(fieldErrs) {
+					t.Fatalf("\nlen(fieldErrs) != %d\ngot %+v\n", len(want), fieldErrs)
+				}
+
+				for _, field := range want {
+					if _, ok := fieldErrs[field]; !ok {
+						t.Errorf("want %s in fieldErrs, got %+v", field, fieldErrs)
+					}
+				}
+			})
+		}
+	})
+}
+
+func Test_confucius_Load_OptionalPointerStruct(t *testing.T) {
+	type Container struct {
+		Name      string `conf:"name" validate:"required"`
+		Resources struct {
+			Requests *struct {
+				Memory string `conf:"memory" validate:"required"`
+				CPU    string `conf:"cpu" default:"250m"`
+			} `conf:"requests"`
+		} `conf:"resources"`
+	}
+
+	t.Run("nil parent skips required children", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "RESOURCES_REQUESTS_CPU", "500m")
+
+		var cfg Container
+		if err := Load(&cfg, String(`name: app`, DecoderYaml), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Resources.Requests != nil {
+			t.Fatalf("cfg.Resources.Requests == %+v, expected nil", cfg.Resources.Requests)
+		}
+	})
+
+	t.Run("present parent validates children", func(t *testing.T) {
+		var cfg Container
+		err := Load(&cfg, String(`{name: app, resources: {requests: {cpu: 1}}}`, DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		errs := err.(fieldErrors)
+		if len(errs) != 1 {
+			t.Fatalf("want 1 field error, got %+v", errs)
+		}
+		if _, ok := errs["resources.requests.memory"]; !ok {
+			t.Fatalf("want resources.requests.memory in fieldErrors, got %+v", errs)
+		}
+	})
+
+	t.Run("empty parent validates children", func(t *testing.T) {
+		var cfg Container
+		err := Load(&cfg, String(`{name: app, resources: {requests: {}}}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["resources.requests.memory"]; !ok {
+			t.Fatalf("want resources.requests.memory in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("complete parent", func(t *testing.T) {
+		var cfg Container
+		err := Load(&cfg, String(`{name: app, resources: {requests: {memory: 64Mi}}}`, DecoderYaml))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Resources.Requests.Memory != "64Mi" || cfg.Resources.Requests.CPU != "250m" {
+			t.Fatalf("unexpected requests: %+v", cfg.Resources.Requests)
+		}
+	})
+}
+
+func Test_confucius_Load_MaxFileSize(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: "+strings.Repeat("a", 100))
+
+	t.Run("file within limit", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, Dirs(dir), MaxFileSize(106)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
+	t.Run("file exceeding limit", func(t *testing.T) {
+		var cfg Server
+		err := Load(&cfg, Dirs(dir), MaxFileSize(105))
+		if !errors.Is(err, ErrFileTooLarge) {
+			t.Fatalf("want err %v, got %v", ErrFileTooLarge, err)
+		}
+		if !strings.Contains(err.Error(), "config.yaml") {
+			t.Fatalf("want file name in err, got %v", err)
+		}
+	})
+
+	t.Run("reader exceeding limit", func(t *testing.T) {
+		var cfg Server
+		err := Load(&cfg, String("host: "+strings.Repeat("a", 100), DecoderYaml), MaxFileSize(64))
+		if !errors.Is(err, ErrFileTooLarge) {
+			t.Fatalf("want err %v, got %v", ErrFileTooLarge, err)
+		}
+	})
+
+	t.Run("embedded file exceeding limit", func(t *testing.T) {
+		var cfg Pod
+		err := Load(&cfg, File("pod.yaml"), EmbedFS(embedFS), Dirs(t.TempDir()), MaxFileSize(64))
+		if !errors.Is(err, ErrFileTooLarge) {
+			t.Fatalf("want err %v, got %v", ErrFileTooLarge, err)
+		}
+	})
+}
+
+func Test_confucius_Load_DisableEnvExpansion(t *testing.T) {
+	type Config struct {
+		Script string   `conf:"script"`
+		Args   []string `conf:"args"`
+		Addr   string   `conf:"addr" format:"hostport"`
+	}
+
+	os.Clearenv()
+	setenv(t, "FOO", "bar")
+	setenv(t, "ADDR", "10.0.0.1:80")
+
+	content := `{script: "echo ${FOO}", args: ["${FOO:baz}"], addr: "${ADDR}"}`
+
+	t.Run("expanded by default", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(content, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Script: "echo bar", Args: []string{"bar"}, Addr: "10.0.0.1:80"}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{script: "echo ${FOO}", args: ["${FOO:baz}"]}`, DecoderYaml), DisableEnvExpansion())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Script: "echo ${FOO}", Args: []string{"${FOO:baz}"}}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("disabled before format", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`addr: "${ADDR}"`, DecoderYaml), DisableEnvExpansion())
+		if _, ok := err.(fieldErrors)["addr"]; !ok {
+			t.Fatalf("want addr in fieldErrors, got %+v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_EnvOverrideExpansion(t *testing.T) {
+	type Config struct {
+		URL    string            `conf:"url"`
+		Labels map[string]string `conf:"labels"`
+	}
+
+	os.Clearenv()
+	setenv(t, "SCHEME", "https")
+	setenv(t, "MYAPP_URL", "${SCHEME}://host:${PORT:443}")
+	setenv(t, "MYAPP_LABELS_scheme", "${SCHEME}")
+
+	t.Run("expanded", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("myapp")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{URL: "https://host:443", Labels: map[string]string{"scheme": "https"}}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("myapp"), DisableEnvExpansion()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{URL: "${SCHEME}://host:${PORT:443}", Labels: map[string]string{"scheme": "${SCHEME}"}}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		setenv(t, "MYAPP_URL", "${}://host")
 
-				var cfg Server
-				err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
-				if err != nil {
-					t.Fatalf("unexpected err: %v", err)
-				}
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("myapp"))
+		if _, ok := err.(fieldErrors)["url"]; !ok {
+			t.Fatalf("want url in fieldErrors, got %+v", err)
+		}
+	})
+}
 
-				if !reflect.DeepEqual(want, cfg) {
-					t.Errorf("\nwant %+v\ngot %+v", want, cfg)
-				}
-			})
+func Test_confucius_Load_EmptySentinel(t *testing.T) {
+	type Config struct {
+		Name     string `conf:"name" empty:"none" default:"anonymous"`
+		Replicas int    `conf:"replicas" empty:"-1" default:"3"`
+		Timeout  *int   `conf:"timeout" empty:"none"`
+		Comment  string `conf:"comment" empty:"none"`
+		Tags     []string
+	}
+
+	t.Run("file", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{name: none, replicas: -1, timeout: none, comment: nothing, tags: [none]}`, DecoderYaml))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Name: "anonymous", Replicas: 3, Comment: "nothing", Tags: []string{"none"}}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
 		}
 	})
 
-	t.Run("bad defaults reported as errors", func(t *testing.T) {
-		for _, f := range []string{"server.yaml", "server.json", "server.toml"} {
-			t.Run(f, func(t *testing.T) {
-				type Server struct {
-					Host   string `conf:"host" default:"127.0.0.1"`
-					Ports  []int  `conf:"ports" default:"[80,not-a-port]"`
-					Logger struct {
-						LogLevel string `conf:"log_level" default:"info"`
-						Metadata struct {
-							Keys []string `conf:"keys" validate:"required"`
-						}
-					}
-					Application struct {
-						BuildDate time.Time `conf:"build_date" default:"not-a-time"`
-					}
-				}
+	t.Run("env", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "NAME", "none")
+		setenv(t, "REPLICAS", "-1")
+		setenv(t, "TIMEOUT", "none")
 
-				var cfg Server
-				err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
-				if err == nil {
-					t.Fatalf("expected err")
-				}
+		var cfg Config
+		err := Load(&cfg, String(`{name: app, replicas: 5, timeout: 10}`, DecoderYaml), UseEnv(""))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Name: "anonymous", Replicas: 3}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
 
-				want := []string{
-					"ports",
-					"Logger.Metadata.keys",
-					"Application.build_date",
-				}
+	t.Run("other values", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{name: app, replicas: 0, timeout: 10}`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Name != "app" || cfg.Replicas != 3 || cfg.Timeout == nil || *cfg.Timeout != 10 {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
 
-				fieldErrs := err.(fieldErrors)
+	t.Run("env resets pointer with default", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "TIMEOUT", "none")
 
-				if len(want) != len(fieldErrs) {
-					t.Fatalf("\nlen(fieldErrs) != %d\ngot %+v\n", len(want), fieldErrs)
-				}
+		var cfg struct {
+			Timeout *int `conf:"timeout" empty:"none" default:"30"`
+		}
+		if err := Load(&cfg, String(`{timeout: 10}`, DecoderYaml), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Timeout == nil || *cfg.Timeout != 30 {
+			t.Fatalf("want timeout 30, got %v", cfg.Timeout)
+		}
+	})
 
-				for _, field := range want {
-					if _, ok := fieldErrs[field]; !ok {
-						t.Errorf("want %s in fieldErrs, got %+v", field, fieldErrs)
-					}
-				}
-			})
+	t.Run("env resets required pointer", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "PROXY", "none")
+
+		var cfg struct {
+			Proxy *string `conf:"proxy" empty:"none" validate:"required"`
+		}
+		err := Load(&cfg, String(`{proxy: proxy.local}`, DecoderYaml), UseEnv(""))
+		if _, ok := err.(fieldErrors)["proxy"]; !ok {
+			t.Fatalf("want proxy in fieldErrors, got %+v", err)
 		}
 	})
 }
 
+func Test_confucius_Load_Fallback(t *testing.T) {
+	type Server struct {
+		Host  string `conf:"host" fallback:"127.0.0.1" validate:"required"`
+		Level string `conf:"level" fallback:"info" validate:"required"`
+	}
+
+	os.Clearenv()
+	setenv(t, "LEVEL", "debug")
+
+	var cfg Server
+	var sources map[string]string
+	if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv(""), TrackSources(&sources)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Server{Host: "127.0.0.1", Level: "debug"}
+	if cfg != want {
+		t.Fatalf("want %+v, got %+v", want, cfg)
+	}
+	if sources["host"] != "fallback" {
+		t.Fatalf("sources[host] == %s, expected %s", sources["host"], "fallback")
+	}
+}
+
+func Test_confucius_Load_IgnoreBadDefaults(t *testing.T) {
+	type Server struct {
+		Host   string `conf:"host" default:"127.0.0.1"`
+		Ports  []int  `conf:"ports" default:"[80,not-a-port]"`
+		Logger struct {
+			LogLevel string `conf:"log_level" default:"info"`
+		} `conf:"logger"`
+		Application struct {
+			BuildDate *time.Time `conf:"build_date" default:"not-a-time"`
+			Version   string     `conf:"version" default:"1.0.0"`
+		}
+	}
+
+	var warnings []string
+
+	var cfg Server
+	err := Load(&cfg,
+		File("server.yaml"),
+		Dirs(filepath.Join("testdata", "valid")),
+		IgnoreBadDefaults(),
+		Logger(StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+			if level == WarningLevel {
+				warnings = append(warnings, fmt.Sprint(fields["field"]))
+			}
+		})),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if cfg.Host != "0.0.0.0" {
+		t.Errorf("cfg.Host == %s, expected %s", cfg.Host, "0.0.0.0")
+	}
+	if cfg.Ports != nil {
+		t.Errorf("cfg.Ports == %+v, expected nil", cfg.Ports)
+	}
+	if cfg.Logger.LogLevel != "debug" {
+		t.Errorf("cfg.Logger.LogLevel == %s, expected %s", cfg.Logger.LogLevel, "debug")
+	}
+	if cfg.Application.BuildDate != nil {
+		t.Errorf("cfg.Application.BuildDate == %v, expected nil", cfg.Application.BuildDate)
+	}
+	if cfg.Application.Version != "1.0.0" {
+		t.Errorf("cfg.Application.Version == %s, expected %s", cfg.Application.Version, "1.0.0")
+	}
+
+	want := []string{"ports", "Application.build_date"}
+	if !reflect.DeepEqual(want, warnings) {
+		t.Errorf("want warnings for %+v, got %+v", want, warnings)
+	}
+}
+
 func Test_confucius_Load_RequiredAndDefaults(t *testing.T) {
 	for _, f := range []string{"server.yaml", "server.json", "server.toml"} {
 		t.Run(f, func(t *testing.T) {
@@ -669,9 +2636,151 @@ func Test_confucius_Load_WithOptions(t *testing.T) {
 	}
 }
 
+func Test_confucius_Load_TimeLayouts(t *testing.T) {
+	type Application struct {
+		BuildDate   time.Time  `conf:"build_date"`
+		ReleaseDate time.Time  `conf:"release_date"`
+		PatchDate   *time.Time `conf:"patch_date"`
+		EOLDate     time.Time  `conf:"eol_date" default:"12-31-2030"`
+		Dates       []time.Time
+	}
+
+	os.Clearenv()
+	setenv(t, "APP_PATCH_DATE", "2020-03-01T08:00:00Z")
+	setenv(t, "APP_DATES", "[2020-01-01T00:00:00Z,06-15-2020]")
+
+	var cfg Application
+	err := Load(&cfg,
+		String(`{"build_date": "2020-01-09T12:30:00Z", "release_date": "02-01-2020"}`, DecoderJSON),
+		TimeLayouts(time.RFC3339, "01-02-2006"),
+		UseEnv("app"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Application{
+		BuildDate:   time.Date(2020, 1, 9, 12, 30, 0, 0, time.UTC),
+		ReleaseDate: time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		EOLDate:     time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC),
+		Dates: []time.Time{
+			time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	patchDate := time.Date(2020, 3, 1, 8, 0, 0, 0, time.UTC)
+	want.PatchDate = &patchDate
+
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	t.Run("no layout matches", func(t *testing.T) {
+		os.Clearenv()
+
+		var cfg Application
+		err := Load(&cfg,
+			String(`{"build_date": "Jan 9 2020"}`, DecoderJSON),
+			TimeLayouts(time.RFC3339, "01-02-2006"),
+		)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if !strings.Contains(err.Error(), `"Jan 9 2020"`) || !strings.Contains(err.Error(), "01-02-2006") {
+			t.Errorf("err == %v, expected value and layouts", err)
+		}
+	})
+}
+
+func Test_unixTime(t *testing.T) {
+	want := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		Name string
+		In   int64
+	}{
+		{Name: "seconds", In: 1577836800},
+		{Name: "milliseconds", In: 1577836800000},
+		{Name: "microseconds", In: 1577836800000000},
+		{Name: "nanoseconds", In: 1577836800000000000},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := unixTime(tc.In); !got.Equal(want) {
+				t.Fatalf("want %v, got %v", want, got)
+			}
+		})
+	}
+
+	t.Run("fractional seconds", func(t *testing.T) {
+		got := unixTimeFloat(1577836800.5)
+		if want := want.Add(500 * time.Millisecond); !got.Equal(want) {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	})
+
+	t.Run("fractional milliseconds", func(t *testing.T) {
+		got := unixTimeFloat(1577836800000.5)
+		if want := want.Add(500 * time.Microsecond); !got.Equal(want) {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	})
+}
+
+func Test_confucius_Load_UnixTime(t *testing.T) {
+	type Event struct {
+		Created  time.Time  `conf:"created"`
+		Updated  time.Time  `conf:"updated"`
+		Deleted  *time.Time `conf:"deleted"`
+		Archived time.Time  `conf:"archived" default:"1577836800"`
+		Build    time.Time  `conf:"build"`
+	}
+
+	os.Clearenv()
+	setenv(t, "EVENT_DELETED", "1577836800000")
+
+	var cfg Event
+	err := Load(&cfg,
+		String(`{"created": 1577836800, "updated": 1577836800123, "build": "2020-01-01T00:00:00Z"}`, DecoderJSON),
+		UnixTime(),
+		UseEnv("event"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	epoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := Event{
+		Created:  epoch,
+		Updated:  epoch.Add(123 * time.Millisecond),
+		Deleted:  &epoch,
+		Archived: epoch,
+		Build:    epoch,
+	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	t.Run("yaml seconds", func(t *testing.T) {
+		var cfg Event
+		if err := Load(&cfg, String(`created: 1577836800`, DecoderYaml), UnixTime()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !cfg.Created.Equal(epoch) {
+			t.Errorf("cfg.Created == %v, expected %v", cfg.Created, epoch)
+		}
+	})
+
+	t.Run("timestamps are rejected without option", func(t *testing.T) {
+		var cfg Event
+		if err := Load(&cfg, String(`{"created": 1577836800}`, DecoderJSON)); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
 func Test_confucius_Load_Server_If_Env_Set_In_Conf_File(t *testing.T) {
 	os.Setenv("SERVICE_HOST", "192.168.0.128")
-	for _, f := range []string{"server.yaml", "server.json", "server.toml"} {
+	for _, f := range []string{"server.yaml", "server.json", "server.toml", "server.ini", "server.properties"} {
 		t.Run(f, func(t *testing.T) {
 			type Server struct {
 				Host string `conf:"host"`
@@ -857,7 +2966,7 @@ func Test_confucius_Load_Server_With_Profile_When_Config_Is_Invalid(t *testing.T
 func Test_confucius_decodeFile(t *testing.T) {
 	confucius := defaultConfucius()
 
-	for _, f := range []string{"bad.yaml", "bad.json", "bad.toml"} {
+	for _, f := range []string{"bad.yaml", "bad.json", "bad.toml", "bad.hcl"} {
 		t.Run(f, func(t *testing.T) {
 			file := filepath.Join("testdata", "invalid", f)
 			if !fileExists(file) {
@@ -871,7 +2980,7 @@ func Test_confucius_decodeFile(t *testing.T) {
 	}
 
 	t.Run("unsupported file extension", func(t *testing.T) {
-		file := filepath.Join("testdata", "invalid", "list.hcl")
+		file := filepath.Join("testdata", "invalid", "list.xml")
 		if !fileExists(file) {
 			t.Fatalf("test file %s does not exist", file)
 		}
@@ -936,7 +3045,161 @@ func Test_confucius_decodeMap(t *testing.T) {
 	}
 }
 
-func Test_confucius_processCfg(t *testing.T) {
+func Test_confucius_Load_DecodeHook(t *testing.T) {
+	yesNoHook := func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Bool {
+			return data, nil
+		}
+		switch strings.ToLower(data.(string)) {
+		case "yes":
+			return true, nil
+		case "no":
+			return false, nil
+		}
+		return data, nil
+	}
+
+	type Server struct {
+		Host   string        `conf:"host"`
+		TLS    bool          `conf:"tls"`
+		Debug  bool          `conf:"debug"`
+		Strict bool          `conf:"strict"`
+		Retry  time.Duration `conf:"retry"`
+	}
+
+	var cfg Server
+	err := Load(&cfg,
+		String(`{"host": "localhost", "tls": "yes", "debug": "no", "strict": "true", "retry": "5s"}`, DecoderJSON),
+		DecodeHook(yesNoHook),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Server{Host: "localhost", TLS: true, Strict: true, Retry: 5 * time.Second}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	t.Run("hook errors are returned", func(t *testing.T) {
+		failingHook := func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+			if t.Kind() == reflect.Bool {
+				return nil, fmt.Errorf("no bools allowed")
+			}
+			return data, nil
+		}
+
+		var cfg Server
+		err := Load(&cfg,
+			String(`{"tls": "yes"}`, DecoderJSON),
+			DecodeHook(yesNoHook),
+			DecodeHook(failingHook),
+		)
+		if err == nil || !strings.Contains(err.Error(), "no bools allowed") {
+			t.Fatalf("expected hook err, got %v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_SliceOfMaps(t *testing.T) {
+	type Config struct {
+		Rules   []map[string]string      `conf:"rules"`
+		Plugins []map[string]interface{} `conf:"plugins"`
+		Name    string                   `conf:"name" default:"app"`
+	}
+
+	files := map[string]string{
+		string(DecoderYaml): `
+rules:
+  - match: "/api/*"
+    action: proxy
+  - match: "/static/*"
+    action: serve
+    cache: "1h"
+plugins:
+  - name: auth
+    options:
+      realm: internal
+`,
+		string(DecoderJSON): `{
+  "rules": [
+    {"match": "/api/*", "action": "proxy"},
+    {"match": "/static/*", "action": "serve", "cache": "1h"}
+  ],
+  "plugins": [{"name": "auth", "options": {"realm": "internal"}}]
+}`,
+	}
+
+	for decoder, content := range files {
+		t.Run(decoder, func(t *testing.T) {
+			os.Clearenv()
+			setenv(t, "NAME", "from-env")
+
+			var cfg Config
+			if err := Load(&cfg, String(content, Decoder(decoder)), UseEnv("")); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			want := Config{
+				Rules: []map[string]string{
+					{"match": "/api/*", "action": "proxy"},
+					{"match": "/static/*", "action": "serve", "cache": "1h"},
+				},
+				Plugins: []map[string]interface{}{
+					{"name": "auth", "options": map[string]interface{}{"realm": "internal"}},
+				},
+				Name: "from-env",
+			}
+			if !reflect.DeepEqual(want, cfg) {
+				t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+			}
+		})
+	}
+}
+
+func Test_confucius_processCfg(t *testing.T) {
+	t.Run("slice elements not set by env with DisableEnvSliceBinding", func(t *testing.T) {
+		confucius := defaultConfucius()
+		confucius.tag = "conf"
+		confucius.useEnv = true
+		DisableEnvSliceBinding()(confucius)
+
+		os.Clearenv()
+		setenv(t, "A_0_B", "b0")
+		setenv(t, "A_0_C_D", "9000")
+		setenv(t, "NAME", "app")
+		setenv(t, "PORTS", "[80,443]")
+
+		type elem struct {
+			B string
+			C struct {
+				D int
+			}
+		}
+		cfg := struct {
+			A     []elem
+			Name  string
+			Ports []int
+		}{A: []elem{{B: "boo"}}}
+
+		err := confucius.processCfg(&cfg)
+		if err != nil {
+			t.Fatalf("processCfg() returned unexpected error: %v", err)
+		}
+		if cfg.A[0].B != "boo" {
+			t.Errorf("cfg.A[0].B == %s, expected %s", cfg.A[0].B, "boo")
+		}
+		if cfg.A[0].C.D != 0 {
+			t.Errorf("cfg.A[0].C.D == %d, expected %d", cfg.A[0].C.D, 0)
+		}
+		if cfg.Name != "app" {
+			t.Errorf("cfg.Name == %s, expected %s", cfg.Name, "app")
+		}
+		if !reflect.DeepEqual(cfg.Ports, []int{80, 443}) {
+			t.Errorf("cfg.Ports == %v, expected %v", cfg.Ports, []int{80, 443})
+		}
+	})
+
 	t.Run("slice elements set by env", func(t *testing.T) {
 		confucius := defaultConfucius()
 		confucius.tag = "conf"
@@ -976,6 +3239,147 @@ func Test_confucius_processCfg(t *testing.T) {
 		}
 	})
 
+	t.Run("slice elements grown by env", func(t *testing.T) {
+		confucius := defaultConfucius()
+		confucius.tag = "conf"
+		confucius.useEnv = true
+		confucius.envPrefix = "app"
+
+		os.Clearenv()
+		setenv(t, "APP_SERVERS_0_HOST", "a.local")
+		setenv(t, "APP_SERVERS_2_HOST", "c.local")
+		setenv(t, "APP_SERVERS_2_PORTS_1_NUMBER", "443")
+		setenv(t, "APP_BACKENDS_0_NAME", "db")
+		setenv(t, "APP_SERVERS_X_HOST", "ignored")
+
+		type port struct {
+			Number int
+		}
+		cfg := struct {
+			Servers []struct {
+				Host  string
+				Port  int `default:"80"`
+				Ports []port
+			}
+			Backends []*struct {
+				Name string
+			}
+		}{}
+
+		err := confucius.processCfg(&cfg)
+		if err != nil {
+			t.Fatalf("processCfg() returned unexpected error: %v", err)
+		}
+		if len(cfg.Servers) != 3 {
+			t.Fatalf("len(cfg.Servers) == %d, expected %d", len(cfg.Servers), 3)
+		}
+		if cfg.Servers[0].Host != "a.local" || cfg.Servers[2].Host != "c.local" {
+			t.Errorf("cfg.Servers == %+v, expected hosts a.local and c.local", cfg.Servers)
+		}
+		if cfg.Servers[1].Host != "" || cfg.Servers[1].Port != 80 {
+			t.Errorf("cfg.Servers[1] == %+v, expected defaults", cfg.Servers[1])
+		}
+		if !reflect.DeepEqual(cfg.Servers[2].Ports, []port{{}, {Number: 443}}) {
+			t.Errorf("cfg.Servers[2].Ports == %+v, expected %+v", cfg.Servers[2].Ports, []port{{}, {Number: 443}})
+		}
+		if len(cfg.Backends) != 1 || cfg.Backends[0] == nil || cfg.Backends[0].Name != "db" {
+			t.Errorf("cfg.Backends == %+v, expected one backend named db", cfg.Backends)
+		}
+	})
+
+	t.Run("slice elements not grown by env with DisableEnvSliceBinding", func(t *testing.T) {
+		confucius := defaultConfucius()
+		confucius.tag = "conf"
+		confucius.useEnv = true
+		DisableEnvSliceBinding()(confucius)
+
+		os.Clearenv()
+		setenv(t, "SERVERS_0_HOST", "a.local")
+
+		cfg := struct {
+			Servers []struct {
+				Host string
+			}
+		}{}
+
+		err := confucius.processCfg(&cfg)
+		if err != nil {
+			t.Fatalf("processCfg() returned unexpected error: %v", err)
+		}
+		if len(cfg.Servers) != 0 {
+			t.Errorf("cfg.Servers == %+v, expected empty", cfg.Servers)
+		}
+	})
+
+	t.Run("map entries set by env", func(t *testing.T) {
+		confucius := defaultConfucius()
+		confucius.tag = "conf"
+		confucius.useEnv = true
+		confucius.envPrefix = "app"
+
+		os.Clearenv()
+		setenv(t, "APP_A_LABELS_tier", "1")
+		setenv(t, "APP_A_LABELS_ENV_prod", "2")
+		setenv(t, "APP_A_TIMEOUTS_read", "5s")
+		setenv(t, "APP_A_NODES_0_TAGS_zone", "eu")
+
+		cfg := struct {
+			A struct {
+				Labels   map[string]int           `conf:"labels"`
+				Timeouts map[string]time.Duration `conf:"timeouts"`
+				Servers  map[string]struct {
+					Host string
+				} `conf:"servers"`
+				Nodes []struct {
+					Tags map[string]string `conf:"tags"`
+				} `conf:"nodes"`
+			} `conf:"a"`
+		}{}
+		cfg.A.Labels = map[string]int{"tier": 5, "team": 3}
+		cfg.A.Nodes = make([]struct {
+			Tags map[string]string `conf:"tags"`
+		}, 1)
+
+		err := confucius.processCfg(&cfg)
+		if err != nil {
+			t.Fatalf("processCfg() returned unexpected error: %v", err)
+		}
+
+		wantLabels := map[string]int{"tier": 1, "team": 3, "ENV_prod": 2}
+		if !reflect.DeepEqual(wantLabels, cfg.A.Labels) {
+			t.Errorf("cfg.A.Labels == %+v, expected %+v", cfg.A.Labels, wantLabels)
+		}
+		if cfg.A.Timeouts["read"] != 5*time.Second {
+			t.Errorf("cfg.A.Timeouts == %+v, expected read of %v", cfg.A.Timeouts, 5*time.Second)
+		}
+		if cfg.A.Servers != nil {
+			t.Errorf("cfg.A.Servers == %+v, expected nil", cfg.A.Servers)
+		}
+		if cfg.A.Nodes[0].Tags["zone"] != "eu" {
+			t.Errorf("cfg.A.Nodes[0].Tags == %+v, expected zone of %s", cfg.A.Nodes[0].Tags, "eu")
+		}
+	})
+
+	t.Run("bad map entry set by env", func(t *testing.T) {
+		confucius := defaultConfucius()
+		confucius.useEnv = true
+
+		os.Clearenv()
+		setenv(t, "LABELS_tier", "high")
+
+		cfg := struct {
+			Labels map[string]int
+		}{}
+
+		err := confucius.processCfg(&cfg)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if _, ok := err.(fieldErrors)["Labels"]; !ok {
+			t.Fatalf("want Labels in fieldErrors, got %+v", err)
+		}
+	})
+
 	t.Run("embedded struct set by env", func(t *testing.T) {
 		confucius := defaultConfucius()
 		confucius.useEnv = true
@@ -1127,6 +3531,60 @@ func Test_confucius_processField(t *testing.T) {
 		}
 	})
 
+	t.Run("field with fallback and required", func(t *testing.T) {
+		cfg := struct {
+			X int `conf:"y" fallback:"10" validate:"required"`
+		}{}
+		parent := &field{
+			v:        reflect.ValueOf(&cfg).Elem(),
+			t:        reflect.ValueOf(&cfg).Elem().Type(),
+			sliceIdx: -1,
+		}
+
+		f := newStructField(parent, 0, confucius.tag)
+		err := confucius.processField(f)
+		if err != nil {
+			t.Fatalf("processField() returned unexpected error: %v", err)
+		}
+		if cfg.X != 10 {
+			t.Fatalf("cfg.X == %d, expected %d", cfg.X, 10)
+		}
+	})
+
+	t.Run("field with default and fallback", func(t *testing.T) {
+		cfg := struct {
+			X int `conf:"y" default:"5" fallback:"10"`
+		}{}
+		parent := &field{
+			v:        reflect.ValueOf(&cfg).Elem(),
+			t:        reflect.ValueOf(&cfg).Elem().Type(),
+			sliceIdx: -1,
+		}
+
+		f := newStructField(parent, 0, confucius.tag)
+		err := confucius.processField(f)
+		if err == nil {
+			t.Fatalf("processField() expected error")
+		}
+	})
+
+	t.Run("bad fallback", func(t *testing.T) {
+		cfg := struct {
+			X int `conf:"y" fallback:"ten" validate:"required"`
+		}{}
+		parent := &field{
+			v:        reflect.ValueOf(&cfg).Elem(),
+			t:        reflect.ValueOf(&cfg).Elem().Type(),
+			sliceIdx: -1,
+		}
+
+		f := newStructField(parent, 0, confucius.tag)
+		err := confucius.processField(f)
+		if err == nil {
+			t.Fatalf("processField() expected error")
+		}
+	})
+
 	t.Run("field overwritten by env", func(t *testing.T) {
 		confucius := defaultConfucius()
 		confucius.tag = "conf"
@@ -1190,7 +3648,7 @@ func Test_confucius_setFromEnv(t *testing.T) {
 	fv := reflect.ValueOf(&s)
 
 	os.Clearenv()
-	err := confucius.setFromEnv(fv, "config.string")
+	err := confucius.setFromEnv(fv, structTag{}, "config.string")
 	if err != nil {
 		t.Fatalf("setFromEnv() unexpected error: %v", err)
 	}
@@ -1199,7 +3657,7 @@ func Test_confucius_setFromEnv(t *testing.T) {
 	}
 
 	setenv(t, "CONFUCIUS_CONFIG_STRING", "goroutine")
-	err = confucius.setFromEnv(fv, "config.string")
+	err = confucius.setFromEnv(fv, structTag{}, "config.string")
 	if err != nil {
 		t.Fatalf("setFromEnv() unexpected error: %v", err)
 	}
@@ -1249,12 +3707,227 @@ func Test_confucius_formatEnvKey(t *testing.T) {
 	}
 }
 
+func Test_confucius_envKey(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		env    string
+		prefix string
+		opts   []Option
+		want   string
+	}{
+		{name: "computed", prefix: "myapp", want: "MYAPP_SERVER_HOST"},
+		{name: "pinned", env: "SERVICE_HOST", want: "SERVICE_HOST"},
+		{name: "pinned ignores prefix", env: "SERVICE_HOST", prefix: "myapp", want: "SERVICE_HOST"},
+		{name: "pinned with prefix", env: "SERVICE_HOST", prefix: "myapp", opts: []Option{PrefixEnvTags()}, want: "MYAPP_SERVICE_HOST"},
+		{name: "pinned without prefix", env: "SERVICE_HOST", opts: []Option{PrefixEnvTags()}, want: "SERVICE_HOST"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			confucius := New(tc.opts...).c
+			confucius.envPrefix = tc.prefix
+			got := confucius.envKey("server.host", structTag{env: tc.env})
+			if got != tc.want {
+				t.Errorf("envKey() == %s, expected %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_EnvPrefixTag(t *testing.T) {
+	type Config struct {
+		Database struct {
+			Host    string `conf:"host"`
+			User    string `conf:"user" env:"PGUSER"`
+			Replica struct {
+				Host string `conf:"host"`
+			} `conf:"replica"`
+			Pool struct {
+				Size int `conf:"size"`
+			} `conf:"pool" envprefix:"POOL"`
+			Shards []struct {
+				Host string `conf:"host"`
+			} `conf:"shards"`
+		} `conf:"database" envprefix:"DB"`
+		Name string `conf:"name"`
+	}
+
+	os.Clearenv()
+	setenv(t, "DB_HOST", "db.local")
+	setenv(t, "MYAPP_DATABASE_HOST", "ignored")
+	setenv(t, "PGUSER", "admin")
+	setenv(t, "DB_REPLICA_HOST", "replica.local")
+	setenv(t, "POOL_SIZE", "10")
+	setenv(t, "DB_SHARDS_0_HOST", "shard0.local")
+	setenv(t, "MYAPP_DB_HOST", "prefixed.local")
+	setenv(t, "MYAPP_NAME", "app")
+
+	t.Run("custom prefix", func(t *testing.T) {
+		sources := map[string]string{}
+		var cfg Config
+		err := Load(&cfg, String(`{"database": {"shards": [{"host": "shard"}]}}`, DecoderJSON), UseEnv("myapp"), TrackSources(&sources))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Database.Host != "db.local" {
+			t.Errorf("want host db.local, got %s", cfg.Database.Host)
+		}
+		if cfg.Database.User != "admin" {
+			t.Errorf("want user admin, got %s", cfg.Database.User)
+		}
+		if cfg.Database.Replica.Host != "replica.local" {
+			t.Errorf("want replica host replica.local, got %s", cfg.Database.Replica.Host)
+		}
+		if cfg.Database.Pool.Size != 10 {
+			t.Errorf("want pool size 10, got %d", cfg.Database.Pool.Size)
+		}
+		if cfg.Database.Shards[0].Host != "shard0.local" {
+			t.Errorf("want shard host shard0.local, got %s", cfg.Database.Shards[0].Host)
+		}
+		if cfg.Name != "app" {
+			t.Errorf("want name app, got %s", cfg.Name)
+		}
+		if sources["database.host"] != "env:DB_HOST" {
+			t.Errorf("want source env:DB_HOST, got %s", sources["database.host"])
+		}
+	})
+
+	t.Run("custom prefix with PrefixEnvTags", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("myapp"), PrefixEnvTags()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Database.Host != "prefixed.local" {
+			t.Errorf("want host prefixed.local, got %s", cfg.Database.Host)
+		}
+	})
+}
+
+func Test_confucius_Load_UseEnvOnly(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port" default:"8080"`
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: file.local\nport: 9000")
+
+	os.Clearenv()
+	setenv(t, "MYAPP_HOST", "env.local")
+
+	var cfg Config
+	if err := Load(&cfg, Dirs(dir), UseEnvOnly("myapp")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := (Config{Host: "env.local", Port: 8080}); !reflect.DeepEqual(want, cfg) {
+		t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+	}
+}
+
+func Test_confucius_Load_EnvTag(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Host string `conf:"host" env:"SERVICE_HOST"`
+			Port int    `conf:"port"`
+		} `conf:"server"`
+		Labels map[string]string `conf:"labels" env:"SERVICE_LABELS"`
+	}
+
+	os.Clearenv()
+	setenv(t, "SERVICE_HOST", "10.0.0.1")
+	setenv(t, "MYAPP_SERVICE_HOST", "10.0.0.2")
+	setenv(t, "MYAPP_SERVER_HOST", "10.0.0.3")
+	setenv(t, "MYAPP_SERVER_PORT", "8080")
+	setenv(t, "SERVICE_LABELS_TEAM", "core")
+
+	t.Run("pinned name", func(t *testing.T) {
+		sources := map[string]string{}
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("myapp"), TrackSources(&sources)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Server.Host != "10.0.0.1" || cfg.Server.Port != 8080 {
+			t.Errorf("want host 10.0.0.1 and port 8080, got %+v", cfg.Server)
+		}
+		if cfg.Labels["TEAM"] != "core" {
+			t.Errorf("want labels from SERVICE_LABELS_*, got %v", cfg.Labels)
+		}
+		if sources["server.host"] != "env:SERVICE_HOST" {
+			t.Errorf("want source env:SERVICE_HOST, got %s", sources["server.host"])
+		}
+	})
+
+	t.Run("pinned name with prefix", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml), UseEnv("myapp"), PrefixEnvTags()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Server.Host != "10.0.0.2" {
+			t.Errorf("want host 10.0.0.2, got %s", cfg.Server.Host)
+		}
+	})
+
+	t.Run("requires UseEnv", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Server.Host != "" {
+			t.Errorf("want host unset, got %s", cfg.Server.Host)
+		}
+	})
+}
+
+func Test_confucius_Load_DefaultEnvExpansion(t *testing.T) {
+	type Config struct {
+		Dir     string        `conf:"dir" default:"${APP_HOME}/config"`
+		Cache   string        `conf:"cache" default:"${APP_CACHE:/tmp/cache}"`
+		Port    int           `conf:"port" default:"${APP_PORT:8080}"`
+		Timeout time.Duration `conf:"timeout" fallback:"${APP_TIMEOUT}"`
+	}
+
+	os.Clearenv()
+	setenv(t, "APP_HOME", "/home/app")
+	setenv(t, "APP_TIMEOUT", "5s")
+
+	t.Run("expanded", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Config{Dir: "/home/app/config", Cache: "/tmp/cache", Port: 8080, Timeout: 5 * time.Second}
+		if !reflect.DeepEqual(want, cfg) {
+			t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+		}
+	})
+
+	t.Run("DisableEnvExpansion", func(t *testing.T) {
+		var cfg struct {
+			Dir string `conf:"dir" default:"${APP_HOME}/config"`
+		}
+		if err := Load(&cfg, String(`{}`, DecoderJSON), DisableEnvExpansion()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Dir != "${APP_HOME}/config" {
+			t.Errorf("want the default kept as is, got %s", cfg.Dir)
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		var cfg struct {
+			Dir string `conf:"dir" default:"${}/config"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderJSON))
+		if _, ok := err.(fieldErrors)["dir"]; !ok {
+			t.Fatalf("want dir in fieldErrors, got %+v", err)
+		}
+	})
+}
+
 func Test_confucius_setDefaultValue(t *testing.T) {
 	confucius := defaultConfucius()
 	var b bool
 	fv := reflect.ValueOf(&b).Elem()
 
-	err := confucius.setDefaultValue(fv, "true")
+	err := confucius.setDefaultValue(fv, structTag{}, "true")
 	if err == nil {
 		t.Fatalf("expected err")
 	}
@@ -1263,31 +3936,91 @@ func Test_confucius_setDefaultValue(t *testing.T) {
 func Test_confucius_setValue(t *testing.T) {
 	confucius := defaultConfucius()
 
-	t.Run("nil ptr", func(t *testing.T) {
-		var s *string
-		fv := reflect.ValueOf(&s)
+	t.Run("nil ptr", func(t *testing.T) {
+		var s *string
+		fv := reflect.ValueOf(&s)
+
+		err := confucius.setValue(fv, "bat")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if *s != "bat" {
+			t.Fatalf("want %s, got %s", "bat", *s)
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		var b []byte
+		fv := reflect.ValueOf(&b).Elem()
+
+		if err := confucius.setValue(fv, "aGVsbG8/Pz4+"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if string(b) != "hello??>>" {
+			t.Fatalf("want %q, got %q", "hello??>>", b)
+		}
+
+		if err := confucius.setValue(fv, "aGVsbG8_Pz4-"); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+
+	t.Run("bytes url encoding", func(t *testing.T) {
+		conf := defaultConfucius()
+		ByteEncoding(base64.RawURLEncoding)(conf)
+
+		var b []byte
+		if err := conf.setValue(reflect.ValueOf(&b).Elem(), "aGVsbG8_Pz4-"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if string(b) != "hello??>>" {
+			t.Fatalf("want %q, got %q", "hello??>>", b)
+		}
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		var slice []int
+		fv := reflect.ValueOf(&slice).Elem()
+
+		err := confucius.setValue(fv, "5")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if !reflect.DeepEqual([]int{5}, slice) {
+			t.Fatalf("want %+v, got %+v", []int{5}, slice)
+		}
+	})
+
+	t.Run("ptr to slice", func(t *testing.T) {
+		var slice *[]string
+		fv := reflect.ValueOf(&slice).Elem()
 
-		err := confucius.setValue(fv, "bat")
+		err := confucius.setValue(fv, "[a,b]")
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
 
-		if *s != "bat" {
-			t.Fatalf("want %s, got %s", "bat", *s)
+		if slice == nil || !reflect.DeepEqual([]string{"a", "b"}, *slice) {
+			t.Fatalf("want %+v, got %+v", []string{"a", "b"}, slice)
 		}
 	})
 
-	t.Run("slice", func(t *testing.T) {
-		var slice []int
+	t.Run("slice of ptrs", func(t *testing.T) {
+		var slice []*int
 		fv := reflect.ValueOf(&slice).Elem()
 
-		err := confucius.setValue(fv, "5")
+		err := confucius.setValue(fv, "[1,2]")
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
 
-		if !reflect.DeepEqual([]int{5}, slice) {
-			t.Fatalf("want %+v, got %+v", []int{5}, slice)
+		if len(slice) != 2 || slice[0] == nil || slice[1] == nil {
+			t.Fatalf("want 2 allocated elements, got %+v", slice)
+		}
+		if *slice[0] != 1 || *slice[1] != 2 {
+			t.Fatalf("want [1 2], got [%d %d]", *slice[0], *slice[1])
 		}
 	})
 
@@ -1305,6 +4038,31 @@ func Test_confucius_setValue(t *testing.T) {
 		}
 	})
 
+	t.Run("int8 overflow", func(t *testing.T) {
+		var i int8
+		fv := reflect.ValueOf(&i).Elem()
+
+		err := confucius.setValue(fv, "300")
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Fatalf("want err %v, got %v", strconv.ErrRange, err)
+		}
+		if i != 0 {
+			t.Fatalf("want %d, got %d", 0, i)
+		}
+	})
+
+	t.Run("int8 bounds", func(t *testing.T) {
+		var i int8
+		fv := reflect.ValueOf(&i).Elem()
+
+		if err := confucius.setValue(fv, "-128"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if i != -128 {
+			t.Fatalf("want %d, got %d", -128, i)
+		}
+	})
+
 	t.Run("bool", func(t *testing.T) {
 		var b bool
 		fv := reflect.ValueOf(&b).Elem()
@@ -1367,6 +4125,19 @@ func Test_confucius_setValue(t *testing.T) {
 		}
 	})
 
+	t.Run("uint8 overflow", func(t *testing.T) {
+		var i uint8
+		fv := reflect.ValueOf(&i).Elem()
+
+		err := confucius.setValue(fv, "256")
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Fatalf("want err %v, got %v", strconv.ErrRange, err)
+		}
+		if i != 0 {
+			t.Fatalf("want %d, got %d", 0, i)
+		}
+	})
+
 	t.Run("float", func(t *testing.T) {
 		var f float32
 		fv := reflect.ValueOf(&f).Elem()
@@ -1381,6 +4152,71 @@ func Test_confucius_setValue(t *testing.T) {
 		}
 	})
 
+	t.Run("complex", func(t *testing.T) {
+		var c complex128
+		fv := reflect.ValueOf(&c).Elem()
+
+		err := confucius.setValue(fv, "(1+2i)")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if c != complex(1, 2) {
+			t.Fatalf("want %v, got %v", complex(1, 2), c)
+		}
+	})
+
+	t.Run("complex slice", func(t *testing.T) {
+		var c []complex64
+		fv := reflect.ValueOf(&c).Elem()
+
+		err := confucius.setValue(fv, "[(1+2i),3i,-4.5]")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		want := []complex64{complex(1, 2), complex(0, 3), complex(-4.5, 0)}
+		if !reflect.DeepEqual(want, c) {
+			t.Fatalf("want %v, got %v", want, c)
+		}
+	})
+
+	t.Run("bad complex", func(t *testing.T) {
+		var c complex64
+		fv := reflect.ValueOf(&c).Elem()
+
+		err := confucius.setValue(fv, "(1+2j)")
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+
+	t.Run("float32 rounding", func(t *testing.T) {
+		var f float32
+		fv := reflect.ValueOf(&f).Elem()
+
+		// just below the midpoint of two float32s, a float64 rounds it
+		// to the midpoint which then rounds to the upper float32.
+		err := confucius.setValue(fv, "1.00000017881393432617187499")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if want := math.Float32frombits(0x3f800001); f != want {
+			t.Fatalf("want %v, got %v", want, f)
+		}
+	})
+
+	t.Run("float32 out of range", func(t *testing.T) {
+		var f float32
+		fv := reflect.ValueOf(&f).Elem()
+
+		err := confucius.setValue(fv, "1e39")
+		if !errors.Is(err, strconv.ErrRange) {
+			t.Fatalf("expected err %v, got %v", strconv.ErrRange, err)
+		}
+	})
+
 	t.Run("bad float", func(t *testing.T) {
 		var f float32
 		fv := reflect.ValueOf(&f).Elem()
@@ -1414,7 +4250,7 @@ func Test_confucius_setValue(t *testing.T) {
 			t.Fatalf("unexpected err: %v", err)
 		}
 
-		want, err := time.Parse(confucius.timeLayout, "2020-01-01T00:00:00Z")
+		want, err := time.Parse(DefaultTimeLayout, "2020-01-01T00:00:00Z")
 		if err != nil {
 			t.Fatalf("error parsing time: %v", err)
 		}
@@ -1494,6 +4330,12 @@ func Test_confucius_setSlice(t *testing.T) {
 			WantSlice: &[]string{"a", "b", "c", "d"},
 			Val:       "[a,b,c,d]",
 		},
+		{
+			Name:      "quoted strings",
+			InSlice:   &[]string{},
+			WantSlice: &[]string{"cn=a,ou=b", "c"},
+			Val:       `["cn=a,ou=b","c"]`,
+		},
 		{
 			Name:      "durations",
 			InSlice:   &[]time.Duration{},
@@ -1535,6 +4377,146 @@ func Test_confucius_setSlice(t *testing.T) {
 			t.Fatalf("expected err")
 		}
 	})
+
+	t.Run("custom delimiter", func(t *testing.T) {
+		f := defaultConfucius()
+		SliceDelimiter(";")(f)
+
+		in := &[]string{}
+		if err := f.setSlice(reflect.ValueOf(in).Elem(), "[cn=a,ou=b;cn=c,ou=d]"); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := []string{"cn=a,ou=b", "cn=c,ou=d"}
+		if !reflect.DeepEqual(want, *in) {
+			t.Fatalf("want %+v, got %+v", want, *in)
+		}
+	})
+}
+
+func Test_confucius_Load_Overflow(t *testing.T) {
+	os.Clearenv()
+	setenv(t, "APP_LIMITS_RETRIES", "300")
+	setenv(t, "APP_LIMITS_WORKERS", "-1")
+
+	type Config struct {
+		Limits struct {
+			Retries int8   `conf:"retries"`
+			Workers uint16 `conf:"workers"`
+			Queue   uint8  `conf:"queue" default:"256"`
+		} `conf:"limits"`
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("app"))
+	if err == nil {
+		t.Fatalf("expected err")
+	}
+
+	errs, ok := err.(fieldErrors)
+	if !ok {
+		t.Fatalf("want fieldErrors, got %T", err)
+	}
+	for _, path := range []string{"limits.retries", "limits.queue"} {
+		if !errors.Is(errs[path], strconv.ErrRange) {
+			t.Errorf("want range error for %s, got %v", path, errs[path])
+		}
+	}
+	if _, ok := errs["limits.workers"]; !ok {
+		t.Errorf("want limits.workers in fieldErrors, got %+v", errs)
+	}
+}
+
+func Test_confucius_Load_DottedKey(t *testing.T) {
+	type Config struct {
+		Key    string `conf:"my.dotted.key" validate:"required"`
+		Nested struct {
+			Key string `conf:"key"`
+		} `conf:"my"`
+	}
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		Decoder Decoder
+	}{
+		{Name: "yaml", Content: `{"my.dotted.key": "literal", my: {key: "nested"}}`, Decoder: DecoderYaml},
+		{Name: "json", Content: `{"my.dotted.key": "literal", "my": {"key": "nested"}}`, Decoder: DecoderJSON},
+		{Name: "toml", Content: "\"my.dotted.key\" = \"literal\"\n[my]\nkey = \"nested\"", Decoder: DecoderToml},
+		{Name: "ini", Content: "\"my.dotted.key\" = literal\nmy.key = nested", Decoder: DecoderINI},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			if err := Load(&cfg, String(tc.Content, tc.Decoder)); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if cfg.Key != "literal" {
+				t.Errorf("cfg.Key == %s, expected %s", cfg.Key, "literal")
+			}
+			if cfg.Nested.Key != "nested" {
+				t.Errorf("cfg.Nested.Key == %s, expected %s", cfg.Nested.Key, "nested")
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_PointerSliceDefaults(t *testing.T) {
+	type Volume struct {
+		Name  string    `conf:"name"`
+		Modes *[]string `conf:"modes" default:"[ro,noexec]"`
+	}
+	type Config struct {
+		Hosts   *[]string `conf:"hosts" default:"[a,b]"`
+		Ports   []*int    `conf:"ports" default:"[80,443]"`
+		Volumes []*Volume `conf:"volumes"`
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`volumes: [{name: "data"}, {name: "logs", modes: ["rw"]}]`, DecoderYaml))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if cfg.Hosts == nil || !reflect.DeepEqual([]string{"a", "b"}, *cfg.Hosts) {
+		t.Errorf("cfg.Hosts == %+v, expected %+v", cfg.Hosts, []string{"a", "b"})
+	}
+	if len(cfg.Ports) != 2 || cfg.Ports[0] == nil || cfg.Ports[1] == nil || *cfg.Ports[0] != 80 || *cfg.Ports[1] != 443 {
+		t.Errorf("cfg.Ports == %+v, expected allocated [80 443]", cfg.Ports)
+	}
+	if len(cfg.Volumes) != 2 {
+		t.Fatalf("len(cfg.Volumes) == %d, expected %d", len(cfg.Volumes), 2)
+	}
+	if m := cfg.Volumes[0].Modes; m == nil || !reflect.DeepEqual([]string{"ro", "noexec"}, *m) {
+		t.Errorf("cfg.Volumes[0].Modes == %+v, expected %+v", m, []string{"ro", "noexec"})
+	}
+	if m := cfg.Volumes[1].Modes; m == nil || !reflect.DeepEqual([]string{"rw"}, *m) {
+		t.Errorf("cfg.Volumes[1].Modes == %+v, expected %+v", m, []string{"rw"})
+	}
+}
+
+func Test_confucius_Load_SliceDelimiter(t *testing.T) {
+	os.Clearenv()
+	setenv(t, "APP_HEADERS", "id;name,surname")
+
+	type Config struct {
+		DNs     []string `conf:"dns" default:"[cn=a,ou=b;cn=c,ou=d]"`
+		Headers []string `conf:"headers"`
+		Ports   []int    `conf:"ports" default:"80;443"`
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("app"), SliceDelimiter(";"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Config{
+		DNs:     []string{"cn=a,ou=b", "cn=c,ou=d"},
+		Headers: []string{"id", "name,surname"},
+		Ports:   []int{80, 443},
+	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Fatalf("want %+v, got %+v", want, cfg)
+	}
 }
 
 func Test_confucius_Load_Logger(t *testing.T) {
@@ -1556,6 +4538,7 @@ func Test_confucius_Load_Logger(t *testing.T) {
 
 func Test_confucius_Load_Logger_Callback(t *testing.T) {
 	writer := bytes.NewBufferString("")
+	output := bytes.NewBufferString("")
 
 	var cfg struct{}
 	err := Load(&cfg,
@@ -1565,7 +4548,7 @@ func Test_confucius_Load_Logger_Callback(t *testing.T) {
 			Callback(func(level LogLevel, message, file string, line int) {
 				writer.WriteString(message)
 			}),
-			SetOutput(os.Stdout),
+			SetOutput(output),
 		),
 	)
 
@@ -1576,6 +4559,228 @@ func Test_confucius_Load_Logger_Callback(t *testing.T) {
 	if !strings.Contains(writer.String(), "confucius starting") {
 		t.Fail()
 	}
+	if !strings.Contains(output.String(), "confucius starting") {
+		t.Fail()
+	}
+}
+
+func Test_confucius_Load_Logger_StructuredCallback(t *testing.T) {
+	var events []map[string]interface{}
+
+	var cfg Pod
+	err := Load(&cfg,
+		File("pod.yaml"),
+		Dirs(filepath.Join("testdata", "valid")),
+		Logger(
+			SetLevel(DebugLevel),
+			StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+				if len(fields) > 0 {
+					fields["message"] = message
+					events = append(events, fields)
+				}
+			}),
+		),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	file := filepath.Join("testdata", "valid", "pod.yaml")
+	want := []map[string]interface{}{
+		{"message": "file found", "file": file},
+		{"message": "merge applied", "file": file, "action": "merge"},
+	}
+	if !reflect.DeepEqual(want, events) {
+		t.Fatalf("\nwant %+v\ngot  %+v", want, events)
+	}
+
+	t.Run("validation failed", func(t *testing.T) {
+		var events []map[string]interface{}
+
+		var cfg Pod
+		err := Load(&cfg,
+			String(`metadata: {master: true}`, DecoderYaml),
+			Logger(StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+				if message == "validation failed" && level == ErrorLevel {
+					events = append(events, fields)
+				}
+			})),
+		)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if len(events) != 1 || events[0]["field"] != "kind" || events[0]["error"] == "" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+	})
+}
+
+func Test_confucius_Load_RequireExportedFields(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+		port int    `conf:"port" default:"8080"`
+		name string
+	}
+
+	t.Run("unexported tagged fields are ignored by default", func(t *testing.T) {
+		var cfg Server
+		if err := Load(&cfg, String(`host: "127.0.0.1"`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.port != 0 {
+			t.Errorf("cfg.port == %d, expected %d", cfg.port, 0)
+		}
+	})
+
+	t.Run("unexported tagged fields are reported", func(t *testing.T) {
+		var cfg Server
+		err := Load(&cfg,
+			String(`host: "127.0.0.1"`, DecoderYaml),
+			RequireExportedFields(),
+		)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+
+		fieldErrs := err.(fieldErrors)
+		if len(fieldErrs) != 1 {
+			t.Fatalf("\nlen(fieldErrs) != %d\ngot %+v\n", 1, fieldErrs)
+		}
+		if _, ok := fieldErrs["port"]; !ok {
+			t.Errorf("want port in fieldErrs, got %+v", fieldErrs)
+		}
+	})
+}
+
+func Test_Loader_Reload(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+		Port int    `conf:"port" default:"8080"`
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	writeFile(t, file, "host: \"127.0.0.1\"\nport: 9000")
+
+	loader := New(Dirs(dir))
+
+	var cfg Server
+	if err := loader.Load(&cfg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := (Server{Host: "127.0.0.1", Port: 9000}); !reflect.DeepEqual(want, cfg) {
+		t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	writeFile(t, file, "host: \"0.0.0.0\"")
+
+	if err := loader.Reload(&cfg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := (Server{Host: "0.0.0.0", Port: 8080}); !reflect.DeepEqual(want, cfg) {
+		t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+	}
+
+	t.Run("reader is reused", func(t *testing.T) {
+		loader := New(String(`host: "localhost"`, DecoderYaml))
+
+		for i := 0; i < 2; i++ {
+			var cfg Server
+			if err := loader.Reload(&cfg); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if cfg.Host != "localhost" {
+				t.Fatalf("cfg.Host == %s, expected %s", cfg.Host, "localhost")
+			}
+		}
+	})
+
+	t.Run("load starts from a clean state", func(t *testing.T) {
+		var sources map[string]string
+		loader := New(String(`host: "localhost"`, DecoderYaml), TrackSources(&sources))
+
+		var first Server
+		if err := loader.Load(&first); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		firstSources := sources
+
+		var second Server
+		if err := loader.Load(&second); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if first != second {
+			t.Fatalf("\nwant %+v\ngot %+v", first, second)
+		}
+		if !reflect.DeepEqual(firstSources, sources) {
+			t.Fatalf("\nwant %+v\ngot %+v", firstSources, sources)
+		}
+
+		sources["host"] = "changed"
+		if firstSources["host"] == "changed" {
+			t.Fatalf("sources of different loads share the same map")
+		}
+	})
+
+	t.Run("cfg is untouched on error", func(t *testing.T) {
+		writeFile(t, file, "host: [")
+
+		if err := loader.Reload(&cfg); err == nil {
+			t.Fatalf("expected err")
+		}
+		if want := (Server{Host: "0.0.0.0", Port: 8080}); !reflect.DeepEqual(want, cfg) {
+			t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+		}
+	})
+}
+
+func Test_confucius_Reload(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host" validate:"required"`
+	}
+
+	dir := t.TempDir()
+	conf := defaultConfucius()
+	conf.dirs = []string{dir}
+	conf.profiles = []string{"test"}
+
+	var cfg Server
+	if err := conf.Load(&cfg); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("expected err %v, got %v", ErrFileNotFound, err)
+	}
+	if len(conf.expectedConfigFiles) != 2 {
+		t.Fatalf("len(expectedConfigFiles) == %d, expected %d", len(conf.expectedConfigFiles), 2)
+	}
+
+	conf.reset()
+	if len(conf.expectedConfigFiles) != 0 {
+		t.Fatalf("expectedConfigFiles not reset: %+v", conf.expectedConfigFiles)
+	}
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), `host: "127.0.0.1"`)
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), `host: "10.0.0.1"`)
+
+	for i := 0; i < 2; i++ {
+		if err := conf.Reload(&cfg); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "10.0.0.1" {
+			t.Fatalf("cfg.Host == %s, expected %s", cfg.Host, "10.0.0.1")
+		}
+		if len(conf.expectedConfigFiles) != 0 {
+			t.Fatalf("expectedConfigFiles == %+v, expected none", conf.expectedConfigFiles)
+		}
+	}
+
+	if err := conf.Reload(cfg); err == nil {
+		t.Fatalf("expected err")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() unexpected error: %v", err)
+	}
 }
 
 func setenv(t *testing.T, key, value string) {
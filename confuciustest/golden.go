@@ -0,0 +1,68 @@
+// Package confuciustest provides helpers for testing configuration loaded
+// with confucius.
+package confuciustest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/netologist/confucius"
+)
+
+var update = flag.Bool("update", false, "update the golden files of confuciustest.Golden")
+
+// TB is the subset of testing.TB used by Golden.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Golden loads the configuration into cfg using the given options and
+// compares its serialization, as produced by confucius.Dump, against the
+// contents of the golden file at goldenPath.
+//
+//	func TestConfig(t *testing.T) {
+//	  var cfg Config
+//	  confuciustest.Golden(t, &cfg, "testdata/config.golden", confucius.File("config.yaml"))
+//	}
+//
+// Run the tests with the `-update` flag to write the golden files instead
+// of comparing against them.
+func Golden(t TB, cfg interface{}, goldenPath string, options ...confucius.Option) {
+	t.Helper()
+
+	if err := confucius.Load(cfg, options...); err != nil {
+		t.Fatalf("confucius.Load() unexpected error: %v", err)
+		return
+	}
+
+	got, err := confucius.Dump(cfg, options...)
+	if err != nil {
+		t.Fatalf("confucius.Dump() unexpected error: %v", err)
+		return
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("unable to create golden file dir: %v", err)
+			return
+		}
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("unable to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("unable to read golden file (run with -update to create it): %v", err)
+		return
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("config does not match golden file %s\nwant:\n%s\ngot:\n%s", goldenPath, want, got)
+	}
+}
@@ -0,0 +1,89 @@
+package confuciustest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netologist/confucius"
+)
+
+type Server struct {
+	Host   string `conf:"host"`
+	Ports  []int  `conf:"ports"`
+	Logger struct {
+		Level string `conf:"level"`
+		Trace bool   `conf:"trace"`
+	} `conf:"logger"`
+	Timeout time.Duration `conf:"timeout" default:"30s"`
+}
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func Test_Golden(t *testing.T) {
+	var cfg Server
+	Golden(t, &cfg, filepath.Join("testdata", "server.golden"),
+		confucius.File("server.yaml"),
+		confucius.Dirs("testdata"),
+	)
+
+	t.Run("mismatch is reported", func(t *testing.T) {
+		var rec recorder
+		var cfg Server
+		Golden(&rec, &cfg, filepath.Join("testdata", "server.golden"),
+			confucius.String(`host: "127.0.0.1"`, confucius.DecoderYaml),
+		)
+
+		if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "does not match golden file") {
+			t.Fatalf("expected mismatch error, got %+v", rec.errors)
+		}
+	})
+
+	t.Run("missing golden file is reported", func(t *testing.T) {
+		var rec recorder
+		var cfg Server
+		Golden(&rec, &cfg, filepath.Join(t.TempDir(), "missing.golden"),
+			confucius.String(`host: "127.0.0.1"`, confucius.DecoderYaml),
+		)
+
+		if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "-update") {
+			t.Fatalf("expected missing golden file error, got %+v", rec.errors)
+		}
+	})
+
+	t.Run("update writes golden file", func(t *testing.T) {
+		*update = true
+		defer func() { *update = false }()
+
+		golden := filepath.Join(t.TempDir(), "nested", "server.golden")
+
+		var cfg Server
+		Golden(t, &cfg, golden, confucius.String(`host: "127.0.0.1"`, confucius.DecoderYaml))
+
+		content, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !strings.HasPrefix(string(content), "host: 127.0.0.1\n") {
+			t.Fatalf("unexpected golden file content: %s", content)
+		}
+
+		*update = false
+		Golden(t, &cfg, golden, confucius.String(`host: "127.0.0.1"`, confucius.DecoderYaml))
+	})
+}
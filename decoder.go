@@ -1,10 +1,171 @@
 package confucius
 
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
 type Decoder string
 
 const (
 	DecoderYaml Decoder = Decoder(".yaml")
 	DecoderYml          = Decoder(".yml")
 	DecoderJSON         = Decoder(".json")
+	DecoderJSONC        = Decoder(".jsonc")
 	DecoderToml         = Decoder(".toml")
+	DecoderHCL          = Decoder(".hcl")
+	DecoderINI          = Decoder(".ini")
+	DecoderProperties   = Decoder(".properties")
 )
+
+// ConfigDecoder is implemented by types that decode their own config values.
+// When the pointer to a struct, or to any other type, given to Load or held
+// by one of its fields implements ConfigDecoder, DecodeConfig is called with
+// the decoded map of its values instead of binding them by reflection.
+// The map holds the values of the config files and readers, with their
+// environment variable references expanded. The hooks given with DecodeHook
+// run before DecodeConfig. The fields of the decoded value are processed
+// afterwards like any other, so they can still be set from the environment,
+// get defaults and be validated.
+//
+// The name Decoder is taken by the decoders of the file formats.
+type ConfigDecoder interface {
+	DecodeConfig(vals map[string]interface{}) error
+}
+
+var configDecoderType = reflect.TypeOf((*ConfigDecoder)(nil)).Elem()
+
+// configDecoderHookFunc returns a hook that decodes the maps given to the
+// types implementing ConfigDecoder by calling their DecodeConfig method.
+// expand is applied to the strings of the map beforehand, like the hook
+// expanding the environment variables would have.
+func configDecoderHookFunc(expand func(string) (string, error)) mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		var vals map[string]interface{}
+		switch d := data.(type) {
+		case map[string]interface{}:
+			vals = d
+		case decodedObject:
+			vals = d
+		default:
+			return data, nil
+		}
+		if t.Kind() == reflect.Ptr || !reflect.PtrTo(t).Implements(configDecoderType) {
+			return data, nil
+		}
+
+		expanded, err := expandNested(vals, expand)
+		if err != nil {
+			return nil, err
+		}
+
+		v := reflect.New(t)
+		if err := v.Interface().(ConfigDecoder).DecodeConfig(expanded.(map[string]interface{})); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+}
+
+// Unmarshaler is implemented by types that unmarshal their own config
+// values, whatever their shape. When the pointer to a struct, or to any
+// other type, given to Load or held by one of its fields implements
+// Unmarshaler, UnmarshalConf is called with the decoded sub-tree of its
+// value, a map, a slice or a scalar, instead of binding it by reflection,
+// which leaves room for polymorphic configs and version dependent parsing.
+// The strings of raw have their environment variable references expanded.
+// Unmarshaler takes precedence over ConfigDecoder and, like it, the fields
+// of the unmarshaled value are processed afterwards like any other.
+type Unmarshaler interface {
+	UnmarshalConf(raw interface{}) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// unmarshalerHookFunc returns a hook that unmarshals the values given to
+// the types implementing Unmarshaler by calling their UnmarshalConf method.
+// expand is applied to the strings of the values beforehand, like the hook
+// expanding the environment variables would have.
+func unmarshalerHookFunc(expand func(string) (string, error)) mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t.Kind() == reflect.Ptr || !reflect.PtrTo(t).Implements(unmarshalerType) {
+			return data, nil
+		}
+
+		raw, err := expandNested(data, expand)
+		if err != nil {
+			return nil, err
+		}
+
+		v := reflect.New(t)
+		if err := v.Interface().(Unmarshaler).UnmarshalConf(raw); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+}
+
+// expandNested calls expandValues on the maps and slices given to a hook.
+// A string is returned as is, the hook expanding the environment variables
+// runs first and has already expanded it, and expanding it again would
+// substitute the references that came from the environment.
+func expandNested(val interface{}, expand func(string) (string, error)) (interface{}, error) {
+	if _, ok := val.(string); ok {
+		return val, nil
+	}
+	return expandValues(val, expand)
+}
+
+// expandValues returns a copy of val in which expand is applied to every
+// string, including the ones nested in maps and slices.
+func expandValues(val interface{}, expand func(string) (string, error)) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		return expand(v)
+	case decodedObject:
+		return expandValues(map[string]interface{}(v), expand)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			expanded, err := expandValues(elem, expand)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = expanded
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			expanded, err := expandValues(elem, expand)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = expanded
+		}
+		return list, nil
+	}
+	return val, nil
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// rawMessageHookFunc returns a hook that encodes the values given to
+// json.RawMessage fields as json, keeping sub-trees such as the configs
+// of plugins to be decoded later. expand is applied to their strings
+// beforehand, like the hook expanding environment variables would have.
+func rawMessageHookFunc(expand func(string) (string, error)) mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != rawMessageType {
+			return data, nil
+		}
+
+		expanded, err := expandNested(data, expand)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(expanded)
+	}
+}
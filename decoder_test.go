@@ -0,0 +1,312 @@
+package confucius
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type endpoint struct {
+	Scheme string
+	Host   string
+	Port   int
+}
+
+func (e *endpoint) DecodeConfig(vals map[string]interface{}) error {
+	raw, ok := vals["url"].(string)
+	if !ok {
+		return fmt.Errorf("url is missing")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	e.Scheme, e.Host = u.Scheme, u.Hostname()
+	if e.Port, err = strconv.Atoi(u.Port()); err != nil {
+		return fmt.Errorf("invalid port: %w", err)
+	}
+	return nil
+}
+
+type rootDecoder struct {
+	Keys []string
+}
+
+func (r *rootDecoder) DecodeConfig(vals map[string]interface{}) error {
+	for key := range vals {
+		r.Keys = append(r.Keys, key)
+	}
+	return nil
+}
+
+type diskStorage struct {
+	Path string
+}
+
+type s3Storage struct {
+	Bucket string
+	Region string
+}
+
+// storage decodes into disk or s3 according to its type key.
+type storage struct {
+	Type string
+	Disk *diskStorage
+	S3   *s3Storage
+}
+
+func (s *storage) UnmarshalConf(raw interface{}) error {
+	vals, ok := raw.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("storage must be an object, got %T", raw)
+	}
+	s.Type, _ = vals["type"].(string)
+	switch s.Type {
+	case "disk":
+		path, _ := vals["path"].(string)
+		s.Disk = &diskStorage{Path: path}
+	case "s3":
+		bucket, _ := vals["bucket"].(string)
+		region, _ := vals["region"].(string)
+		s.S3 = &s3Storage{Bucket: bucket, Region: region}
+	default:
+		return fmt.Errorf("unknown storage type %q", s.Type)
+	}
+	return nil
+}
+
+// version accepts both numbers and strings, e.g. 2 and "v2".
+type version int
+
+func (v *version) UnmarshalConf(raw interface{}) error {
+	s := strings.TrimPrefix(fmt.Sprint(raw), "v")
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid version %v", raw)
+	}
+	*v = version(n)
+	return nil
+}
+
+// label keeps the string it is given as is.
+type label string
+
+func (l *label) UnmarshalConf(raw interface{}) error {
+	*l = label(fmt.Sprint(raw))
+	return nil
+}
+
+func Test_confucius_Load_ExpandOnce(t *testing.T) {
+	type Config struct {
+		Label label           `conf:"label"`
+		Note  json.RawMessage `conf:"note"`
+	}
+
+	os.Clearenv()
+	setenv(t, "TOKEN", "a${X}b")
+	setenv(t, "X", "expanded")
+
+	var cfg Config
+	if err := Load(&cfg, String(`{"label": "${TOKEN}", "note": "${TOKEN}"}`, DecoderJSON)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Label != "a${X}b" {
+		t.Errorf("want label a${X}b, got %s", cfg.Label)
+	}
+	if string(cfg.Note) != `"a${X}b"` {
+		t.Errorf("want note \"a${X}b\", got %s", cfg.Note)
+	}
+}
+
+func Test_confucius_Load_Unmarshaler(t *testing.T) {
+	type Config struct {
+		Name     string     `conf:"name"`
+		Version  version    `conf:"version"`
+		Primary  storage    `conf:"primary"`
+		Backups  []storage  `conf:"backups"`
+		Archive  *storage   `conf:"archive"`
+		Versions []*version `conf:"versions"`
+	}
+
+	os.Clearenv()
+	setenv(t, "BUCKET", "backups")
+
+	t.Run("discriminator", func(t *testing.T) {
+		var cfg Config
+		content := `{
+			"name": "app",
+			"version": "v2",
+			"primary": {"type": "disk", "path": "/data"},
+			"backups": [{"type": "s3", "bucket": "${BUCKET}", "region": "eu-west-1"}, {"type": "disk", "path": "/mnt"}],
+			"archive": {"type": "s3", "bucket": "archive"},
+			"versions": [1, "v3"]
+		}`
+		if err := Load(&cfg, String(content, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Name != "app" || cfg.Version != 2 {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+		if cfg.Primary.Type != "disk" || cfg.Primary.Disk == nil || cfg.Primary.Disk.Path != "/data" || cfg.Primary.S3 != nil {
+			t.Fatalf("unexpected primary %+v", cfg.Primary)
+		}
+		if len(cfg.Backups) != 2 {
+			t.Fatalf("want 2 backups, got %+v", cfg.Backups)
+		}
+		if s3 := cfg.Backups[0].S3; s3 == nil || *s3 != (s3Storage{Bucket: "backups", Region: "eu-west-1"}) {
+			t.Fatalf("unexpected backup %+v", cfg.Backups[0])
+		}
+		if disk := cfg.Backups[1].Disk; disk == nil || disk.Path != "/mnt" {
+			t.Fatalf("unexpected backup %+v", cfg.Backups[1])
+		}
+		if cfg.Archive == nil || cfg.Archive.S3 == nil || cfg.Archive.S3.Bucket != "archive" {
+			t.Fatalf("unexpected archive %+v", cfg.Archive)
+		}
+		if len(cfg.Versions) != 2 || *cfg.Versions[0] != 1 || *cfg.Versions[1] != 3 {
+			t.Fatalf("unexpected versions %+v", cfg.Versions)
+		}
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		var cfg Config
+		content := "version = 4\n[primary]\ntype = \"s3\"\nbucket = \"b\"\n"
+		if err := Load(&cfg, String(content, DecoderToml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Version != 4 || cfg.Primary.S3 == nil || cfg.Primary.S3.Bucket != "b" {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{"primary": {"type": "tape"}}`, DecoderJSON))
+		if err == nil || !strings.Contains(err.Error(), `unknown storage type "tape"`) {
+			t.Fatalf("want unknown storage type err, got %v", err)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		var cfg storage
+		if err := Load(&cfg, String(`{"type": "disk", "path": "/root"}`, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Disk == nil || cfg.Disk.Path != "/root" {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_ConfigDecoder(t *testing.T) {
+	type Config struct {
+		Name     string    `conf:"name"`
+		API      endpoint  `conf:"api"`
+		Fallback *endpoint `conf:"fallback"`
+		Port     int       `conf:"port" default:"80"`
+	}
+
+	os.Clearenv()
+	setenv(t, "API_HOST", "api.example.com")
+
+	t.Run("nested", func(t *testing.T) {
+		var cfg Config
+		content := `{name: app, api: {url: "https://${API_HOST}:8443"}, fallback: {url: "http://localhost:8080"}}`
+		if err := Load(&cfg, String(content, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := endpoint{Scheme: "https", Host: "api.example.com", Port: 8443}
+		if cfg.Name != "app" || cfg.API != want || cfg.Port != 80 {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+		if cfg.Fallback == nil || *cfg.Fallback != (endpoint{Scheme: "http", Host: "localhost", Port: 8080}) {
+			t.Fatalf("unexpected fallback %+v", cfg.Fallback)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{api: {url: "https://host:port"}}`, DecoderYaml)); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		var cfg rootDecoder
+		if err := Load(&cfg, String(`{a: 1}`, DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if len(cfg.Keys) != 1 || cfg.Keys[0] != "a" {
+			t.Fatalf("unexpected keys %+v", cfg.Keys)
+		}
+	})
+}
+
+func Test_confucius_Load_RawMessage(t *testing.T) {
+	type Config struct {
+		Name    string                     `conf:"name"`
+		Plugins map[string]json.RawMessage `conf:"plugins"`
+		Extra   *json.RawMessage           `conf:"extra"`
+		Env     json.RawMessage            `conf:"env"`
+	}
+
+	type cachePlugin struct {
+		Size int      `json:"size"`
+		Dirs []string `json:"dirs"`
+	}
+
+	os.Clearenv()
+	setenv(t, "CACHE_DIR", "/var/cache")
+
+	for _, f := range []string{"config.yaml", "config.json", "config.toml"} {
+		t.Run(f, func(t *testing.T) {
+			dir := t.TempDir()
+			content := map[string]string{
+				"config.yaml": "name: app\nplugins:\n  cache: {size: 10, dirs: [\"${CACHE_DIR}\", /tmp]}\nextra: [1, 2]",
+				"config.json": `{"name": "app", "plugins": {"cache": {"size": 10, "dirs": ["${CACHE_DIR}", "/tmp"]}}, "extra": [1, 2]}`,
+				"config.toml": "name = \"app\"\nextra = [1, 2]\n[plugins.cache]\nsize = 10\ndirs = [\"${CACHE_DIR}\", \"/tmp\"]",
+			}[f]
+			writeFile(t, filepath.Join(dir, f), content)
+
+			var cfg Config
+			if err := Load(&cfg, Dirs(dir), File(f)); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			var cache cachePlugin
+			if err := json.Unmarshal(cfg.Plugins["cache"], &cache); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			want := cachePlugin{Size: 10, Dirs: []string{"/var/cache", "/tmp"}}
+			if !reflect.DeepEqual(want, cache) {
+				t.Fatalf("want %+v, got %+v", want, cache)
+			}
+			if cfg.Extra == nil || string(*cfg.Extra) != "[1,2]" {
+				t.Fatalf("unexpected extra %s", cfg.Extra)
+			}
+		})
+	}
+
+	t.Run("env", func(t *testing.T) {
+		setenv(t, "ENV", `{"debug": true}`)
+
+		var cfg Config
+		if err := Load(&cfg, String(`name: app`, DecoderYaml), UseEnv("")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if string(cfg.Env) != `{"debug": true}` {
+			t.Fatalf("unexpected env %s", cfg.Env)
+		}
+
+		setenv(t, "ENV", `{debug`)
+		err := Load(&cfg, String(`name: app`, DecoderYaml), UseEnv(""))
+		if _, ok := err.(fieldErrors)["env"]; !ok {
+			t.Fatalf("want env in fieldErrors, got %+v", err)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// diagnosedValue returns the value of f as it is compared by diagnostics.
+// Secrets are not masked, logChange masks them when they are logged.
+func (c *confucius) diagnosedValue(f *field) string {
+	v := f.orig
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// logChange logs the change of the value of f made by source, e.g. env,
+// when diagnostics are enabled. old is the value of f before the change,
+// the value after the change is returned.
+func (c *confucius) logChange(f *field, source, old string) string {
+	if !c.diagnostics {
+		return old
+	}
+	val := c.diagnosedValue(f)
+	if val != old {
+		loggedOld, loggedNew := old, val
+		if c.masked(f.structTag, f.v) {
+			loggedOld, loggedNew = secretMask, secretMask
+		}
+		c.logger.Event(DebugLevel, map[string]interface{}{
+			"field":  f.path(),
+			"source": source,
+			"old":    loggedOld,
+			"new":    loggedNew,
+		}, "field changed")
+	}
+	return val
+}
@@ -0,0 +1,62 @@
+package confucius
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_confucius_Load_Diagnostics(t *testing.T) {
+	type Config struct {
+		Host     string `conf:"host"`
+		Port     int    `conf:"port" default:"8080"`
+		Level    string `conf:"level"`
+		Password string `conf:"password" secret:"true"`
+	}
+
+	os.Clearenv()
+	setenv(t, "MYAPP_HOST", "0.0.0.0")
+	setenv(t, "MYAPP_LEVEL", "info")
+	setenv(t, "MYAPP_PASSWORD", "hunter2")
+
+	load := func(t *testing.T, options ...Option) []map[string]interface{} {
+		t.Helper()
+
+		var changes []map[string]interface{}
+		callback := StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+			if message == "field changed" {
+				if level != DebugLevel {
+					t.Errorf("want %s, got %s", DebugLevel, level)
+				}
+				changes = append(changes, fields)
+			}
+		})
+
+		var cfg Config
+		options = append(options, String(`{host: localhost, level: info, password: secret}`, DecoderYaml), UseEnv("myapp"), Logger(callback))
+		if err := Load(&cfg, options...); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		return changes
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		// level is set to the same value so it is not logged as
+		// changed, the password is logged masked.
+		want := []map[string]interface{}{
+			{"field": "host", "source": "env", "old": "localhost", "new": "0.0.0.0"},
+			{"field": "port", "source": "default", "old": "0", "new": "8080"},
+			{"field": "password", "source": "env", "old": secretMask, "new": secretMask},
+		}
+
+		if got := load(t, Diagnostics()); !reflect.DeepEqual(want, got) {
+			t.Fatalf("\nwant %+v\ngot  %+v", want, got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if got := load(t); len(got) != 0 {
+			t.Fatalf("want no changes, got %+v", got)
+		}
+	})
+}
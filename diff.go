@@ -0,0 +1,191 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangeType is the kind of a Change between two configs.
+type ChangeType int
+
+const (
+	// ChangeAdded is a value that is zero in the old config and set in the new one.
+	ChangeAdded ChangeType = iota
+	// ChangeRemoved is a value that is set in the old config and zero in the new one.
+	ChangeRemoved
+	// ChangeModified is a value that is set to different values in both configs.
+	ChangeModified
+)
+
+// String returns the name of the change type.
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a difference between two configs found by Diff.
+type Change struct {
+	Path string      // the path of the value, e.g. server.ports[0].
+	Type ChangeType  // whether the value was added, removed or modified.
+	Old  interface{} // the value in the old config, nil if it was added.
+	New  interface{} // the value in the new config, nil if it was removed.
+}
+
+// String formats the change, e.g. "server.host: localhost -> 0.0.0.0".
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %v", c.Path, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %v", c.Path, c.Old)
+	}
+	return fmt.Sprintf("%s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// Diff compares two configs of the same type field by field and returns
+// the values that differ, in the order the fields are defined in the struct.
+// Values are named by their paths like in the errors of Load, so the Tag
+// option affects the paths. The parameters must be structs or pointers to
+// structs.
+//
+//	changes, err := confucius.Diff(&current, &next)
+//	for _, change := range changes {
+//	  fmt.Println(change) // server.host: localhost -> 0.0.0.0
+//	}
+//
+// The values of fields tagged with `secret:"true"` are reported as "****"
+// unless the UnmaskSecrets option is used, a change is still reported when
+// a secret changes.
+func Diff(oldCfg, newCfg interface{}, options ...Option) ([]Change, error) {
+	c := New(options...).c
+
+	ov := reflect.Indirect(reflect.ValueOf(oldCfg))
+	nv := reflect.Indirect(reflect.ValueOf(newCfg))
+	if ov.Kind() != reflect.Struct || nv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("configs must be structs or pointers to structs")
+	}
+	if ov.Type() != nv.Type() {
+		return nil, fmt.Errorf("cannot diff configs of different types %s and %s", ov.Type(), nv.Type())
+	}
+
+	var changes []Change
+	c.diffValues(ov, nv, "", structTag{}, &changes)
+	return changes, nil
+}
+
+// diffValues appends the differences between ov and nv, which have the
+// same type, to changes. st is the tag of the field holding the values.
+func (c *confucius) diffValues(ov, nv reflect.Value, path string, st structTag, changes *[]Change) {
+	for ov.Kind() == reflect.Ptr || ov.Kind() == reflect.Interface {
+		if ov.IsNil() || nv.IsNil() || ov.Elem().Type() != nv.Elem().Type() {
+			c.diffLeaf(ov, nv, path, st, changes)
+			return
+		}
+		ov, nv = ov.Elem(), nv.Elem()
+	}
+
+	switch ov.Kind() {
+	case reflect.Struct:
+		if _, ok := ov.Interface().(time.Time); ok || isBig(ov.Type()) || ov.Type() == quantityType {
+			break
+		}
+		for i := 0; i < ov.NumField(); i++ {
+			sf := ov.Type().Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			fst := parseTag(sf.Tag, c.tag)
+			if fst.squash && sf.Type.Kind() == reflect.Struct {
+				c.diffValues(ov.Field(i), nv.Field(i), path, fst, changes)
+				continue
+			}
+			name := fst.altName
+			if name == "" {
+				name = sf.Name
+			}
+			c.diffValues(ov.Field(i), nv.Field(i), strings.TrimPrefix(path+"."+name, "."), fst, changes)
+		}
+		return
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < ov.Len() || i < nv.Len(); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= nv.Len():
+				c.diffLeaf(ov.Index(i), reflect.Zero(ov.Type().Elem()), elemPath, st, changes)
+			case i >= ov.Len():
+				c.diffLeaf(reflect.Zero(nv.Type().Elem()), nv.Index(i), elemPath, st, changes)
+			default:
+				c.diffValues(ov.Index(i), nv.Index(i), elemPath, st, changes)
+			}
+		}
+		return
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, m := range []reflect.Value{ov, nv} {
+			for _, key := range m.MapKeys() {
+				keys[fmt.Sprint(key.Interface())] = key
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		zero := reflect.Zero(ov.Type().Elem())
+		for _, name := range names {
+			oe, ne := ov.MapIndex(keys[name]), nv.MapIndex(keys[name])
+			if !oe.IsValid() {
+				oe = zero
+			}
+			if !ne.IsValid() {
+				ne = zero
+			}
+			c.diffValues(oe, ne, path+"."+name, st, changes)
+		}
+		return
+	}
+
+	c.diffLeaf(ov, nv, path, st, changes)
+}
+
+// diffLeaf appends the difference between the values ov and nv, compared
+// as a whole, to changes.
+func (c *confucius) diffLeaf(ov, nv reflect.Value, path string, st structTag, changes *[]Change) {
+	if reflect.DeepEqual(ov.Interface(), nv.Interface()) {
+		return
+	}
+
+	change := Change{Path: path, Type: ChangeModified, Old: c.diffValue(ov, st), New: c.diffValue(nv, st)}
+	switch {
+	case isZero(ov):
+		change.Type, change.Old = ChangeAdded, nil
+	case isZero(nv):
+		change.Type, change.New = ChangeRemoved, nil
+	}
+	*changes = append(*changes, change)
+}
+
+// diffValue returns the value reported for v in a Change.
+func (c *confucius) diffValue(v reflect.Value, st structTag) interface{} {
+	if c.masked(st, v) {
+		return secretMask
+	}
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if isBig(v.Type()) {
+		return bigValue(v)
+	}
+	return v.Interface()
+}
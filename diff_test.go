@@ -0,0 +1,104 @@
+package confucius
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Diff(t *testing.T) {
+	type Server struct {
+		Host     string        `conf:"host"`
+		Port     int           `conf:"port"`
+		Timeout  time.Duration `conf:"timeout"`
+		Password string        `conf:"password" secret:"true"`
+		Replicas []string      `conf:"replicas"`
+		Labels   map[string]string
+		TLS      *struct {
+			Cert string `conf:"cert"`
+		} `conf:"tls"`
+	}
+
+	old := Server{
+		Host:     "localhost",
+		Timeout:  time.Second,
+		Password: "hunter2",
+		Replicas: []string{"a", "b"},
+		Labels:   map[string]string{"tier": "web", "env": "dev"},
+	}
+	next := old
+	next.Host = "0.0.0.0"
+	next.Port = 8080
+	next.Password = "hunter3"
+	next.Replicas = []string{"a"}
+	next.Labels = map[string]string{"tier": "web", "zone": "eu"}
+
+	changes, err := Diff(&old, next)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []Change{
+		{Path: "host", Type: ChangeModified, Old: "localhost", New: "0.0.0.0"},
+		{Path: "port", Type: ChangeAdded, New: 8080},
+		{Path: "password", Type: ChangeModified, Old: "****", New: "****"},
+		{Path: "replicas[1]", Type: ChangeRemoved, Old: "b"},
+		{Path: "Labels.env", Type: ChangeRemoved, Old: "dev"},
+		{Path: "Labels.zone", Type: ChangeAdded, New: "eu"},
+	}
+	if !reflect.DeepEqual(want, changes) {
+		t.Fatalf("\nwant %+v\ngot  %+v", want, changes)
+	}
+
+	t.Run("unmasked secrets", func(t *testing.T) {
+		changes, err := Diff(old, next, UnmaskSecrets())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if changes[2].Old != "hunter2" || changes[2].New != "hunter3" {
+			t.Fatalf("unexpected change %+v", changes[2])
+		}
+	})
+
+	t.Run("pointer set", func(t *testing.T) {
+		next := old
+		next.TLS = &struct {
+			Cert string `conf:"cert"`
+		}{Cert: "a.pem"}
+
+		changes, err := Diff(old, next)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if len(changes) != 1 || changes[0].Path != "tls" || changes[0].Type != ChangeAdded {
+			t.Fatalf("unexpected changes %+v", changes)
+		}
+	})
+
+	t.Run("equal", func(t *testing.T) {
+		if changes, err := Diff(old, old); err != nil || len(changes) != 0 {
+			t.Fatalf("want no changes, got %+v %v", changes, err)
+		}
+	})
+
+	t.Run("different types", func(t *testing.T) {
+		if _, err := Diff(old, struct{}{}); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
+func Test_Change_String(t *testing.T) {
+	for _, tc := range []struct {
+		Change Change
+		Want   string
+	}{
+		{Change: Change{Path: "host", Type: ChangeModified, Old: "a", New: "b"}, Want: "host: a -> b"},
+		{Change: Change{Path: "port", Type: ChangeAdded, New: 80}, Want: "port: added 80"},
+		{Change: Change{Path: "tags[0]", Type: ChangeRemoved, Old: "x"}, Want: "tags[0]: removed x"},
+	} {
+		if got := tc.Change.String(); got != tc.Want {
+			t.Errorf("want %q, got %q", tc.Want, got)
+		}
+	}
+}
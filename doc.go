@@ -1,7 +1,7 @@
 /*
 package confucius loads configuration files into Go structs with extra juice for validating fields and setting defaults.
 
-Config files may be defined in in yaml, json or toml format.
+Config files may be defined in in yaml, json, jsonc (json with comments and trailing commas), toml, hcl, ini or properties format.
 
 When you call `Load()`, confucius takes the following steps:
 
@@ -67,6 +67,8 @@ It can be configured to look elsewhere.
 
 Pass options as additional parameters to `Load()` to configure fig's behaviour.
 
+Options that contradict each other or cannot be used as given, e.g. `PrefixEnvTags()` without `UseEnv()`, make `Load()` return a wrapped `ErrConflictingOptions` before anything is loaded.
+
 # File
 
 Change the file and directories confucius searches in with `File()`.
@@ -78,7 +80,29 @@ Change the file and directories confucius searches in with `File()`.
 
 Fig searches for the file in dirs sequentially and uses the first matching file.
 
-The decoder (yaml/json/toml) used is picked based on the file's extension.
+A file named without an extension, e.g. `File("config")`, is looked for as config.yaml, config.yml, config.json and config.toml in that order, so that the format is not hardcoded. The profile files follow the extension found.
+
+A config file that is not found fails Load with ErrFileNotFound, unless the `OptionalFile()` option is used, in which case the missing files are skipped and the fields are set from the other sources and their defaults.
+
+The decoder (yaml/json/jsonc/toml/hcl/ini/properties) used is picked based on the file's extension.
+
+The sections and keys of ini and properties files are split into nested keys at dots, e.g. `server.tls.cert`. Use `FlatKeySeparator("__")` to split them at another separator instead.
+
+A file shared by several apps can be loaded one section at a time with `Section("apps.billing")`, which loads the object at that dot separated path into the struct.
+
+A yaml file may contain several documents separated by `---`, each document is merged over the ones before it.
+
+Config split into fragments can be loaded with `Files()`, which merges every file matching the patterns in lexical order.
+
+	confucius.Load(&cfg, confucius.Files("config.d/*.yaml"))
+
+When the locations of the files are known, `FilePaths()` loads exactly those paths, merging them in the order given.
+
+	confucius.Load(&cfg, confucius.FilePaths("/etc/app/base.yaml", "/etc/app/override.yaml"))
+
+With `AutoConfD()` the files of the config.d directory next to config.yaml are merged over it in lexical order, as drop-in overrides.
+
+Every value of a file, profile file or reader merged over the ones before it replaces theirs, including empty values. Use `MergeSkipEmpty()` to keep nulls, empty strings and empty lists from overriding, and `MergeAppendSlice()` to append lists instead of replacing them.
 
 # Tag
 
@@ -94,11 +118,32 @@ The struct tag key tag confucius looks for to find the field's alt name can be c
 
 By default confucius uses the tag key `fig`.
 
+Keys of the config files are matched to the alt names of the fields, or their Go names without one, ignoring case. Use `MatchCamelSnake()` to also ignore underscores and dashes, so that max_percent_util loads into an untagged MaxPercentUtil field, or `KeyMatcher()` to match keys your own way.
+
+	confucius.Load(&cfg, confucius.MatchCamelSnake())
+
+A field with the profile option in its tag is set to the active profiles, which is useful for logging and telemetry. A []string field receives each profile, a string field the profiles joined by commas.
+
+	type Config struct {
+	  Profiles []string `conf:",profile"` // []string{"dev"} with confucius.Profiles("dev")
+	}
+
+A struct field with the squash option in its tag has its fields promoted to the struct containing it. They are read from the same level of the config files and named like their new siblings in the environment and in errors.
+
+	type Meta struct {
+	  Name string `conf:"name"`
+	}
+
+	type Config struct {
+	  Meta  `conf:",squash"` // name: api sets Meta.Name, as does MYAPP_NAME
+	  Image string `conf:"image"`
+	}
+
 # Environment
 
 Fig can be configured to additionally set fields using the environment. This will happen after the struct is loaded from a config file and thus any values found in the environment will overwrite existing values in the struct.
 
-This is meant to be used in conjunction with loading from a file. There is no support to ONLY load from the environment. You could, but you'd still have to provide an (empty) config file.
+This is meant to be used in conjunction with loading from a file. To ONLY load from the environment use `UseEnvOnly(prefix)` instead, which does not search for config files and cannot be combined with `File()`, `Files()`, `FilePaths()` or `Reader()`.
 
 This behaviour is disabled by default and can be enabled using the option `UseEnv(prefix)`. Prefix is a string that will be prepended to the keys that are searched in the environment. Although discouraged, prefix may be left empty.
 
@@ -123,6 +168,20 @@ environment variables:
 	MYAPP_LOG_LEVEL
 	MYAPP_SERVER_HOST
 
+An env key in the field tag pins the name of its environment variable instead. The pinned name is used as is, without the prefix unless the `PrefixEnvTags()` option is used.
+
+	type Config struct {
+	  Host string `conf:"host" env:"SERVICE_HOST"` // set from SERVICE_HOST
+	}
+
+An envprefix key in the tag of a nested struct replaces the computed prefix of its fields with its own, which is used as is like a pinned name. The nearest struct with an envprefix wins and pinned names take precedence.
+
+	type Config struct {
+	  Database struct {
+	    Host string `conf:"host"` // set from DB_HOST instead of MYAPP_DATABASE_HOST
+	  } `conf:"database" envprefix:"DB"`
+	}
+
 Fields contained in struct slices whose elements already exists can be also be set via the environment in the form PARENT_IDX_FIELD, where idx is the index of the field in the slice.
 
 	type Config struct {
@@ -137,7 +196,36 @@ With the config above individual servers may be configured with the following en
 	MYAPP_SERVER_1_HOST
 	...
 
-Note: the Server slice must already have members inside it (i.e. from loading of the configuration file) for the containing fields to be altered via the environment. Fig will not instantiate and insert elements into the slice.
+The slice is grown to hold the highest index set in the environment, so elements that are not in the configuration file are created with their defaults, e.g. MYAPP_SERVER_1_HOST alone yields two servers.
+
+Use `DisableEnvSliceBinding()` to stop the environment from setting the fields of slice elements altogether.
+
+Environment variables with the prefix that match no field, e.g. a misspelled MYAPP_HSOT, are ignored. Use `WarnUnboundEnv()` to log a warning for each of them, or `StrictEnv()` to fail with an error wrapping ErrUnboundEnv.
+
+	confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.StrictEnv())
+
+Entries of maps with string keys can be set via the environment in the form PARENT_KEY, where key is the entry's key taken verbatim from the variable's name, keeping its case and any underscores.
+
+	type Config struct {
+	  Labels map[string]int
+	}
+
+With the config above the following environment variables set the entries "tier" and "ENV_prod":
+
+	MYAPP_LABELS_tier=1
+	MYAPP_LABELS_ENV_prod=2
+
+Note: only maps whose values can be set from a string (basic types, time.Time, time.Duration and slices of them) can be set this way. Maps of structs are left untouched.
+
+The values of the environment override the ones of the config files, which override the ones of the reader given with String or Reader. Use the Precedence option to merge the sources in a different order, e.g. to keep the values of the config files over the environment:
+
+	confucius.Load(&cfg, confucius.UseEnv("MYAPP"), confucius.Precedence(confucius.SourceReader, confucius.SourceEnv, confucius.SourceFile))
+
+References to other environment variables in the values of the environment, e.g. MYAPP_URL=${SCHEME}://host, are expanded like the ones in config files, unless the DisableEnvExpansion option is used.
+
+With the InterpolateConfig option, references to other keys of the config files and readers, e.g. `url: http://${config:server.host}:${config:server.port}`, are replaced with the values of those keys once the sources are merged. References that form a cycle fail Load with an error wrapping ErrReferenceCycle. Without the option the references are left as they are.
+
+With the SecretFileExpansion option, values of the form file:/run/secrets/name, in the environment or config files, are replaced with the content of the file, like the secrets mounted by Docker and Kubernetes.
 
 # Time
 
@@ -153,8 +241,14 @@ Change the layout confucius uses to parse times using `TimeLayout()`.
 	fmt.Printf("%+v", cfg)
 	// Output: {Date:2019-12-25 00:00:00 +0000 UTC}
 
+Use `TimeLayouts()` when times are written in more than one layout, each layout is tried in order.
+
+	confucius.Load(&cfg, confucius.TimeLayouts(time.RFC3339, "01-02-2006"))
+
 By default confucius parses time using the `RFC.3339` layout (`2006-01-02T15:04:05Z07:00`).
 
+The native datetimes of toml files are loaded as is, without a layout. Local datetimes, dates and times, which have no offset, are loaded in UTC.
+
 # Required
 
 A validate key with a required value in the field's struct tag makes confucius check if the field has been set after it's been loaded. Required fields that are not set are returned as an error.
@@ -166,7 +260,7 @@ A validate key with a required value in the field's struct tag makes confucius c
 Fig uses the following properties to check if a field is set:
 
 	basic types:           != to its zero value ("" for str, 0 for int, etc.)
-	slices, arrays:        len() > 0
+	slices, arrays, maps:  len() > 0 (an empty slice or map is not set)
 	pointers*, interfaces: != nil
 	structs:               always true (use a struct pointer to check for struct presence)
 	time.Time:             !time.IsZero()
@@ -174,6 +268,14 @@ Fig uses the following properties to check if a field is set:
 
 	*pointers to non-struct types (with the exception of time.Time) are de-referenced if they are non-nil and then checked
 
+Fields inside a struct pointer that is nil are not processed, so an optional section can be expressed as a struct pointer whose required fields are only validated once the section is present in the config file:
+
+	type Config struct {
+	  TLS *struct {
+	    Cert string `validate:"required"` // only required if tls is set
+	  } `conf:"tls"`
+	}
+
 See example below to help understand:
 
 	type Config struct {
@@ -208,6 +310,52 @@ See example below to help understand:
 	fmt.Print(err)
 	// A: required, B: required, C: required, D: required, E: required, G: required, H.J: required, K: required, M: required
 
+# Validation
+
+Additional rules can be given in the validate key, separated by commas. Rules other than required are only checked when the field is set. Rules confucius does not know, e.g. the ones of go-playground/validator sharing the key, are ignored.
+
+	type Config struct {
+	  NotBefore  time.Time `validate:"past"`            // must be before the current time
+	  Expiration time.Time `validate:"required,future"` // must be set and after the current time
+	}
+
+The subset rule checks that every element of a slice is one of the values given, separated by spaces.
+
+	type Config struct {
+	  Environments []string `validate:"subset=dev staging prod"`
+	}
+
+The unique rule checks that a slice has no duplicate elements and the sorted rule that the numbers or strings of a slice are in ascending order.
+
+	type Config struct {
+	  Ports []int `validate:"unique,sorted"`
+	}
+
+The semver rule checks that a string is a semantic version, e.g. v1.2.3-rc.1. The semver_constraint rule also checks that the version satisfies comparisons, separated by spaces, using the operators =, !=, >, >=, < and <=.
+
+	type Config struct {
+	  Version string `validate:"semver"`
+	  API     string `validate:"semver_constraint=>=1.2.0 <2.0.0"`
+	}
+
+A field can be required depending on its siblings, which are named by their alt name or their name in the struct. Multiple siblings are separated by spaces.
+
+	type Config struct {
+	  TLSCert  string `validate:"required_with=TLSKey"`     // required if TLSKey is set
+	  TLSKey   string `validate:"required_with=TLSCert"`    // required if TLSCert is set
+	  Token    string `validate:"required_without=Password"` // required if Password is not set
+	  Password string
+	}
+
+An unknown rule is returned as an error.
+
+Rules given in the warn key instead, with the same syntax, only log a warning through the logger when they fail and do not fail Load, e.g. to roll out stricter validations gradually. The recommended rule of the validate key is a shorthand for `warn:"required"`.
+
+	type Config struct {
+	  Owner   string `validate:"recommended"` // logs a warning when not set
+	  Version string `warn:"semver"`           // logs a warning when not a semantic version
+	}
+
 # Default
 
 A default key in the field tag makes confucius fill the field with the value specified when the field is not otherwise set.
@@ -218,12 +366,29 @@ Fig attempts to parse the value based on the field's type. If parsing fails then
 	  Port int `conf:"port" default:"8000"` // or simply `default:"8000"`
 	}
 
+Defaults and fallbacks may reference environment variables like the config files do, unless the DisableEnvExpansion option is used.
+
+	type Config struct {
+	  Dir  string `conf:"dir" default:"${HOME}/config"`
+	  Port int    `conf:"port" default:"${PORT:8000}"` // 8000 when PORT is not set
+	}
+
 A default value can be set for the following types:
 
-	all basic types except bool and complex
+	all basic types except bool
 	time.Time
 	time.Duration
+	big.Int and big.Float
+	confucius.Quantity
+	confucius.ByteSize
 	slices (of above types)
+	pointers (to above types, e.g. *[]string or []*int)
+
+The default of types that cannot be written in a tag, e.g. *tls.Config, can be registered once with `RegisterDefault()`. It then applies to every field of that type that is not set and has no default key.
+
+	confucius.RegisterDefault(reflect.TypeOf(&tls.Config{}), func() reflect.Value {
+	  return reflect.ValueOf(&tls.Config{MinVersion: tls.VersionTLS12})
+	})
 
 Successive elements of slice defaults should be separated by a comma. The entire slice can optionally be enclosed in square brackets:
 
@@ -231,8 +396,73 @@ Successive elements of slice defaults should be separated by a comma. The entire
 	  Durations []time.Duration `default:"[30m,1h,90m,2h]"` // or `default:"30m,1h,90m,2h"`
 	}
 
+Elements that contain a comma can be enclosed in quotes, e.g. `default:"[\"cn=a,ou=b\",cn=c]"`, or a different delimiter can be configured with the SliceDelimiter option. The same rules apply to slices set via the environment.
+
+Floats are parsed at the size of the field and rounded to the nearest value, so `default:"0.1"` sets a float32 to float32(0.1) and a float64 to 0.1, exactly like the Go constants. A value out of the range of the field is an error rather than infinity.
+
+Likewise the numbers of config files, like the values of defaults and environment variables, must fit the sized ints, uints and floats they are loaded into, e.g. 300 is an error for an int8 instead of wrapping around.
+
+Complex numbers use the syntax of strconv.ParseComplex, e.g. `default:"(1+2i)"`.
+
+A big.Int or big.Float holds numbers beyond the range or precision of the basic types, e.g. `default:"115792089237316195423570985008687907853269984665640564039457584007913129639935"`. Integers may be prefixed with their base like 0x. A big.Float without a precision gets enough to hold every digit given. Quote such numbers in config files, they are decoded as a float64 otherwise.
+
+A confucius.Quantity holds an amount of a resource written like the resource limits of Kubernetes, e.g. `memory: 64Mi` or `cpu: 250m`, from a config file, the environment or a default. Quantities of the same amount are equal, so 1Gi == 1024Mi.
+
+	type Resources struct {
+	  CPU    confucius.Quantity `conf:"cpu" default:"100m"`
+	  Memory confucius.Quantity `conf:"memory" default:"64Mi"`
+	}
+
+A confucius.ByteSize holds a number of bytes written with an SI or IEC unit, e.g. `max_upload: 10MB` or `default:"2GiB"`. Plain numbers are bytes.
+
+Types whose pointer implements encoding.BinaryUnmarshaler, e.g. some key types, are given the bytes of their value as is, or base64 decoded with the `BinaryBase64()` option, whether it comes from a config file, the environment or a default. Types that also implement encoding.TextUnmarshaler, such as time.Time, are left out.
+
+A []byte is not split into elements, its value from a config file or the environment, or its default, is base64 decoded instead, e.g. `default:"c2VjcmV0"`, and Dump writes it base64 encoded. The encoding can be changed with the ByteEncoding option.
+
 Note: the default setter knows if it should fill a field or not by comparing if the current value of the field is equal to the corresponding zero value for that field's type. This happens after the configuration is loaded and has the implication that the zero value set explicitly by the user will get overwritten by any default value registered for that field. It's for this reason that defaults on booleans are not permitted, as a boolean field with a default value of `true` would always be true (since if it were set to false it'd be overwritten).
 
+# Empty
+
+An empty key in the field tag sets the value that leaves the field unset, as if it were missing from the config file or the environment. The field is left zero, pointers are left nil, so that its default applies.
+
+	type Config struct {
+	  Replicas int    `empty:"-1" default:"3"` // replicas: -1 sets 3
+	  Proxy    string `empty:"none"`           // MYAPP_PROXY=none clears the proxy of the config file
+	}
+
+# Format
+
+A format key in the field tag makes confucius parse the field's value, whether it comes from a config file, the environment or a default, using the given format.
+
+	type Config struct {
+	  MaxUtil float64 `conf:"max" format:"percent"` // "50%" is loaded as 0.5
+	}
+
+The following formats are supported:
+
+	percent:  float fields, "50%" or "0.5" are both loaded as 0.5. Use `format:"percent,clamp"` to clamp the value to [0,1].
+	hostport: string fields, the value must be a valid "host:port" endpoint such as "0.0.0.0:8080", "[::1]:80" or "localhost:http".
+
+A unit key on a time.Duration field gives the unit of bare numbers, so that the common "seconds as int" idiom can be loaded into a duration. Values with a unit of their own, such as "500ms", are parsed as they are.
+
+	type Config struct {
+	  Timeout time.Duration `conf:"timeout" unit:"s"` // 30 is loaded as 30s
+	}
+
+Any of the units accepted by time.ParseDuration can be used.
+
+With `ExtendedDurations()` durations can also be written with days and weeks, e.g. "3d" or "1w12h", or as ISO 8601 durations, e.g. "P1DT2H", wherever they come from.
+
+# Secrets
+
+A secret key in the field tag marks the field's value as sensitive. Its value is masked as "****" in the output of Dump and in the errors and logs of Load.
+
+	type Config struct {
+	  Password string `conf:"password" secret:"true"`
+	}
+
+Use the UnmaskSecrets option to disable the masking while debugging.
+
 # Mutual exclusion
 
 The required validation and the default field tags are mutually exclusive as they are contradictory.
@@ -243,6 +473,45 @@ This is not allowed:
 	  Level string `validate:"required" default:"warn"` // will result in an error
 	}
 
+Use a fallback instead of a default for a required field with a suggested value. The fallback is set when the field is not set by the config file or the environment, before the required validation is checked, so the validation never fails because of a missing value.
+
+	type Config struct {
+	  Level string `validate:"required" fallback:"warn"` // level is "warn" unless set
+	}
+
+A field cannot have both a default and a fallback.
+
+Interactive tools can ask for the required fields that are still missing with the PromptMissing option instead. Each field is prompted for by its path and its value read as a line, after the fallbacks are set.
+
+	confucius.Load(&cfg, confucius.PromptMissing(os.Stdin, os.Stderr))
+
+# Custom decoding
+
+Types whose pointer implements ConfigDecoder decode their own values, whether they are the config struct itself or one of its fields.
+
+	type Endpoint struct {
+	  Host string
+	  Port int
+	}
+
+	func (e *Endpoint) DecodeConfig(vals map[string]interface{}) error {
+	  // parse vals["url"] into e
+	}
+
+Types whose pointer implements Unmarshaler get the raw sub-tree of their value instead, whatever its shape, e.g. to pick the type of a polymorphic config from a discriminator key.
+
+	func (s *Storage) UnmarshalConf(raw interface{}) error {
+	  // decode raw according to raw.(map[string]interface{})["type"]
+	}
+
+The values of the sources can be reshaped before they are loaded with `TransformMap()`, e.g. to migrate the keys of an old config layout, and the loaded struct adjusted with `AfterLoad()`, e.g. to derive a field from other ones.
+
+A json.RawMessage field keeps its sub-tree of the config, whatever the format of the file, encoded as json to be decoded later, e.g. by the plugin it configures.
+
+	type Config struct {
+	  Plugins map[string]json.RawMessage `conf:"plugins"`
+	}
+
 # Errors
 
 A wrapped error `ErrFileNotFound` is returned when confucius is not able to find a config file to load. This can be useful for instance to fallback to a different configuration loading mechanism.
@@ -252,5 +521,20 @@ A wrapped error `ErrFileNotFound` is returned when confucius is not able to find
 	if errors.Is(err, confucius.ErrFileNotFound) {
 	  // load config from elsewhere
 	}
+
+The error is a *MissingFilesError, which lists the files that were not found and the dirs that were searched.
+
+	var mfe *confucius.MissingFilesError
+	if errors.As(err, &mfe) {
+	  fmt.Println(mfe.Files) // [config.yaml config.test.yaml]
+	}
+
+The fields that cannot be set or fail their validations are reported together. `FieldErrors()` returns them, with the paths of the failing fields and their messages, which encode as a json object for web services.
+
+	if fe, ok := confucius.FieldErrors(err); ok {
+	  fmt.Println(fe.Fields()) // [kind spec.containers[0].image]
+	}
+
+With `FailFast()` loading stops at the first failing field, which is then the only one reported.
 */
 package confucius
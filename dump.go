@@ -0,0 +1,141 @@
+package confucius
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Dump serializes cfg into yaml, the format confucius uses by default to
+// load it. Fields are written in the order they are defined in the struct
+// using their alt names, times are formatted using the first time layout
+// and durations using their string representation. The parameter `cfg`
+// must be a struct or a pointer to a struct.
+//
+//	out, err := confucius.Dump(&cfg, confucius.Tag("config"))
+//
+// The values of fields tagged with `secret:"true"` are written as "****"
+// unless the UnmaskSecrets option is used. Only the options that affect
+// how fields are named and formatted (such as Tag and TimeLayout) have an
+// effect on Dump.
+//
+// With the KeyOrder option, keys are written in the order given to it,
+// such as the order recorded while loading the config.
+func Dump(cfg interface{}, options ...Option) ([]byte, error) {
+	c := New(options...).c
+	if c.keyOrder != nil {
+		c.keyIndex = make(map[string]int, len(*c.keyOrder))
+		for i, key := range *c.keyOrder {
+			if _, ok := c.keyIndex[key]; !ok {
+				c.keyIndex[key] = i
+			}
+		}
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(cfg))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cfg must be a struct or a pointer to a struct")
+	}
+
+	return yaml.Marshal(c.dumpValue(v, ""))
+}
+
+// dumpValue converts v into a value that yaml serializes the same way
+// confucius decodes it. path is the lowercased path of v, used to look
+// up the order of its keys.
+func (c *confucius) dumpValue(v reflect.Value, path string) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return c.dumpValue(v.Elem(), path)
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t.Format(c.timeLayouts[0])
+		}
+		if isBig(v.Type()) {
+			return bigString(v)
+		}
+		if q, ok := v.Interface().(Quantity); ok {
+			return q.String()
+		}
+		ms := yaml.MapSlice{}
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			st := parseTag(sf.Tag, c.tag)
+			name := st.altName
+			if name == "" {
+				name = sf.Name
+			}
+			if st.squash {
+				if items, ok := c.dumpValue(v.Field(i), path).(yaml.MapSlice); ok {
+					ms = append(ms, items...)
+					continue
+				}
+			}
+			if c.masked(st, v.Field(i)) {
+				ms = append(ms, yaml.MapItem{Key: name, Value: secretMask})
+				continue
+			}
+			ms = append(ms, yaml.MapItem{Key: name, Value: c.dumpValue(v.Field(i), keyPath(path, name))})
+		}
+		if c.keyIndex != nil {
+			c.sortKeys(ms, path)
+		}
+		return ms
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if isByteSlice(v.Type()) {
+			return c.byteEncoding.EncodeToString(v.Bytes())
+		}
+		if raw, ok := v.Interface().(json.RawMessage); ok {
+			var val interface{}
+			if err := json.Unmarshal(raw, &val); err != nil {
+				return string(raw)
+			}
+			return val
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = c.dumpValue(v.Index(i), path)
+		}
+		return list
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if c.keyIndex != nil {
+			ms := yaml.MapSlice{}
+			for _, key := range v.MapKeys() {
+				name := fmt.Sprint(key.Interface())
+				ms = append(ms, yaml.MapItem{Key: name, Value: c.dumpValue(v.MapIndex(key), keyPath(path, name))})
+			}
+			sort.Slice(ms, func(i, j int) bool {
+				return ms[i].Key.(string) < ms[j].Key.(string)
+			})
+			c.sortKeys(ms, path)
+			return ms
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			m[name] = c.dumpValue(v.MapIndex(key), keyPath(path, name))
+		}
+		return m
+	}
+
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
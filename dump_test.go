@@ -0,0 +1,186 @@
+package confucius
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Dump(t *testing.T) {
+	type Server struct {
+		Host    string        `conf:"host"`
+		Ports   []int         `conf:"ports"`
+		Timeout time.Duration `conf:"timeout"`
+		Build   time.Time     `conf:"build"`
+		Labels  map[string]string
+		TLS     *struct {
+			Cert string `conf:"cert"`
+		} `conf:"tls"`
+		secret string
+	}
+
+	cfg := Server{
+		Host:    "127.0.0.1",
+		Ports:   []int{80, 443},
+		Timeout: 90 * time.Second,
+		Build:   time.Date(2020, 1, 9, 12, 30, 0, 0, time.UTC),
+		Labels:  map[string]string{"tier": "web", "env": "prod"},
+		secret:  "hidden",
+	}
+
+	got, err := Dump(&cfg)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := `host: 127.0.0.1
+ports:
+- 80
+- 443
+timeout: 1m30s
+build: "2020-01-09T12:30:00Z"
+Labels:
+  env: prod
+  tier: web
+tls: null
+`
+	if string(got) != want {
+		t.Fatalf("\nwant %s\ngot %s", want, got)
+	}
+
+	t.Run("non struct returns error", func(t *testing.T) {
+		if _, err := Dump("config"); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
+func Test_Dump_RoundTrip(t *testing.T) {
+	var cfg Pod
+	if err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid"))); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	out, err := Dump(&cfg)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	var loaded Pod
+	if err := Load(&loaded, String(string(out), DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg, loaded) {
+		t.Errorf("\nwant %+v\ngot %+v", cfg, loaded)
+	}
+}
+
+func Test_Dump_KeyOrder(t *testing.T) {
+	type Config struct {
+		Host   string            `conf:"host"`
+		Port   int               `conf:"port"`
+		Labels map[string]string `conf:"labels"`
+		Logger struct {
+			Level string `conf:"level"`
+			Trace bool   `conf:"trace"`
+		} `conf:"logger"`
+		Unset string `conf:"unset"`
+	}
+
+	want := `logger:
+  trace: true
+  level: info
+labels:
+  tier: web
+  env: prod
+  app: api
+port: 8080
+host: localhost
+unset: ""
+`
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		Decoder Decoder
+		Want    string
+	}{
+		{
+			Name:    "yaml",
+			Content: "logger: {trace: true, level: info}\nlabels: {tier: web, env: prod}\nport: 8080\nhost: localhost",
+			Decoder: DecoderYaml,
+			Want:    want,
+		},
+		{
+			Name:    "json",
+			Content: `{"logger": {"trace": true, "level": "info"}, "labels": {"tier": "web", "env": "prod"}, "port": 8080, "host": "localhost"}`,
+			Decoder: DecoderJSON,
+			Want:    want,
+		},
+		{
+			Name:    "toml",
+			Content: "port = 8080\nhost = \"localhost\"\n[logger]\ntrace = true\nlevel = \"info\"\n[labels]\ntier = \"web\"\nenv = \"prod\"",
+			Decoder: DecoderToml,
+			// tables follow the keys of the top level table in toml.
+			Want: "port: 8080\nhost: localhost\nlogger:\n  trace: true\n  level: info\nlabels:\n  tier: web\n  env: prod\n  app: api\nunset: \"\"\n",
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var (
+				cfg   Config
+				order []string
+			)
+			if err := Load(&cfg, String(tc.Content, tc.Decoder), KeyOrder(&order)); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			cfg.Labels["app"] = "api"
+
+			got, err := Dump(&cfg, KeyOrder(&order))
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if string(got) != tc.Want {
+				t.Fatalf("\nwant %s\ngot %s", tc.Want, got)
+			}
+		})
+	}
+}
+
+func Test_Dump_RawMessage(t *testing.T) {
+	cfg := struct {
+		Plugin json.RawMessage `conf:"plugin"`
+	}{Plugin: json.RawMessage(`{"size": 10}`)}
+
+	got, err := Dump(&cfg)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := "plugin:\n  size: 10\n"; string(got) != want {
+		t.Fatalf("\nwant %s\ngot %s", want, got)
+	}
+}
+
+func Test_Dump_Bytes(t *testing.T) {
+	type Config struct {
+		Key []byte `conf:"key"`
+	}
+
+	got, err := Dump(&Config{Key: []byte("secret")})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := "key: c2VjcmV0\n"; string(got) != want {
+		t.Fatalf("\nwant %s\ngot %s", want, got)
+	}
+
+	var cfg Config
+	if err := Load(&cfg, String(string(got), DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if string(cfg.Key) != "secret" {
+		t.Fatalf("want key secret loaded back, got %q", cfg.Key)
+	}
+}
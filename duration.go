@@ -0,0 +1,224 @@
+package confucius
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// durationUnits are the units of the durations parsed with the
+// ExtendedDurations option, the units of time.ParseDuration plus days
+// and weeks.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // U+00B5 = micro symbol
+	"μs": time.Microsecond, // U+03BC = Greek letter mu
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// parseDuration parses val as a duration, in the extended formats when the
+// ExtendedDurations option is used.
+func (c *confucius) parseDuration(val string) (time.Duration, error) {
+	if c.extendedDurations {
+		return parseExtendedDuration(val)
+	}
+	return time.ParseDuration(val)
+}
+
+// parseExtendedDuration parses a duration written like the ones accepted by
+// time.ParseDuration, with days and weeks as units too, or as an ISO 8601
+// duration. Days are 24 hours long and weeks 7 days.
+//
+//	"3d"        --->   72h
+//	"1w12h"     --->   180h
+//	"P1DT2H"    --->   26h
+//	"PT1.5S"    --->   1.5s
+func parseExtendedDuration(val string) (time.Duration, error) {
+	if d, err := time.ParseDuration(val); err == nil {
+		return d, nil
+	}
+
+	s, neg := val, false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg, s = s[0] == '-', s[1:]
+	}
+
+	var (
+		d   time.Duration
+		err error
+	)
+	if strings.HasPrefix(s, "P") {
+		d, err = parseISODuration(s[1:])
+	} else {
+		d, err = parseUnitsDuration(s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", val, err)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// parseUnitsDuration parses a sequence of numbers each followed by one of
+// the durationUnits, e.g. 1w12h.
+func parseUnitsDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var total time.Duration
+	for s != "" {
+		num, rest := splitNumber(s)
+		i := strings.IndexAny(rest, "0123456789.")
+		if i < 0 {
+			i = len(rest)
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("missing unit after %s", num)
+		}
+		unit, ok := durationUnits[rest[:i]]
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q", rest[:i])
+		}
+		d, err := scaleDuration(num, unit)
+		if err != nil {
+			return 0, err
+		}
+		if total, err = addDuration(total, d); err != nil {
+			return 0, err
+		}
+		s = rest[i:]
+	}
+	return total, nil
+}
+
+// parseISODuration parses the part of an ISO 8601 duration following its P,
+// e.g. 1DT2H. Years and months are rejected since they have no fixed length.
+func parseISODuration(iso string) (time.Duration, error) {
+	s := iso
+	if s == "" || s == "T" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var (
+		total  time.Duration
+		inTime bool
+	)
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime {
+				return 0, fmt.Errorf("unexpected T")
+			}
+			inTime, s = true, s[1:]
+			continue
+		}
+
+		num, rest := splitNumber(s)
+		if rest == "" {
+			return 0, fmt.Errorf("missing designator after %s", num)
+		}
+
+		var unit time.Duration
+		switch designator := rest[0]; {
+		case !inTime && designator == 'W':
+			unit = durationUnits["w"]
+		case !inTime && designator == 'D':
+			unit = durationUnits["d"]
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		case !inTime && (designator == 'Y' || designator == 'M'):
+			return 0, fmt.Errorf("years and months have no fixed duration")
+		default:
+			return 0, fmt.Errorf("unknown designator %q", designator)
+		}
+
+		d, err := scaleDuration(num, unit)
+		if err != nil {
+			return 0, err
+		}
+		if total, err = addDuration(total, d); err != nil {
+			return 0, err
+		}
+		s = rest[1:]
+	}
+	if strings.HasSuffix(iso, "T") {
+		return 0, fmt.Errorf("missing time after T")
+	}
+	return total, nil
+}
+
+// splitNumber splits the leading number, digits with an optional fraction,
+// from s.
+func splitNumber(s string) (num, rest string) {
+	i := 0
+	for i < len(s) && (s[i] == '.' || ('0' <= s[i] && s[i] <= '9')) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// scaleDuration returns num, a number with an optional fraction, times unit.
+func scaleDuration(num string, unit time.Duration) (time.Duration, error) {
+	whole, frac := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		whole, frac = num[:i], num[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("missing number")
+	}
+
+	var w int64
+	if whole != "" {
+		var err error
+		if w, err = strconv.ParseInt(whole, 10, 64); err != nil || w > math.MaxInt64/int64(unit) {
+			return 0, fmt.Errorf("%s overflows", num)
+		}
+	}
+	d := time.Duration(w) * unit
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %s", num)
+		}
+		if d, err = addDuration(d, time.Duration(f*float64(unit))); err != nil {
+			return 0, err
+		}
+	}
+	return d, nil
+}
+
+// addDuration returns a+b, both positive, or an error on overflow.
+func addDuration(a, b time.Duration) (time.Duration, error) {
+	if a > math.MaxInt64-b {
+		return 0, fmt.Errorf("duration overflows")
+	}
+	return a + b, nil
+}
+
+// stringToDurationHookFunc returns a hook that parses the strings given to
+// time.Duration fields with parse.
+func stringToDurationHookFunc(parse func(string) (time.Duration, error)) mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(time.Duration(0)) {
+			return data, nil
+		}
+		return parse(data.(string))
+	}
+}
@@ -0,0 +1,98 @@
+package confucius
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_parseExtendedDuration(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Want time.Duration
+	}{
+		{In: "1d", Want: 24 * time.Hour},
+		{In: "1w12h", Want: 180 * time.Hour},
+		{In: "2w", Want: 14 * 24 * time.Hour},
+		{In: "1.5d", Want: 36 * time.Hour},
+		{In: "-3d4h30m", Want: -(76*time.Hour + 30*time.Minute)},
+		{In: "1d500ms", Want: 24*time.Hour + 500*time.Millisecond},
+		{In: "90m", Want: 90 * time.Minute},
+		{In: "0", Want: 0},
+		{In: "P1DT2H", Want: 26 * time.Hour},
+		{In: "P2W", Want: 14 * 24 * time.Hour},
+		{In: "PT1.5S", Want: 1500 * time.Millisecond},
+		{In: "PT30M", Want: 30 * time.Minute},
+		{In: "P1D", Want: 24 * time.Hour},
+		{In: "-PT1H", Want: -time.Hour},
+	} {
+		t.Run(tc.In, func(t *testing.T) {
+			got, err := parseExtendedDuration(tc.In)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.Want {
+				t.Fatalf("want %v, got %v", tc.Want, got)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "d", "3", "3x", "1d2", "..d", "P", "PT", "P1Y", "P1M", "PT1D", "P1H", "P1DT", "P1DTT1H", "P1", "106752d", "1e3d"} {
+		t.Run("bad "+in, func(t *testing.T) {
+			if _, err := parseExtendedDuration(in); err == nil {
+				t.Fatalf("expected err")
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_ExtendedDurations(t *testing.T) {
+	type Config struct {
+		Retention time.Duration   `conf:"retention"`
+		Rotation  time.Duration   `conf:"rotation" default:"1w12h"`
+		Grace     *time.Duration  `conf:"grace"`
+		Backoff   time.Duration   `conf:"backoff" unit:"s"`
+		Windows   []time.Duration `conf:"windows"`
+	}
+
+	os.Clearenv()
+	setenv(t, "APP_GRACE", "P1DT2H")
+
+	t.Run("enabled", func(t *testing.T) {
+		var cfg Config
+		content := `{"retention": "30d", "backoff": "1d", "windows": ["1d", "PT1H"]}`
+		if err := Load(&cfg, String(content, DecoderJSON), UseEnv("app"), ExtendedDurations()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Retention != 30*24*time.Hour {
+			t.Errorf("retention: want 720h, got %v", cfg.Retention)
+		}
+		if cfg.Rotation != 180*time.Hour {
+			t.Errorf("rotation: want 180h, got %v", cfg.Rotation)
+		}
+		if cfg.Grace == nil || *cfg.Grace != 26*time.Hour {
+			t.Errorf("grace: want 26h, got %v", cfg.Grace)
+		}
+		if cfg.Backoff != 24*time.Hour {
+			t.Errorf("backoff: want 24h, got %v", cfg.Backoff)
+		}
+		if len(cfg.Windows) != 2 || cfg.Windows[0] != 24*time.Hour || cfg.Windows[1] != time.Hour {
+			t.Errorf("windows: want [24h 1h], got %v", cfg.Windows)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(`{"retention": "30d"}`, DecoderJSON)); err == nil {
+			t.Fatalf("want err for 30d without ExtendedDurations")
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{"retention": "30days"}`, DecoderJSON), ExtendedDurations())
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
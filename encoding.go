@@ -0,0 +1,67 @@
+package confucius
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeText strips the byte order mark data may start with. UTF-16 data,
+// identified by its byte order mark or by the zero bytes of its first ASCII
+// character, is transcoded to UTF-8 when the TranscodeUTF16 option is used
+// and returned as an error otherwise.
+func (c *confucius) decodeText(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, bomUTF8) {
+		return data[len(bomUTF8):], nil
+	}
+
+	var bigEndian bool
+	switch {
+	case bytes.HasPrefix(data, bomUTF16LE):
+		data = data[len(bomUTF16LE):]
+	case bytes.HasPrefix(data, bomUTF16BE):
+		data, bigEndian = data[len(bomUTF16BE):], true
+	case len(data) >= 2 && data[0] != 0 && data[1] == 0:
+	case len(data) >= 2 && data[0] == 0 && data[1] != 0:
+		bigEndian = true
+	default:
+		return data, nil
+	}
+
+	if !c.transcodeUTF16 {
+		return nil, fmt.Errorf("UTF-16 encoded content is not supported without the TranscodeUTF16 option")
+	}
+	return utf16ToUTF8(data, bigEndian)
+}
+
+// utf16ToUTF8 transcodes the UTF-16 data, without its byte order mark, to UTF-8.
+func utf16ToUTF8(data []byte, bigEndian bool) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid UTF-16 content: odd number of bytes")
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(units))
+	var b [utf8.UTFMax]byte
+	for _, r := range utf16.Decode(units) {
+		n := utf8.EncodeRune(b[:], r)
+		buf.Write(b[:n])
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package confucius
+
+import (
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes s as UTF-16, prefixed by a byte order mark if bom is set.
+func encodeUTF16(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	data := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			data = append(data, byte(u>>8), byte(u))
+		} else {
+			data = append(data, byte(u), byte(u>>8))
+		}
+	}
+	return data
+}
+
+func Test_confucius_decodeText(t *testing.T) {
+	const text = `{"name": "café ☕"}`
+
+	for _, tc := range []struct {
+		Name      string
+		In        []byte
+		Transcode bool
+		WantErr   bool
+	}{
+		{Name: "utf-8", In: []byte(text)},
+		{Name: "utf-8 bom", In: append([]byte{0xEF, 0xBB, 0xBF}, text...)},
+		{Name: "utf-16le bom", In: encodeUTF16(text, false, true), Transcode: true},
+		{Name: "utf-16be bom", In: encodeUTF16(text, true, true), Transcode: true},
+		{Name: "utf-16le", In: encodeUTF16(text, false, false), Transcode: true},
+		{Name: "utf-16be", In: encodeUTF16(text, true, false), Transcode: true},
+		{Name: "utf-16 without option", In: encodeUTF16(text, false, true), WantErr: true},
+		{Name: "odd utf-16", In: encodeUTF16(text, false, true)[:5], Transcode: true, WantErr: true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			conf := defaultConfucius()
+			conf.transcodeUTF16 = tc.Transcode
+
+			got, err := conf.decodeText(tc.In)
+			if tc.WantErr {
+				if err == nil {
+					t.Fatalf("expected err")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if string(got) != text {
+				t.Fatalf("want %q, got %q", text, got)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_Encoding(t *testing.T) {
+	type Config struct {
+		Name string `conf:"name"`
+		Port int    `conf:"port"`
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "\xEF\xBB\xBFname: café\nport: 80")
+	writeFile(t, filepath.Join(dir, "config.json"), string(encodeUTF16(`{"name": "café", "port": 80}`, false, true)))
+
+	want := Config{Name: "café", Port: 80}
+
+	t.Run("yaml with bom", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("utf-16 json", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), File("config.json"), TranscodeUTF16()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("utf-16 json without option", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, Dirs(dir), File("config.json")); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
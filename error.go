@@ -1,6 +1,8 @@
 package confucius
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,16 +12,61 @@ import (
 // not found in the given search dirs.
 var ErrFileNotFound = fmt.Errorf("file not found")
 
+// ErrSecretNotFound is returned by a Keyring when the requested secret does not exist.
+var ErrSecretNotFound = fmt.Errorf("secret not found")
+
+// ErrFileTooLarge is returned as a wrapped error by `Load` when a config file
+// or reader exceeds the size set with the MaxFileSize option.
+var ErrFileTooLarge = fmt.Errorf("file too large")
+
+// ErrReferenceCycle is returned as a wrapped error by `Load` when ${config:...}
+// references resolved with the InterpolateConfig option lead back to
+// themselves.
+var ErrReferenceCycle = fmt.Errorf("reference cycle")
+
+// ErrConflictingOptions is returned as a wrapped error by `Load` when the
+// options given are contradictory, e.g. PrefixEnvTags without UseEnv.
+var ErrConflictingOptions = fmt.Errorf("conflicting options")
+
+// ErrUnboundEnv is returned as a wrapped error by `Load` with the StrictEnv
+// option when environment variables with the prefix given to UseEnv match
+// no field.
+var ErrUnboundEnv = fmt.Errorf("unbound environment variables")
+
+// MissingFilesError is returned by `Load` when some of the expected config
+// files, the main file and the files of the active profiles, are not found.
+// It wraps ErrFileNotFound.
+type MissingFilesError struct {
+	Files []string // the names of the files that were not found.
+	Dirs  []string // the dirs searched for the files.
+}
+
+// Error lists the files that were not found.
+func (e *MissingFilesError) Error() string {
+	return fmt.Sprintf("\"%s\" file(s) not found: %v", strings.Join(e.Files, "\", \""), ErrFileNotFound)
+}
+
+// Unwrap returns ErrFileNotFound.
+func (e *MissingFilesError) Unwrap() error {
+	return ErrFileNotFound
+}
+
 // fieldErrors collects errors for fields of config struct.
 type fieldErrors map[string]error
 
-// Error formats all fields errors into a single string.
-func (fe fieldErrors) Error() string {
+// fields returns the paths of the fields in error, sorted.
+func (fe fieldErrors) fields() []string {
 	keys := make([]string, 0, len(fe))
 	for key := range fe {
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
+	return keys
+}
+
+// Error formats all fields errors into a single string.
+func (fe fieldErrors) Error() string {
+	keys := fe.fields()
 
 	var sb strings.Builder
 	sb.Grow(len(keys) * 10)
@@ -33,3 +80,49 @@ func (fe fieldErrors) Error() string {
 
 	return strings.TrimSuffix(sb.String(), ", ")
 }
+
+// ValidationErrors holds the errors of the fields of a config struct that
+// failed to be set or validated by `Load`, keyed by their paths, e.g.
+// spec.containers[0].image. Use FieldErrors to get them from an error.
+type ValidationErrors struct {
+	errs fieldErrors
+}
+
+// FieldErrors returns the errors of the fields of err, or false if err is
+// not, nor wraps, an error of fields returned by `Load`.
+//
+//	if fe, ok := confucius.FieldErrors(err); ok {
+//	  json.NewEncoder(w).Encode(fe) // {"kind": "required validation failed", ...}
+//	}
+func FieldErrors(err error) (ValidationErrors, bool) {
+	var fe fieldErrors
+	if !errors.As(err, &fe) {
+		return ValidationErrors{}, false
+	}
+	return ValidationErrors{errs: fe}, true
+}
+
+// Error formats the errors of all the fields into a single string.
+func (ve ValidationErrors) Error() string {
+	return ve.errs.Error()
+}
+
+// Fields returns the paths of the fields in error, sorted.
+func (ve ValidationErrors) Fields() []string {
+	return ve.errs.fields()
+}
+
+// Field returns the error of the field at path, nil if it has none.
+func (ve ValidationErrors) Field(path string) error {
+	return ve.errs[path]
+}
+
+// MarshalJSON encodes the errors as an object of the messages of the
+// fields keyed by their paths.
+func (ve ValidationErrors) MarshalJSON() ([]byte, error) {
+	msgs := make(map[string]string, len(ve.errs))
+	for path, err := range ve.errs {
+		msgs[path] = err.Error()
+	}
+	return json.Marshal(msgs)
+}
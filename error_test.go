@@ -1,7 +1,11 @@
 package confucius
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -25,3 +29,99 @@ func Test_fieldErrors_Error(t *testing.T) {
 		t.Fatalf("empty errors returned non-empty string: %s", got)
 	}
 }
+
+func Test_FieldErrors(t *testing.T) {
+	var cfg Pod
+	err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "invalid")))
+
+	fe, ok := FieldErrors(fmt.Errorf("wrapped: %w", err))
+	if !ok {
+		t.Fatalf("want field errors, got %v", err)
+	}
+
+	want := []string{
+		"kind",
+		"metadata.master",
+		"spec.containers[0].image",
+		"spec.volumes[0].configMap.items",
+		"spec.volumes[1].name",
+	}
+	if !reflect.DeepEqual(want, fe.Fields()) {
+		t.Errorf("want fields %v, got %v", want, fe.Fields())
+	}
+	if fe.Error() != err.Error() {
+		t.Errorf("want error %q, got %q", err.Error(), fe.Error())
+	}
+	if fe.Field("kind") == nil || fe.Field("apiVersion") != nil {
+		t.Errorf("want an error for kind only, got %v and %v", fe.Field("kind"), fe.Field("apiVersion"))
+	}
+
+	data, err := json.Marshal(fe)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d fields in %s", len(want), data)
+	}
+	for _, field := range want {
+		if got[field] != fe.Field(field).Error() {
+			t.Errorf("want %s: %q, got %q", field, fe.Field(field).Error(), got[field])
+		}
+	}
+	if got["kind"] != "required validation failed" {
+		t.Errorf("want kind: required validation failed, got %q", got["kind"])
+	}
+
+	if _, ok := FieldErrors(ErrFileNotFound); ok {
+		t.Errorf("want no field errors for %v", ErrFileNotFound)
+	}
+}
+
+func Test_MissingFilesError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.dev.yaml"), "a: b")
+
+	for _, tc := range []struct {
+		Name     string
+		Profiles []string
+		Want     []string
+	}{
+		{Name: "main", Profiles: []string{"dev"}, Want: []string{"config.yaml"}},
+		{Name: "main and profile", Profiles: []string{"dev", "test"}, Want: []string{"config.yaml", "config.test.yaml"}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			conf := defaultConfucius()
+			conf.dirs = []string{dir}
+			conf.profiles = tc.Profiles
+
+			var cfg struct{}
+			err := conf.Load(&cfg)
+
+			var mfe *MissingFilesError
+			if !errors.As(err, &mfe) {
+				t.Fatalf("want MissingFilesError, got %v", err)
+			}
+			if !errors.Is(err, ErrFileNotFound) {
+				t.Fatalf("want err %v, got %v", ErrFileNotFound, err)
+			}
+			if !reflect.DeepEqual(tc.Want, mfe.Files) || !reflect.DeepEqual(conf.expectedConfigFiles, mfe.Files) {
+				t.Fatalf("want files %v, got %v", tc.Want, mfe.Files)
+			}
+			if !reflect.DeepEqual([]string{dir}, mfe.Dirs) {
+				t.Fatalf("want dirs %v, got %v", []string{dir}, mfe.Dirs)
+			}
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		err := &MissingFilesError{Files: []string{"config.yaml", "config.test.yaml"}}
+		want := `"config.yaml", "config.test.yaml" file(s) not found: file not found`
+		if err.Error() != want {
+			t.Fatalf("want %q, got %q", want, err.Error())
+		}
+	})
+}
@@ -3,9 +3,19 @@ package confucius
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+const (
+	// tagOptProfile is the option of the alt name tag that binds a field
+	// to the active profiles, e.g. `conf:",profile"`.
+	tagOptProfile = "profile"
+	// tagOptSquash is the option of the alt name tag that promotes the
+	// fields of a struct field to its parent, e.g. `conf:",squash"`.
+	tagOptSquash = "squash"
+)
+
 // flattenCfg recursively flattens a cfg struct into
 // a slice of its constituent fields.
 func flattenCfg(cfg interface{}, tagKey string) []*field {
@@ -22,6 +32,7 @@ func flattenCfg(cfg interface{}, tagKey string) []*field {
 // flattenField recursively flattens a field into its
 // constituent fields, filling fs as it goes.
 func flattenField(f *field, fs *[]*field, tagKey string) {
+	f.orig = f.v
 	for (f.v.Kind() == reflect.Ptr || f.v.Kind() == reflect.Interface) && !f.v.IsNil() {
 		f.v = f.v.Elem()
 		f.t = f.v.Type()
@@ -33,6 +44,13 @@ func flattenField(f *field, fs *[]*field, tagKey string) {
 			unexported := f.t.Field(i).PkgPath != ""
 			embedded := f.t.Field(i).Anonymous
 			if unexported && !embedded {
+				// unexported fields cannot be set, but keep the ones that
+				// are tagged so they can be reported.
+				if hasTags(f.t.Field(i).Tag, tagKey) {
+					child := newStructField(f, i, tagKey)
+					child.unexported = true
+					*fs = append(*fs, child)
+				}
 				continue
 			}
 			child := newStructField(f, i, tagKey)
@@ -87,9 +105,12 @@ type field struct {
 
 	v        reflect.Value
 	t        reflect.Type
+	orig     reflect.Value // the value before pointers are dereferenced, v if there are none.
 	st       reflect.StructField
 	sliceIdx int // >=0 if this field is a member of a slice.
 
+	unexported bool // true if this is an unexported field that carries tags.
+
 	structTag
 }
 
@@ -117,6 +138,10 @@ func (f *field) path() (path string) {
 		if f.parent != nil {
 			visit(f.parent)
 		}
+		if f.squashed() {
+			// the fields of a squashed struct are named like its siblings.
+			return
+		}
 		path += f.name()
 		// if it's a slice/array we don't want a dot before the slice indexer
 		// e.g. we want A[0].B instead of A.[0].B
@@ -128,19 +153,130 @@ func (f *field) path() (path string) {
 	return strings.Trim(path, ".")
 }
 
+// envName returns the name of the environment variable of f derived from
+// the envprefix of its nearest ancestor, the prefix followed by the path of f
+// relative to that ancestor, e.g. DB_HOST. The name pinned with the env tag
+// of f takes precedence. An empty string is returned if neither is set.
+func (f *field) envName() string {
+	if f.env != "" {
+		return f.env
+	}
+	for p := f.parent; p != nil; p = p.parent {
+		if p.envPrefix == "" {
+			continue
+		}
+		rel := strings.TrimPrefix(f.path(), p.path())
+		rel = strings.NewReplacer(".", "_", "[", "_", "]", "").Replace(rel)
+		return strings.ToUpper(p.envPrefix + "_" + strings.TrimLeft(rel, "_"))
+	}
+	return ""
+}
+
+// inSlice reports whether f is an element of a slice or array, or is
+// contained in one.
+func (f *field) inSlice() bool {
+	for ; f != nil; f = f.parent {
+		if f.sliceIdx >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// squashed reports whether f is a struct whose fields are promoted to
+// its parent.
+func (f *field) squashed() bool {
+	return f.squash && f.sliceIdx < 0 && f.parent != nil && f.v.Kind() == reflect.Struct
+}
+
+// sibling returns the value of the field named name in the struct that
+// contains f. name is matched against the alt name and the name of the
+// fields as defined in the struct. The fields of squashed structs are
+// siblings of the fields of the struct they are squashed into.
+func (f *field) sibling(name, tagKey string) (reflect.Value, bool) {
+	parent := f.parent
+	for parent != nil && parent.squashed() {
+		parent = parent.parent
+	}
+	if parent == nil || parent.v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	return structField(parent.v, name, tagKey)
+}
+
+// structField returns the value of the field named name in the struct sv,
+// looking into the structs squashed into it.
+func structField(sv reflect.Value, name, tagKey string) (reflect.Value, bool) {
+	for i := 0; i < sv.NumField(); i++ {
+		sf := sv.Type().Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		st := parseTag(sf.Tag, tagKey)
+		if st.squash && sv.Field(i).Kind() == reflect.Struct {
+			if v, ok := structField(sv.Field(i), name, tagKey); ok {
+				return v, true
+			}
+			continue
+		}
+		if sf.Name == name || st.altName == name {
+			return sv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// hasTags reports whether tag contains any of the keys used by confucius.
+// key is the key of the struct tag which contains the field's alt name.
+func hasTags(tag reflect.StructTag, key string) bool {
+	for _, k := range []string{key, "default", "fallback", "validate", "warn", "env", "envprefix", "keychain", "secret", "unit", "empty", "format"} {
+		if _, ok := tag.Lookup(k); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // parseTag parses a fields struct tags into a more easy to use structTag.
 // key is the key of the struct tag which contains the field's alt name.
 func parseTag(tag reflect.StructTag, key string) (st structTag) {
 	if val, ok := tag.Lookup(key); ok {
-		i := strings.Index(val, ",")
-		if i == -1 {
-			i = len(val)
+		opts := strings.Split(val, ",")
+		st.altName = opts[0]
+		for _, opt := range opts[1:] {
+			switch strings.TrimSpace(opt) {
+			case tagOptProfile:
+				st.profile = true
+			case tagOptSquash:
+				st.squash = true
+			}
+		}
+	}
+
+	if val, ok := tag.Lookup("validate"); ok {
+		for _, rule := range strings.Split(val, ",") {
+			switch rule = strings.TrimSpace(rule); rule {
+			case "":
+			case validateRequired:
+				st.required = true
+			case validateRecommended:
+				st.warnings = append(st.warnings, validateRequired)
+			default:
+				// the tag may be shared with other validators, e.g.
+				// go-playground/validator, whose rules are left alone.
+				if isRule(rule) {
+					st.validations = append(st.validations, rule)
+				}
+			}
 		}
-		st.altName = val[:i]
 	}
 
-	if val := tag.Get("validate"); val == "required" {
-		st.required = true
+	if val, ok := tag.Lookup("warn"); ok {
+		for _, rule := range strings.Split(val, ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				st.warnings = append(st.warnings, rule)
+			}
+		}
 	}
 
 	if val, ok := tag.Lookup("default"); ok {
@@ -148,13 +284,87 @@ func parseTag(tag reflect.StructTag, key string) (st structTag) {
 		st.defaultVal = val
 	}
 
+	if val, ok := tag.Lookup("fallback"); ok {
+		st.setFallback = true
+		st.fallbackVal = val
+	}
+
+	if val, ok := tag.Lookup("keychain"); ok {
+		st.keychain = val
+	}
+
+	if val, ok := tag.Lookup("env"); ok {
+		st.env = val
+	}
+
+	if val, ok := tag.Lookup("envprefix"); ok {
+		st.envPrefix = val
+	}
+
+	if val, ok := tag.Lookup("secret"); ok {
+		st.secret, _ = strconv.ParseBool(val)
+	}
+
+	if val, ok := tag.Lookup("unit"); ok {
+		st.unit = val
+	}
+
+	if val, ok := tag.Lookup("empty"); ok {
+		st.setEmpty = true
+		st.emptyVal = val
+	}
+
+	if val, ok := tag.Lookup("format"); ok {
+		opts := strings.Split(val, ",")
+		st.format = opts[0]
+		st.formatOpts = opts[1:]
+	}
+
 	return
 }
 
+// hasFormatOpt reports whether opt was given in the field's format tag.
+func (st structTag) hasFormatOpt(opt string) bool {
+	for _, o := range st.formatOpts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether val is the field's empty sentinel, a value
+// that leaves the field unset. Only scalar values can be sentinels.
+func (st structTag) isEmpty(val interface{}) bool {
+	if !st.setEmpty {
+		return false
+	}
+	switch val.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return fmt.Sprint(val) == st.emptyVal
+	}
+	return false
+}
+
 // structTag contains information gathered from parsing a field's tags.
 type structTag struct {
-	altName    string // the alt name of the field as defined in the tag.
-	required   bool   // true if the tag contained a required validation key.
-	setDefault bool   // true if tag contained a default key.
-	defaultVal string // the value of the default key.
+	altName     string   // the alt name of the field as defined in the tag.
+	required    bool     // true if the tag contained a required validation key.
+	validations []string // the validation rules other than required, e.g. future.
+	warnings    []string // the validation rules that only log a warning, e.g. required.
+	setDefault  bool     // true if tag contained a default key.
+	defaultVal  string   // the value of the default key.
+	setFallback bool     // true if tag contained a fallback key.
+	fallbackVal string   // the value of the fallback key.
+	setEmpty    bool     // true if tag contained an empty key.
+	emptyVal    string   // the value of the empty key, e.g. none.
+	format      string   // the value of the format key, e.g. percent.
+	formatOpts  []string // the options following the format, e.g. clamp.
+	unit        string   // the unit of bare numbers given to a duration, e.g. s.
+	secret      bool     // true if the value must be masked when printed.
+	profile     bool     // true if the field is set to the active profiles.
+	squash      bool     // true if the fields of the struct are promoted to its parent.
+	keychain    string   // the name of the keychain secret holding the value.
+	env         string   // the name of the environment variable pinned to the field.
+	envPrefix   string   // the prefix of the environment variables of the struct's fields.
 }
@@ -44,6 +44,42 @@ func Test_flattenCfg(t *testing.T) {
 	checkField(t, fields[9], "k", "J.k")
 }
 
+func Test_flattenCfg_UnexportedTagged(t *testing.T) {
+	cfg := struct {
+		A string
+		b string `conf:"b"`
+		c string
+		d int    `default:"5"`
+		e string `keychain:"token"`
+		f string `secret:"true"`
+		g int    `unit:"s"`
+		h string `empty:"none"`
+		i string `format:"json"`
+	}{}
+
+	fields := flattenCfg(&cfg, "conf")
+	if len(fields) != 8 {
+		t.Fatalf("len(fields) == %d, expected %d", len(fields), 8)
+	}
+	checkField(t, fields[0], "A", "A")
+	checkField(t, fields[1], "b", "b")
+	checkField(t, fields[2], "d", "d")
+	checkField(t, fields[3], "e", "e")
+	checkField(t, fields[4], "f", "f")
+	checkField(t, fields[5], "g", "g")
+	checkField(t, fields[6], "h", "h")
+	checkField(t, fields[7], "i", "i")
+
+	if fields[0].unexported {
+		t.Errorf("fields[0].unexported == true")
+	}
+	for _, f := range fields[1:] {
+		if !f.unexported {
+			t.Errorf("tagged unexported field %s not marked as unexported", f.name())
+		}
+	}
+}
+
 func Test_newStructField(t *testing.T) {
 	cfg := struct {
 		A int `conf:"a" default:"5" validate:"required"`
@@ -151,6 +187,26 @@ func Test_parseTag(t *testing.T) {
 			tagVal: `conf:"b" validate:"required" default:"go"`,
 			want:   structTag{altName: "b", required: true, setDefault: true, defaultVal: "go"},
 		},
+		{
+			tagVal: `conf:",profile"`,
+			want:   structTag{profile: true},
+		},
+		{
+			tagVal: `conf:",squash"`,
+			want:   structTag{squash: true},
+		},
+		{
+			tagVal: `conf:"b" validate:"required, future"`,
+			want:   structTag{altName: "b", required: true, validations: []string{"future"}},
+		},
+		{
+			tagVal: `conf:"b" validate:"required,min=1,future"`,
+			want:   structTag{altName: "b", required: true, validations: []string{"future"}},
+		},
+		{
+			tagVal: `conf:"c" empty:"-1"`,
+			want:   structTag{altName: "c", setEmpty: true, emptyVal: "-1"},
+		},
 		{
 			tagVal: `conf:"c,omitempty"`,
 			want:   structTag{altName: "c"},
@@ -174,3 +230,31 @@ func checkField(t *testing.T, f *field, name, path string) {
 		t.Errorf("f.path() == %s, expected %s", f.path(), path)
 	}
 }
+
+func Test_field_path_Squash(t *testing.T) {
+	type Meta struct {
+		Name   string `conf:"name"`
+		Labels struct {
+			Team string `conf:"team"`
+		} `conf:"labels"`
+	}
+	cfg := struct {
+		Meta  `conf:",squash"`
+		Items []struct {
+			Meta `conf:",squash"`
+		} `conf:"items"`
+	}{}
+	cfg.Items = make([]struct {
+		Meta `conf:",squash"`
+	}, 1)
+
+	var paths []string
+	for _, f := range flattenCfg(&cfg, "conf") {
+		paths = append(paths, f.path())
+	}
+
+	want := []string{"", "name", "labels", "labels.team", "items", "items[0]", "items[0].name", "items[0].labels", "items[0].labels.team"}
+	if !reflect.DeepEqual(want, paths) {
+		t.Fatalf("\nwant %q\ngot  %q", want, paths)
+	}
+}
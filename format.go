@@ -0,0 +1,389 @@
+package confucius
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// formatPercent parses values like "50%" into a ratio (0.5).
+	formatPercent = "percent"
+	// formatHostPort validates values like "0.0.0.0:8080" or "[::1]:80".
+	formatHostPort = "hostport"
+	// formatOptClamp makes the format clamp the parsed value into its valid range.
+	formatOptClamp = "clamp"
+)
+
+// setFormattedValue sets fv to val, parsing val with the format defined
+// in the field's `format` tag or the unit defined in its `unit` tag. If
+// the field has neither then it falls back to setValue. The value of
+// secret fields is masked in the returned error.
+// fv must be settable else this panics.
+func (c *confucius) setFormattedValue(fv reflect.Value, st structTag, val string) error {
+	if err := c.setUnmaskedValue(fv, st, val); err != nil {
+		return c.maskError(st, err, val)
+	}
+	return nil
+}
+
+func (c *confucius) setUnmaskedValue(fv reflect.Value, st structTag, val string) error {
+	if !st.formatted() {
+		return c.setValue(fv, val)
+	}
+
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return c.setFormattedValue(fv.Elem(), st, val)
+	case reflect.Slice:
+		ss := stringSlice(val, c.sliceDelimiter)
+		slice := reflect.MakeSlice(fv.Type(), len(ss), cap(ss))
+		for i, s := range ss {
+			if err := c.setFormattedValue(slice.Index(i), st, s); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	if st.unit != "" {
+		if fv.Type() != reflect.TypeOf(time.Duration(0)) {
+			return fmt.Errorf("unit is not supported for type %s", fv.Type())
+		}
+		d, err := parseUnitDuration(val, st.unit, c.parseDuration)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch st.format {
+	case formatPercent:
+		if fv.Kind() != reflect.Float32 && fv.Kind() != reflect.Float64 {
+			return fmt.Errorf("format %s is not supported for type %s", st.format, fv.Kind())
+		}
+		f, err := parsePercent(val)
+		if err != nil {
+			return err
+		}
+		if st.hasFormatOpt(formatOptClamp) {
+			f = clamp(f, 0, 1)
+		}
+		fv.SetFloat(f)
+	case formatHostPort:
+		if fv.Kind() != reflect.String {
+			return fmt.Errorf("format %s is not supported for type %s", st.format, fv.Kind())
+		}
+		if err := validateHostPort(val); err != nil {
+			return err
+		}
+		fv.SetString(val)
+	default:
+		return fmt.Errorf("unsupported format %s", st.format)
+	}
+	return nil
+}
+
+// parseUnitDuration parses a duration in which a bare number is taken
+// to be in the given unit, any of the units time.ParseDuration accepts.
+// Values with a unit of their own are parsed with parse.
+//
+//	"30"     unit "s"   --->   30s
+//	"1.5"    unit "m"   --->   1m30s
+//	"500ms"  unit "s"   --->   500ms
+func parseUnitDuration(val, unit string, parse func(string) (time.Duration, error)) (time.Duration, error) {
+	size, err := time.ParseDuration("1" + unit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unit %s", unit)
+	}
+
+	s := strings.TrimSpace(val)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return parse(s)
+	}
+	d := f * float64(size)
+	// MaxInt64 is rounded up to 2^63 as a float, which does not fit.
+	if math.IsNaN(d) || math.IsInf(d, 0) || d >= math.MaxInt64 || d < math.MinInt64 {
+		return 0, fmt.Errorf("duration %s%s out of range", s, unit)
+	}
+	return time.Duration(d), nil
+}
+
+// parsePercent parses a percentage into a ratio. The percent sign
+// is optional, without it val is taken to be a ratio already.
+//
+//	"50%"   --->   0.5
+//	"0.5"   --->   0.5
+func parsePercent(val string) (float64, error) {
+	s := strings.TrimSpace(val)
+	if !strings.HasSuffix(s, "%") {
+		return strconv.ParseFloat(s, 64)
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64)
+	if err != nil {
+		return 0, err
+	}
+	return f / 100, nil
+}
+
+// validateHostPort reports an error if val is not a valid "host:port"
+// endpoint. The host may be empty (e.g. ":8080") but the port may not.
+// The port is a number or a service name, e.g. "localhost:http".
+func validateHostPort(val string) error {
+	_, port, err := net.SplitHostPort(val)
+	if err != nil {
+		return err
+	}
+	if port == "" {
+		return fmt.Errorf("address %s: missing port", val)
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return fmt.Errorf("address %s: invalid port %s", val, port)
+	}
+	return nil
+}
+
+// clamp limits f to the [min, max] range.
+func clamp(f, min, max float64) float64 {
+	if f < min {
+		return min
+	}
+	if f > max {
+		return max
+	}
+	return f
+}
+
+// decodeFormats converts the string values of fields that have a
+// `format` tag into values of the field's type, before the map is
+// decoded into cfg. mapstructure is unaware of the tags confucius
+// uses so these values would otherwise fail to decode.
+func (c *confucius) decodeFormats(vals decodedObject, cfg interface{}) error {
+	plan := c.planFormats(reflect.TypeOf(cfg), make(map[reflect.Type]*formatPlan))
+	if plan == nil || len(plan.fields) == 0 {
+		return nil
+	}
+
+	errs := make(fieldErrors)
+	c.formatValues(vals, plan, "", errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// formatPlan lists the fields of a struct type whose values are visited
+// by formatValues: the fields tagged with a format, a unit or an empty
+// sentinel, and the fields holding structs with such fields. The tags
+// are thus parsed once per type, and the values of the other fields are
+// not walked.
+type formatPlan struct {
+	fields []formatPlanField
+	done   bool // whether fields is complete, false while it is built.
+}
+
+// formatPlanField is a field of a formatPlan.
+type formatPlanField struct {
+	name string
+	typ  reflect.Type
+	st   structTag
+	plan *formatPlan // the plan of the struct held by the field, if any.
+}
+
+// planFormats returns the plan of the struct type held by t, through
+// pointers, slices and arrays, or nil if t holds no struct. plans holds
+// the plans already built, including the ones of recursive types.
+func (c *confucius) planFormats(t reflect.Type, plans map[reflect.Type]*formatPlan) *formatPlan {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if plan, ok := plans[t]; ok {
+		return plan
+	}
+
+	plan := &formatPlan{}
+	plans[t] = plan
+	c.addFormatFields(plan, t, plans)
+	plan.done = true
+	return plan
+}
+
+// addFormatFields adds the fields of the struct type t to plan. The fields
+// of a squashed struct are added as its own, their values are found among
+// its siblings.
+func (c *confucius) addFormatFields(plan *formatPlan, t reflect.Type, plans map[reflect.Type]*formatPlan) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		st := parseTag(sf.Tag, c.tag)
+		if st.squash {
+			ft := sf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				c.addFormatFields(plan, ft, plans)
+			}
+			continue
+		}
+		name := st.altName
+		if name == "" {
+			name = sf.Name
+		}
+
+		field := formatPlanField{name: name, typ: sf.Type, st: st}
+		if !st.formatted() && !st.setEmpty {
+			field.plan = c.planFormats(sf.Type, plans)
+			if field.plan == nil || (field.plan.done && len(field.plan.fields) == 0) {
+				continue
+			}
+		}
+		plan.fields = append(plan.fields, field)
+	}
+}
+
+// formatValues formats in place the values of the fields of plan found in
+// data, the map of the values of a struct at path. Errors are collected in
+// errs keyed by the field's path.
+func (c *confucius) formatValues(data interface{}, plan *formatPlan, path string, errs fieldErrors) {
+	dv := reflect.ValueOf(data)
+	if dv.Kind() != reflect.Map {
+		return
+	}
+
+	for _, field := range plan.fields {
+		key, ok := mapKey(dv, field.name)
+		if !ok {
+			continue
+		}
+
+		fieldPath := strings.TrimPrefix(path+"."+field.name, ".")
+		val := dv.MapIndex(key).Interface()
+
+		if field.st.isEmpty(val) {
+			// the value is unset, as if the key were missing.
+			dv.SetMapIndex(key, reflect.Value{})
+			continue
+		}
+
+		if field.st.formatted() {
+			formatted, err := c.formatValue(val, field.typ, field.st)
+			if err != nil {
+				errs[fieldPath] = err
+				continue
+			}
+			dv.SetMapIndex(key, formatted)
+			continue
+		}
+
+		if field.plan != nil {
+			c.formatNested(val, field.typ, field.plan, fieldPath, errs)
+		}
+	}
+}
+
+// formatNested formats the values of data, at path, decoded into a value of
+// type t which holds the struct of plan, through pointers, slices and arrays.
+func (c *confucius) formatNested(data interface{}, t reflect.Type, plan *formatPlan, path string, errs fieldErrors) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		c.formatValues(data, plan, path, errs)
+	case reflect.Slice, reflect.Array:
+		dv := reflect.ValueOf(data)
+		if dv.Kind() != reflect.Slice {
+			return
+		}
+		for i := 0; i < dv.Len(); i++ {
+			c.formatNested(dv.Index(i).Interface(), t.Elem(), plan, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// formatValue converts data into a value of type t using the field's
+// format or unit. Slices are formatted element by element and numbers
+// are formatted when the field has a unit or the percent format, any
+// other non-string data is returned as is.
+func (c *confucius) formatValue(data interface{}, t reflect.Type, st structTag) (reflect.Value, error) {
+	switch d := data.(type) {
+	case int, int64, uint64, float64:
+		// percents given as numbers are ratios, which are clamped too.
+		if st.unit != "" || st.format == formatPercent {
+			return c.formatValue(fmt.Sprint(d), t, st)
+		}
+	case string:
+		// the value is formatted before it is decoded, so expand the
+		// environment variables the decode hook would have expanded.
+		d, err := c.expandEnv(d)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		fv := reflect.New(t).Elem()
+		if err := c.setFormattedValue(fv, st, d); err != nil {
+			return reflect.Value{}, err
+		}
+		return fv, nil
+	case []interface{}:
+		et := t
+		for et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() != reflect.Slice && et.Kind() != reflect.Array {
+			break
+		}
+		result := make([]interface{}, len(d))
+		for i, elem := range d {
+			formatted, err := c.formatValue(elem, et.Elem(), st)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			result[i] = formatted.Interface()
+		}
+		return reflect.ValueOf(result), nil
+	}
+	return reflect.ValueOf(data), nil
+}
+
+// formatted reports whether the field's value is parsed using a format
+// or a unit rather than as is.
+func (st structTag) formatted() bool {
+	return st.format != "" || st.unit != ""
+}
+
+// mapKey returns the key of m matching name. Like mapstructure, an
+// exact match is preferred before falling back to a case insensitive
+// match.
+func mapKey(m reflect.Value, name string) (reflect.Value, bool) {
+	keys := m.MapKeys()
+	for _, key := range keys {
+		if fmt.Sprint(key.Interface()) == name {
+			return key, true
+		}
+	}
+	for _, key := range keys {
+		if strings.EqualFold(fmt.Sprint(key.Interface()), name) {
+			return key, true
+		}
+	}
+	return reflect.Value{}, false
+}
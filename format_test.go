@@ -0,0 +1,331 @@
+package confucius
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_parsePercent(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Want float64
+	}{
+		{In: "50%", Want: 0.5},
+		{In: "0.5", Want: 0.5},
+		{In: "100%", Want: 1},
+		{In: " 12.5 % ", Want: 0.125},
+	} {
+		t.Run(tc.In, func(t *testing.T) {
+			got, err := parsePercent(tc.In)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.Want {
+				t.Fatalf("want %v, got %v", tc.Want, got)
+			}
+		})
+	}
+
+	t.Run("bad percent", func(t *testing.T) {
+		if _, err := parsePercent("half%"); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
+func Test_validateHostPort(t *testing.T) {
+	for _, tc := range []struct {
+		In      string
+		WantErr bool
+	}{
+		{In: "0.0.0.0:8080"},
+		{In: "localhost:443"},
+		{In: ":8080"},
+		{In: "[::1]:80"},
+		{In: "localhost", WantErr: true},
+		{In: "[::1]", WantErr: true},
+		{In: "localhost:", WantErr: true},
+		{In: "localhost:http"},
+		{In: "localhost:no-such-service", WantErr: true},
+		{In: "localhost:70000", WantErr: true},
+		{In: "::1:80", WantErr: true},
+	} {
+		t.Run(tc.In, func(t *testing.T) {
+			err := validateHostPort(tc.In)
+			if tc.WantErr && err == nil {
+				t.Fatalf("expected err")
+			}
+			if !tc.WantErr && err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+		})
+	}
+}
+
+func Test_confucius_setFormattedValue(t *testing.T) {
+	confucius := defaultConfucius()
+
+	t.Run("percent", func(t *testing.T) {
+		var f *float64
+		fv := reflect.ValueOf(&f).Elem()
+
+		err := confucius.setFormattedValue(fv, structTag{format: formatPercent}, "50%")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if *f != 0.5 {
+			t.Fatalf("want %v, got %v", 0.5, *f)
+		}
+	})
+
+	t.Run("percent clamp", func(t *testing.T) {
+		var f float64
+		fv := reflect.ValueOf(&f).Elem()
+
+		st := structTag{format: formatPercent, formatOpts: []string{formatOptClamp}}
+		for _, val := range []string{"150%", "1.5"} {
+			if err := confucius.setFormattedValue(fv, st, val); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if f != 1 {
+				t.Fatalf("%s: want %v, got %v", val, 1, f)
+			}
+		}
+	})
+
+	t.Run("percent on int returns error", func(t *testing.T) {
+		var i int
+		fv := reflect.ValueOf(&i).Elem()
+
+		if err := confucius.setFormattedValue(fv, structTag{format: formatPercent}, "50%"); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+
+	t.Run("unsupported format returns error", func(t *testing.T) {
+		var s string
+		fv := reflect.ValueOf(&s).Elem()
+
+		if err := confucius.setFormattedValue(fv, structTag{format: "upper"}, "foo"); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
+func Test_confucius_Load_Percent(t *testing.T) {
+	type Limits struct {
+		MaxUtil    float64   `conf:"max" format:"percent"`
+		MinUtil    *float64  `conf:"min" format:"percent" default:"10%"`
+		Thresholds []float32 `conf:"thresholds" format:"percent"`
+		Limits     []struct {
+			CPU float64 `conf:"cpu" format:"percent,clamp"`
+		} `conf:"limits"`
+	}
+
+	var cfg Limits
+	err := Load(&cfg, String(`
+max: "50%"
+thresholds: ["25%", 0.5, "100%"]
+limits:
+  - cpu: "200%"
+  - cpu: 0.1
+  - cpu: 1.5
+  - cpu: "1.5"
+`, DecoderYaml))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if cfg.MaxUtil != 0.5 {
+		t.Errorf("cfg.MaxUtil == %v, expected %v", cfg.MaxUtil, 0.5)
+	}
+	if *cfg.MinUtil != 0.1 {
+		t.Errorf("cfg.MinUtil == %v, expected %v", *cfg.MinUtil, 0.1)
+	}
+	if !reflect.DeepEqual(cfg.Thresholds, []float32{0.25, 0.5, 1}) {
+		t.Errorf("cfg.Thresholds == %v, expected %v", cfg.Thresholds, []float32{0.25, 0.5, 1})
+	}
+	if cfg.Limits[0].CPU != 1 || cfg.Limits[1].CPU != 0.1 || cfg.Limits[2].CPU != 1 || cfg.Limits[3].CPU != 1 {
+		t.Errorf("cfg.Limits == %+v, expected clamped cpu", cfg.Limits)
+	}
+
+	t.Run("bad percent reported as field error", func(t *testing.T) {
+		var cfg Limits
+		err := Load(&cfg, String(`max: "lots%"`, DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if _, ok := err.(fieldErrors)["max"]; !ok {
+			t.Fatalf("want max in fieldErrors, got %+v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_HostPort(t *testing.T) {
+	type Server struct {
+		Addr  string  `conf:"addr" format:"hostport"`
+		Admin *string `conf:"admin" format:"hostport" default:"[::1]:9090"`
+	}
+
+	var cfg Server
+	if err := Load(&cfg, String(`addr: "0.0.0.0:8080"`, DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Addr != "0.0.0.0:8080" {
+		t.Errorf("cfg.Addr == %s, expected %s", cfg.Addr, "0.0.0.0:8080")
+	}
+	if *cfg.Admin != "[::1]:9090" {
+		t.Errorf("cfg.Admin == %s, expected %s", *cfg.Admin, "[::1]:9090")
+	}
+
+	t.Run("missing port reported as field error", func(t *testing.T) {
+		var cfg Server
+		err := Load(&cfg, String(`addr: "0.0.0.0"`, DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if _, ok := err.(fieldErrors)["addr"]; !ok {
+			t.Fatalf("want addr in fieldErrors, got %+v", err)
+		}
+	})
+}
+
+func Test_parseUnitDuration(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Unit string
+		Want time.Duration
+	}{
+		{In: "30", Unit: "s", Want: 30 * time.Second},
+		{In: "1.5", Unit: "m", Want: 90 * time.Second},
+		{In: "250", Unit: "ms", Want: 250 * time.Millisecond},
+		{In: "500ms", Unit: "s", Want: 500 * time.Millisecond},
+		{In: " 2 ", Unit: "h", Want: 2 * time.Hour},
+	} {
+		t.Run(tc.In+tc.Unit, func(t *testing.T) {
+			got, err := parseUnitDuration(tc.In, tc.Unit, time.ParseDuration)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.Want {
+				t.Fatalf("want %v, got %v", tc.Want, got)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		In   string
+		Unit string
+	}{
+		{In: "30", Unit: "fortnights"},
+		{In: "thirty", Unit: "s"},
+		{In: "1e12", Unit: "h"},
+		{In: "9223372036854775807", Unit: "ns"},
+		{In: "NaN", Unit: "s"},
+		{In: "Inf", Unit: "s"},
+		{In: "-Inf", Unit: "s"},
+	} {
+		t.Run("bad "+tc.In+tc.Unit, func(t *testing.T) {
+			if _, err := parseUnitDuration(tc.In, tc.Unit, time.ParseDuration); err == nil {
+				t.Fatalf("expected err")
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_Unit(t *testing.T) {
+	type Server struct {
+		Timeout  time.Duration   `conf:"timeout" unit:"s"`
+		Interval time.Duration   `conf:"interval" unit:"ms"`
+		Idle     *time.Duration  `conf:"idle" unit:"m" default:"5"`
+		Backoff  []time.Duration `conf:"backoff" unit:"s"`
+		Grace    time.Duration   `conf:"grace" unit:"s"`
+	}
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		Decoder Decoder
+	}{
+		{Name: "yaml", Content: `{timeout: 30, interval: 1500, backoff: [1, 2.5, "1m"]}`, Decoder: DecoderYaml},
+		{Name: "json", Content: `{"timeout": 30, "interval": "1500", "backoff": [1, 2.5, "1m"]}`, Decoder: DecoderJSON},
+		{Name: "toml", Content: "timeout = 30\ninterval = 1500\nbackoff = [\"1\", \"2.5\", \"1m\"]", Decoder: DecoderToml},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			os.Clearenv()
+			setenv(t, "APP_GRACE", "10")
+
+			var cfg Server
+			if err := Load(&cfg, String(tc.Content, tc.Decoder), UseEnv("app")); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			if cfg.Timeout != 30*time.Second {
+				t.Errorf("cfg.Timeout == %v, expected %v", cfg.Timeout, 30*time.Second)
+			}
+			if cfg.Interval != 1500*time.Millisecond {
+				t.Errorf("cfg.Interval == %v, expected %v", cfg.Interval, 1500*time.Millisecond)
+			}
+			if cfg.Idle == nil || *cfg.Idle != 5*time.Minute {
+				t.Errorf("cfg.Idle == %v, expected %v", cfg.Idle, 5*time.Minute)
+			}
+			want := []time.Duration{time.Second, 2500 * time.Millisecond, time.Minute}
+			if !reflect.DeepEqual(want, cfg.Backoff) {
+				t.Errorf("cfg.Backoff == %v, expected %v", cfg.Backoff, want)
+			}
+			if cfg.Grace != 10*time.Second {
+				t.Errorf("cfg.Grace == %v, expected %v", cfg.Grace, 10*time.Second)
+			}
+		})
+	}
+
+	t.Run("unit on int returns error", func(t *testing.T) {
+		var cfg struct {
+			Timeout int `conf:"timeout" unit:"s"`
+		}
+		err := Load(&cfg, String(`timeout: 30`, DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if _, ok := err.(fieldErrors)["timeout"]; !ok {
+			t.Fatalf("want timeout in fieldErrors, got %+v", err)
+		}
+	})
+}
+
+func Test_confucius_planFormats(t *testing.T) {
+	type Node struct {
+		Weight   float64 `conf:"weight" format:"percent"`
+		Children []*Node `conf:"children"`
+	}
+	type Config struct {
+		Name  string `conf:"name"`
+		Inner struct {
+			Host string `conf:"host"`
+		} `conf:"inner"`
+		Addr  string `conf:"addr" format:"hostport"`
+		Proxy string `conf:"proxy" empty:"none"`
+		Tree  Node   `conf:"tree"`
+	}
+
+	plan := defaultConfucius().planFormats(reflect.TypeOf(&Config{}), make(map[reflect.Type]*formatPlan))
+
+	var names []string
+	for _, f := range plan.fields {
+		names = append(names, f.name)
+	}
+	if want := []string{"addr", "proxy", "tree"}; !reflect.DeepEqual(want, names) {
+		t.Fatalf("want fields %v, got %v", want, names)
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`{tree: {weight: "10%", children: [{weight: "20%", children: [{weight: "30%"}]}]}}`, DecoderYaml))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got := cfg.Tree.Children[0].Children[0].Weight; got != 0.3 {
+		t.Fatalf("want nested weight 0.3, got %v", got)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/netologist/confucius
 go 1.16
 
 require (
+	github.com/hashicorp/hcl v1.0.0
 	github.com/imdario/mergo v0.3.12
 	github.com/mattn/goveralls v0.0.8 // indirect
 	github.com/mitchellh/mapstructure v1.1.2
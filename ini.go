@@ -0,0 +1,139 @@
+package confucius
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeINI decodes an ini or properties file into a nested object.
+//
+// Sections and dotted keys become nested objects, lines starting with
+// `;` or `#` are comments and a key that is repeated is decoded as a
+// list of its values:
+//
+//	name = ${APP_NAME:app}
+//	replicas = abc
+//	replicas = xyz
+//
+//	[logger]
+//	log_level = debug
+//
+// Quoted keys, e.g. `"my.dotted.key" = value`, are not nested.
+// Properties files may additionally separate keys from values with `:`.
+// sep separates the nested keys of sections and properties, e.g. `.`.
+func decodeINI(reader io.Reader, properties bool, sep string) (decodedObject, error) {
+	vals := make(decodedObject)
+	section := []string{}
+
+	scanner := bufio.NewScanner(reader)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "", strings.HasPrefix(line, ";"), strings.HasPrefix(line, "#"):
+			continue
+		case !properties && strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section %q", lineNo, line)
+			}
+			section = splitINIKey(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"), sep)
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if properties {
+			if i := strings.Index(line, ":"); i >= 0 && (eq < 0 || i < eq) {
+				eq = i
+			}
+		}
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: missing separator in %q", lineNo, line)
+		}
+
+		key := strings.TrimSpace(line[:eq])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key in %q", lineNo, line)
+		}
+
+		keys := append(append([]string{}, section...), splitINIKey(key, sep)...)
+		if err := setNested(vals, keys, unquote(strings.TrimSpace(line[eq+1:])), sep); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// splitINIKey splits a section name or key into its nested keys
+// separated by sep. Quoted parts of the key are taken literally so that keys which
+// contain the separator can be used:
+//
+//	a.b.c            --->   []string{"a", "b", "c"}
+//	a."b.c"          --->   []string{"a", "b.c"}
+//	"my.dotted.key"  --->   []string{"my.dotted.key"}
+func splitINIKey(key, sep string) []string {
+	var (
+		keys  []string
+		start int
+		quote byte
+	)
+	for i := 0; i < len(key); i++ {
+		switch {
+		case quote != 0:
+			if key[i] == quote {
+				quote = 0
+			}
+		case key[i] == '"' || key[i] == '\'':
+			quote = key[i]
+		case sep != "" && strings.HasPrefix(key[i:], sep):
+			keys = append(keys, unquote(strings.TrimSpace(key[start:i])))
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(keys, unquote(strings.TrimSpace(key[start:])))
+}
+
+// setNested sets val in m under the nested keys, creating the objects
+// in between. If the key is already set val is appended to a list of
+// its values. sep joins the keys in errors.
+func setNested(m decodedObject, keys []string, val, sep string) error {
+	for i, key := range keys[:len(keys)-1] {
+		switch next := m[key].(type) {
+		case nil:
+			child := make(decodedObject)
+			m[key] = child
+			m = child
+		case decodedObject:
+			m = next
+		default:
+			return fmt.Errorf("key %s is both a value and a section", strings.Join(keys[:i+1], sep))
+		}
+	}
+
+	key := keys[len(keys)-1]
+	switch existing := m[key].(type) {
+	case nil:
+		m[key] = val
+	case decodedObject:
+		return fmt.Errorf("key %s is both a value and a section", strings.Join(keys, sep))
+	case []interface{}:
+		m[key] = append(existing, val)
+	default:
+		m[key] = []interface{}{existing, val}
+	}
+	return nil
+}
+
+// unquote removes matching surrounding quotes from s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
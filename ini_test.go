@@ -0,0 +1,203 @@
+package confucius
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_decodeINI(t *testing.T) {
+	for _, tc := range []struct {
+		Name       string
+		In         string
+		Properties bool
+		Want       decodedObject
+	}{
+		{
+			Name: "sections",
+			In:   "a = 1\n[server]\nhost = localhost\n[server.tls]\ncert = /etc/cert",
+			Want: decodedObject{
+				"a": "1",
+				"server": decodedObject{
+					"host": "localhost",
+					"tls":  decodedObject{"cert": "/etc/cert"},
+				},
+			},
+		},
+		{
+			Name: "comments",
+			In:   "; comment\n# another comment\n\na = 1",
+			Want: decodedObject{"a": "1"},
+		},
+		{
+			Name: "values with separators",
+			In:   "dsn = user=admin password=secret\nurl = \"http://localhost:9000\"",
+			Want: decodedObject{
+				"dsn": "user=admin password=secret",
+				"url": "http://localhost:9000",
+			},
+		},
+		{
+			Name: "duplicate keys",
+			In:   "port = 80\nport = 443\nport = 8080",
+			Want: decodedObject{"port": []interface{}{"80", "443", "8080"}},
+		},
+		{
+			Name:       "dotted properties",
+			In:         "server.host=localhost\nserver.port: 8080\nserver.url=http://localhost:8080",
+			Properties: true,
+			Want: decodedObject{
+				"server": decodedObject{
+					"host": "localhost",
+					"port": "8080",
+					"url":  "http://localhost:8080",
+				},
+			},
+		},
+		{
+			Name: "quoted keys",
+			In:   "\"my.dotted.key\" = a\nserver.'tls.cert' = b\n[\"log.level\"]\ndebug = c",
+			Want: decodedObject{
+				"my.dotted.key": "a",
+				"server":        decodedObject{"tls.cert": "b"},
+				"log.level":     decodedObject{"debug": "c"},
+			},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := decodeINI(strings.NewReader(tc.In), tc.Properties, DefaultFlatKeySeparator)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(tc.Want, got) {
+				t.Fatalf("want %+v, got %+v", tc.Want, got)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		Name string
+		In   string
+	}{
+		{Name: "unterminated section", In: "[server\nhost = localhost"},
+		{Name: "missing separator", In: "host"},
+		{Name: "missing key", In: "= localhost"},
+		{Name: "value and section", In: "server = a\nserver.host = localhost"},
+		{Name: "section and value", In: "server.host = localhost\nserver = a"},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if _, err := decodeINI(strings.NewReader(tc.In), false, DefaultFlatKeySeparator); err == nil {
+				t.Fatalf("expected err")
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_INI(t *testing.T) {
+	type Server struct {
+		Host   string `conf:"host"`
+		Logger struct {
+			LogLevel string `conf:"log_level"`
+			Appender string `conf:"appender"`
+		} `conf:"logger"`
+		Replicas []string `conf:"replicas"`
+	}
+
+	for _, f := range []string{"server.ini", "server.properties"} {
+		t.Run(f, func(t *testing.T) {
+			setenv(t, "SERVICE_HOST", "10.0.0.1")
+			defer os.Unsetenv("SERVICE_HOST")
+
+			var cfg Server
+			err := Load(&cfg, File(f), Dirs(filepath.Join("testdata", "valid")))
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			want := Server{Host: "10.0.0.1", Replicas: []string{"abc", "xyz"}}
+			want.Logger.LogLevel = "debug"
+			want.Logger.Appender = "file"
+
+			if !reflect.DeepEqual(want, cfg) {
+				t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+			}
+		})
+	}
+}
+
+func Test_decodeINI_separator(t *testing.T) {
+	want := decodedObject{
+		"server": decodedObject{
+			"host": "localhost",
+			"tls":  decodedObject{"cert.pem": "/etc/cert"},
+		},
+		"log.level": "debug",
+	}
+
+	for _, tc := range []struct {
+		Name string
+		Sep  string
+		In   string
+	}{
+		{Name: "dot", Sep: ".", In: "\"log.level\" = debug\n[server]\nhost = localhost\ntls.\"cert.pem\" = /etc/cert"},
+		{Name: "double underscore", Sep: "__", In: "log.level = debug\n[server]\nhost = localhost\ntls__cert.pem = /etc/cert"},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := decodeINI(strings.NewReader(tc.In), false, tc.Sep)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Fatalf("want %+v, got %+v", want, got)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		got, err := decodeINI(strings.NewReader("a.b__c = 1"), true, "")
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if want := (decodedObject{"a.b__c": "1"}); !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %+v, got %+v", want, got)
+		}
+	})
+}
+
+func Test_confucius_Load_FlatKeySeparator(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Host string `conf:"host"`
+			TLS  struct {
+				Cert string `conf:"cert"`
+			} `conf:"tls"`
+		} `conf:"server"`
+	}
+
+	var want Config
+	want.Server.Host = "localhost"
+	want.Server.TLS.Cert = "/etc/cert"
+
+	for _, tc := range []struct {
+		Name    string
+		In      string
+		Decoder Decoder
+		Options []Option
+	}{
+		{Name: "default properties", In: "server.host = localhost\nserver.tls.cert: /etc/cert", Decoder: DecoderProperties},
+		{Name: "properties", In: "server__host = localhost\nserver__tls__cert: /etc/cert", Decoder: DecoderProperties, Options: []Option{FlatKeySeparator("__")}},
+		{Name: "ini", In: "[server]\nhost = localhost\n[server__tls]\ncert = /etc/cert", Decoder: DecoderINI, Options: []Option{FlatKeySeparator("__")}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			if err := Load(&cfg, append(tc.Options, String(tc.In, tc.Decoder))...); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(want, cfg) {
+				t.Errorf("\nwant %+v\ngot  %+v", want, cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package confucius
+
+// stripJSONC converts JSON with comments (JSONC) into standard JSON by
+// removing `//` line comments, `/* */` block comments and trailing commas
+// before closing brackets. Comment markers inside string literals are
+// preserved.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+
+		switch {
+		case ch == '"':
+			// copy the whole string literal, honoring escaped characters.
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(data) {
+				i = len(data) - 1
+			}
+			out = append(out, data[start:i+1]...)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			for i += 2; i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/'); i++ {
+			}
+			i++
+		case ch == ']' || ch == '}':
+			out = trimTrailingComma(out)
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+
+	return out
+}
+
+// trimTrailingComma removes a comma at the end of b, ignoring any
+// whitespace following it.
+func trimTrailingComma(b []byte) []byte {
+	for i := len(b) - 1; i >= 0; i-- {
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case ',':
+			return append(b[:i], b[i+1:]...)
+		}
+		break
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package confucius
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_stripJSONC(t *testing.T) {
+	for _, tc := range []struct {
+		Name string
+		In   string
+		Want map[string]interface{}
+	}{
+		{
+			Name: "line comments",
+			In:   "{\n // comment\n \"a\": 1 // trailing\n}",
+			Want: map[string]interface{}{"a": 1.0},
+		},
+		{
+			Name: "block comments",
+			In:   `{/* a */"a": /* inline */ 1 /* multi ` + "\n" + ` line */}`,
+			Want: map[string]interface{}{"a": 1.0},
+		},
+		{
+			Name: "comment markers inside strings",
+			In:   `{"url": "http://localhost:9000", "glob": "/* not a comment */", "quote": "a \" // b"}`,
+			Want: map[string]interface{}{
+				"url":   "http://localhost:9000",
+				"glob":  "/* not a comment */",
+				"quote": `a " // b`,
+			},
+		},
+		{
+			Name: "trailing commas",
+			In:   "{\"a\": [1, 2, ],\n \"b\": {\"c\": \"d\",\n},\n}",
+			Want: map[string]interface{}{
+				"a": []interface{}{1.0, 2.0},
+				"b": map[string]interface{}{"c": "d"},
+			},
+		},
+		{
+			Name: "commas inside strings",
+			In:   `{"a": "x,]", "b": "y,}",}`,
+			Want: map[string]interface{}{"a": "x,]", "b": "y,}"},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var got map[string]interface{}
+			if err := json.Unmarshal(stripJSONC([]byte(tc.In)), &got); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(tc.Want, got) {
+				t.Fatalf("want %+v, got %+v", tc.Want, got)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_JSONC(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+		URL  string `conf:"url"`
+	}
+
+	var cfg Server
+	err := Load(&cfg, String(`{
+		// the host to bind to
+		"host": "127.0.0.1",
+		"url": "http://127.0.0.1:8080", /* public url */
+	}`, DecoderJSONC))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Server{Host: "127.0.0.1", URL: "http://127.0.0.1:8080"}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+	}
+}
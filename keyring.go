@@ -0,0 +1,81 @@
+package confucius
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Keyring looks up secrets stored in an OS keychain. Implementations
+// must return ErrSecretNotFound when the secret does not exist.
+type Keyring interface {
+	Get(service, key string) (string, error)
+}
+
+// osKeyring is the default Keyring. It uses the `security` tool on macOS
+// and the `secret-tool` (libsecret) tool on linux.
+type osKeyring struct{}
+
+// Get returns the secret stored under key for the given service.
+func (osKeyring) Get(service, key string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "username", key)
+	default:
+		return "", fmt.Errorf("keychain is not supported on %s", runtime.GOOS)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", err
+		}
+		stderr := strings.TrimSpace(string(exitErr.Stderr))
+		if secretNotFound(runtime.GOOS, exitErr.ExitCode(), stderr) {
+			return "", ErrSecretNotFound
+		}
+		if stderr == "" {
+			return "", fmt.Errorf("%s: %w", cmd.Args[0], err)
+		}
+		return "", fmt.Errorf("%s: %w: %s", cmd.Args[0], err, stderr)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// secretNotFound reports whether the keychain tool of goos exited with
+// code and stderr because the secret does not exist, rather than because
+// the keychain could not be used, e.g. when it is locked.
+//
+//	security     --->   exit code 44, errSecItemNotFound
+//	secret-tool  --->   exit code 1 and nothing on stderr
+func secretNotFound(goos string, code int, stderr string) bool {
+	switch goos {
+	case "darwin":
+		return code == 44
+	case "linux":
+		return code == 1 && stderr == ""
+	}
+	return false
+}
+
+// setFromKeychain sets fv, the field at path, to the secret named key,
+// if the secret exists.
+func (c *confucius) setFromKeychain(fv reflect.Value, st structTag, path string) error {
+	val, err := c.keyring.Get(c.keychainService, st.keychain)
+	if errors.Is(err, ErrSecretNotFound) {
+		c.logger.Debug("keychain secret not found: %s", st.keychain)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.setSource(path, "keychain:"+st.keychain)
+	return c.setFormattedValue(fv, st, val)
+}
@@ -0,0 +1,124 @@
+package confucius
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+type fakeKeyring map[string]string
+
+func (k fakeKeyring) Get(service, key string) (string, error) {
+	if service != "myapp" {
+		return "", fmt.Errorf("unknown service %s", service)
+	}
+	if val, ok := k[key]; ok {
+		return val, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+func Test_confucius_Load_Keychain(t *testing.T) {
+	type Config struct {
+		Database struct {
+			Host     string `conf:"host"`
+			Password string `conf:"password" keychain:"db_password" validate:"required"`
+			Token    string `conf:"token" keychain:"db_token" default:"none"`
+		} `conf:"database"`
+	}
+
+	keyring := fakeKeyring{"db_password": "S3cr3t"}
+
+	t.Run("secrets are merged", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg,
+			String(`database: {host: "localhost"}`, DecoderYaml),
+			Keychain("myapp"),
+			UseKeyring(keyring),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if cfg.Database.Host != "localhost" {
+			t.Errorf("cfg.Database.Host == %s, expected %s", cfg.Database.Host, "localhost")
+		}
+		if cfg.Database.Password != "S3cr3t" {
+			t.Errorf("cfg.Database.Password == %s, expected %s", cfg.Database.Password, "S3cr3t")
+		}
+		if cfg.Database.Token != "none" {
+			t.Errorf("cfg.Database.Token == %s, expected %s", cfg.Database.Token, "none")
+		}
+	})
+
+	t.Run("env overrides secrets", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "MYAPP_DATABASE_PASSWORD", "from-env")
+
+		var cfg Config
+		err := Load(&cfg,
+			String(`database: {host: "localhost"}`, DecoderYaml),
+			Keychain("myapp"),
+			UseKeyring(keyring),
+			UseEnv("myapp"),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		if cfg.Database.Password != "from-env" {
+			t.Errorf("cfg.Database.Password == %s, expected %s", cfg.Database.Password, "from-env")
+		}
+	})
+
+	t.Run("keyring errors reported as field errors", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg,
+			String(`database: {host: "localhost"}`, DecoderYaml),
+			Keychain("otherapp"),
+			UseKeyring(keyring),
+		)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if _, ok := err.(fieldErrors)["database.password"]; !ok {
+			t.Errorf("want database.password in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("keychain is not used without option", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg,
+			String(`database: {host: "localhost", password: "plain"}`, DecoderYaml),
+			UseKeyring(keyring),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Database.Password != "plain" {
+			t.Errorf("cfg.Database.Password == %s, expected %s", cfg.Database.Password, "plain")
+		}
+	})
+}
+
+func Test_secretNotFound(t *testing.T) {
+	for _, tc := range []struct {
+		Name   string
+		GOOS   string
+		Code   int
+		Stderr string
+		Want   bool
+	}{
+		{"security not found", "darwin", 44, "security: SecKeychainSearchCopyNext: The specified item could not be found in the keychain.", true},
+		{"security locked", "darwin", 36, "security: SecKeychainSearchCopyNext: User interaction is not allowed.", false},
+		{"secret-tool not found", "linux", 1, "", true},
+		{"secret-tool no dbus", "linux", 1, "secret-tool: Cannot autolaunch D-Bus without X11 $DISPLAY", false},
+		{"unsupported os", "windows", 1, "", false},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := secretNotFound(tc.GOOS, tc.Code, tc.Stderr); got != tc.Want {
+				t.Fatalf("secretNotFound() == %v, expected %v", got, tc.Want)
+			}
+		})
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"io"
 	"log"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 type LogCallback func(level LogLevel, message string, file string, line int)
 
+// StructuredLogCallback receives log entries along with their key-value
+// context, e.g. {"file": "pod.yaml", "action": "merge"}.
+type StructuredLogCallback func(level LogLevel, message string, fields map[string]interface{})
+
 type LogOption func(l *logger)
 
 type LogLevel int
@@ -66,11 +72,23 @@ func defaultCallback(output io.Writer) LogCallback {
 	}
 }
 
+// Callback returns a LogOption that sends the log entries to callback.
+// It can be combined with SetOutput to send the entries to both.
 func Callback(callback LogCallback) LogOption {
 	return func(l *logger) {
 		l.useCallback = true
 		l.callback = callback
-		l.output = io.Discard
+	}
+}
+
+// StructuredCallback returns a LogOption that sends the log entries to
+// callback with their fields kept apart from the message, so adapters for
+// structured loggers such as zap or zerolog can log them as key-values.
+// It can be combined with Callback and SetOutput, which receive the fields
+// formatted into the message.
+func StructuredCallback(callback StructuredLogCallback) LogOption {
+	return func(l *logger) {
+		l.structuredCallback = callback
 	}
 }
 
@@ -80,34 +98,77 @@ func SetLevel(level LogLevel) LogOption {
 	}
 }
 
+// SetOutput returns a LogOption that writes the log entries to writer.
+// When a callback is also used the entries are written to writer after
+// they are sent to the callback.
 func SetOutput(writer io.Writer) LogOption {
 	return func(l *logger) {
+		l.output = writer
 		if !l.useCallback {
-			l.output = writer
 			l.callback = defaultCallback(writer)
-		} else {
-			l.Warn("log output feature is not usable when using callback")
 		}
 	}
 }
 
 type logger struct {
-	useCallback bool
-	callback    LogCallback
-	level       LogLevel
-	output      io.Writer
+	useCallback        bool
+	callback           LogCallback
+	structuredCallback StructuredLogCallback
+	level              LogLevel
+	output             io.Writer
 }
 
 func (l *logger) Print(level LogLevel, message string, args ...interface{}) {
+	// skip print, Print and the level method calling it, e.g. Debug.
+	l.print(3, level, nil, fmt.Sprintf(message, args...))
+}
+
+// Event logs message along with the key-value context of an event of
+// the loading pipeline.
+func (l *logger) Event(level LogLevel, fields map[string]interface{}, message string, args ...interface{}) {
+	l.print(2, level, fields, fmt.Sprintf(message, args...))
+}
+
+// print sends the entry to the callbacks and the output. skip is the
+// number of stack frames to skip to report the caller logging the entry.
+func (l *logger) print(skip int, level LogLevel, fields map[string]interface{}, msg string) {
 	if level < l.level {
 		return
 	}
-	msg := fmt.Sprintf(message, args...)
-	if _, file, line, ok := runtime.Caller(2); ok {
-		l.callback(level, msg, file, line)
-	} else {
-		l.callback(level, msg, "n/a", -1)
+	file, line := "n/a", -1
+	if _, f, n, ok := runtime.Caller(skip); ok {
+		file, line = f, n
+	}
+
+	if l.structuredCallback != nil {
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		l.structuredCallback(level, msg, fields)
+	}
+
+	msg += formatFields(fields)
+	l.callback(level, msg, file, line)
+	if l.useCallback && l.output != io.Discard {
+		defaultCallback(l.output)(level, msg, file, line)
+	}
+}
+
+// formatFields formats fields as key=value pairs sorted by key.
+//
+//	{"file": "pod.yaml", "action": "merge"}   --->   " action=merge file=pod.yaml"
+func formatFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&sb, " %s=%v", key, fields[key])
 	}
+	return sb.String()
 }
 
 func (l *logger) Debug(message string, args ...interface{}) {
@@ -117,3 +117,114 @@ func Test_logger(t *testing.T) {
 		})
 	}
 }
+
+func Test_logger_CallbackAndOutput(t *testing.T) {
+	for _, tc := range []struct {
+		Name string
+		Opts func(callback LogCallback, output io.Writer) []LogOption
+	}{
+		{
+			Name: "callback first",
+			Opts: func(callback LogCallback, output io.Writer) []LogOption {
+				return []LogOption{Callback(callback), SetOutput(output)}
+			},
+		},
+		{
+			Name: "output first",
+			Opts: func(callback LogCallback, output io.Writer) []LogOption {
+				return []LogOption{SetOutput(output), Callback(callback)}
+			},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var messages []string
+			callback := func(level LogLevel, message, file string, line int) {
+				messages = append(messages, message)
+			}
+			var output bytes.Buffer
+
+			l := defaultLogger()
+			for _, opt := range append(tc.Opts(callback, &output), SetLevel(InfoLevel)) {
+				opt(l)
+			}
+
+			l.Debug("suppressed")
+			l.Info("message")
+
+			if len(messages) != 1 || messages[0] != "message" {
+				t.Errorf("unexpected callback entries: %+v", messages)
+			}
+			if !strings.Contains(output.String(), "INFO") || !strings.Contains(output.String(), "message") {
+				t.Errorf("unexpected output: %q", output.String())
+			}
+			if strings.Contains(output.String(), "suppressed") {
+				t.Errorf("entry below level written to output: %q", output.String())
+			}
+		})
+	}
+}
+
+func Test_formatFields(t *testing.T) {
+	got := formatFields(map[string]interface{}{"file": "pod.yaml", "action": "merge"})
+	if want := " action=merge file=pod.yaml"; got != want {
+		t.Fatalf("want %q, got %q", want, got)
+	}
+	if got := formatFields(nil); got != "" {
+		t.Fatalf("want empty string, got %q", got)
+	}
+}
+
+func Test_logger_StructuredCallback(t *testing.T) {
+	type entry struct {
+		level   LogLevel
+		message string
+		fields  map[string]interface{}
+	}
+
+	var entries []entry
+	var messages []string
+
+	l := defaultLogger()
+	StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+		entries = append(entries, entry{level, message, fields})
+	})(l)
+	Callback(func(level LogLevel, message, file string, line int) {
+		messages = append(messages, message)
+	})(l)
+
+	l.Event(InfoLevel, map[string]interface{}{"file": "pod.yaml"}, "file %s", "found")
+	l.Warn("plain")
+
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, got %+v", entries)
+	}
+	if entries[0].level != InfoLevel || entries[0].message != "file found" || entries[0].fields["file"] != "pod.yaml" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].fields == nil || len(entries[1].fields) != 0 {
+		t.Errorf("want empty fields, got %+v", entries[1].fields)
+	}
+
+	want := []string{"file found file=pod.yaml", "plain"}
+	if strings.Join(messages, "|") != strings.Join(want, "|") {
+		t.Errorf("want messages %+v, got %+v", want, messages)
+	}
+}
+
+func Test_logger_Caller(t *testing.T) {
+	var files []string
+
+	l := defaultLogger()
+	Callback(func(level LogLevel, message, file string, line int) {
+		files = append(files, file)
+	})(l)
+
+	l.Info("message")
+	l.Event(InfoLevel, nil, "message")
+
+	for _, file := range files {
+		if !strings.HasSuffix(file, "logger_test.go") {
+			t.Errorf("want caller in logger_test.go, got %s", file)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// matchCamelSnake reports whether key and name are the same once their
+// case, underscores and dashes are ignored, e.g. max_percent_util and
+// MaxPercentUtil.
+func matchCamelSnake(key, name string) bool {
+	normalize := strings.NewReplacer("_", "", "-", "")
+	return strings.EqualFold(normalize.Replace(key), normalize.Replace(name))
+}
+
+// matchKeys renames the keys of vals that the matcher given with the
+// KeyMatcher option binds to the fields of t, the type of the config
+// struct, to the names of those fields, so that they are decoded into them.
+func (c *confucius) matchKeys(vals decodedObject, t reflect.Type) decodedObject {
+	if c.keyMatcher == nil {
+		return vals
+	}
+	return decodedObject(c.matchValueKeys(map[string]interface{}(vals), t, "").(map[string]interface{}))
+}
+
+// matchValueKeys renames the keys of the maps of val, at path, decoded into
+// a value of type t, recursing into nested structs, slices and maps.
+func (c *confucius) matchValueKeys(val interface{}, t reflect.Type, path string) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) || reflect.PtrTo(t).Implements(configDecoderType) {
+		// such types decode the values as they are.
+		return val
+	}
+
+	switch v := val.(type) {
+	case decodedObject:
+		return decodedObject(c.matchValueKeys(map[string]interface{}(v), t, path).(map[string]interface{}))
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			return c.matchStructKeys(v, t, path)
+		case reflect.Map:
+			for key, elem := range v {
+				v[key] = c.matchValueKeys(elem, t.Elem(), joinPath(path, key))
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, elem := range v {
+				v[i] = c.matchValueKeys(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case []map[string]interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, elem := range v {
+				v[i] = c.matchValueKeys(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i)).(map[string]interface{})
+			}
+		}
+	}
+	return val
+}
+
+// matchStructKeys renames the keys of m, at path, matching the fields of the
+// struct type t, along with the sources of their values. Keys equal to the
+// name of a field are kept as they are.
+func (c *confucius) matchStructKeys(m map[string]interface{}, t reflect.Type, path string) map[string]interface{} {
+	fields := make(map[string]reflect.Type)
+	var names []string
+	c.collectFieldNames(t, fields, &names)
+
+	result := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		name := key
+		if _, ok := fields[key]; !ok {
+			for _, n := range names {
+				if c.keyMatcher(key, n) {
+					name = n
+					break
+				}
+			}
+		}
+		if _, exact := m[name]; exact && name != key {
+			// the key named exactly like the field wins, along with the
+			// source of its value.
+			continue
+		}
+		if _, exists := result[name]; exists && name != key {
+			continue
+		}
+		if name != key {
+			c.renameFileSources(joinPath(path, key), joinPath(path, name))
+		}
+		if ft, ok := fields[name]; ok {
+			val = c.matchValueKeys(val, ft, joinPath(path, name))
+		}
+		result[name] = val
+	}
+	return result
+}
+
+// collectFieldNames adds the names the fields of the struct type t are
+// decoded from, their alt names or Go names, to fields and names, in the
+// order of the fields. The fields of squashed structs are added as well.
+func (c *confucius) collectFieldNames(t reflect.Type, fields map[string]reflect.Type, names *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		st := parseTag(sf.Tag, c.tag)
+		if st.squash && sf.Type.Kind() == reflect.Struct {
+			c.collectFieldNames(sf.Type, fields, names)
+			continue
+		}
+		name := sf.Name
+		if st.altName != "" {
+			name = st.altName
+		}
+		if name == "-" {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = sf.Type
+			*names = append(*names, name)
+		}
+	}
+}
+
+// renameFileSources moves the sources of the values at from, and of the
+// values nested in them, to the path to.
+func (c *confucius) renameFileSources(from, to string) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	if from == to {
+		return
+	}
+	for p, origin := range c.fileSources {
+		if p == from || strings.HasPrefix(p, from+".") || strings.HasPrefix(p, from+"[") {
+			delete(c.fileSources, p)
+			c.fileSources[to+p[len(from):]] = origin
+		}
+	}
+}
+
+// joinPath returns the path of the key of the map at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
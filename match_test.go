@@ -0,0 +1,142 @@
+package confucius
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_matchCamelSnake(t *testing.T) {
+	for _, tc := range []struct {
+		key, name string
+		want      bool
+	}{
+		{"max_percent_util", "MaxPercentUtil", true},
+		{"max-percent-util", "MaxPercentUtil", true},
+		{"maxPercentUtil", "MaxPercentUtil", true},
+		{"MAX_PERCENT_UTIL", "max_percent_util", true},
+		{"max_util", "MaxPercentUtil", false},
+		{"", "Name", false},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := matchCamelSnake(tc.key, tc.name); got != tc.want {
+				t.Errorf("matchCamelSnake(%q, %q) = %v, want %v", tc.key, tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_MatchCamelSnake(t *testing.T) {
+	type Container struct {
+		ImageName string
+	}
+	type Config struct {
+		MaxPercentUtil int
+		ListenAddr     string `conf:"listenAddr"`
+		Containers     []Container
+		Labels         map[string]string
+		Exact          string
+		ExactOther     string `conf:"exact_other"`
+	}
+
+	content := `{
+		"max_percent_util": 80,
+		"listen-addr": ":8080",
+		"containers": [{"image_name": "nginx"}],
+		"labels": {"app_name": "web"},
+		"exact_other": "exact"
+	}`
+
+	t.Run("matches keys", func(t *testing.T) {
+		sources := map[string]string{}
+		var cfg Config
+		if err := Load(&cfg, String(content, DecoderJSON), MatchCamelSnake(), TrackSources(&sources)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.MaxPercentUtil != 80 {
+			t.Errorf("want max percent util 80, got %d", cfg.MaxPercentUtil)
+		}
+		if cfg.ListenAddr != ":8080" {
+			t.Errorf("want listen addr :8080, got %q", cfg.ListenAddr)
+		}
+		if len(cfg.Containers) != 1 || cfg.Containers[0].ImageName != "nginx" {
+			t.Errorf("want containers [{nginx}], got %+v", cfg.Containers)
+		}
+		if cfg.Labels["app_name"] != "web" {
+			t.Errorf("want map keys kept as they are, got %v", cfg.Labels)
+		}
+		if cfg.Exact != "" || cfg.ExactOther != "exact" {
+			t.Errorf("want key named like a field bound to it, got %q and %q", cfg.Exact, cfg.ExactOther)
+		}
+		if sources["MaxPercentUtil"] != "reader" {
+			t.Errorf("want source of MaxPercentUtil reader, got %q", sources["MaxPercentUtil"])
+		}
+		if sources["Containers[0].ImageName"] != "reader" {
+			t.Errorf("want source of Containers[0].ImageName reader, got %q", sources["Containers[0].ImageName"])
+		}
+	})
+
+	t.Run("file sources", func(t *testing.T) {
+		type Config struct {
+			MaxConns int
+			Timeout  string
+		}
+
+		dir := t.TempDir()
+		base, override := filepath.Join(dir, "base.yaml"), filepath.Join(dir, "override.yaml")
+		writeFile(t, base, "max_conns: 5\ntimeout: 1s")
+		writeFile(t, override, "MaxConns: 7")
+
+		os.Clearenv()
+		setenv(t, "APP_MAXCONNS", "9")
+		setenv(t, "APP_TIMEOUT", "2s")
+
+		sources := map[string]string{}
+		var cfg Config
+		err := Load(&cfg, FilePaths(base, override), MatchCamelSnake(), UseEnv("app"),
+			Precedence(SourceEnv, SourceFile), TrackSources(&sources))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if want := (Config{MaxConns: 7, Timeout: "1s"}); cfg != want {
+			t.Fatalf("\nwant %+v\ngot %+v", want, cfg)
+		}
+		if sources["MaxConns"] != "file:"+override {
+			t.Errorf("want source of MaxConns file:%s, got %q", override, sources["MaxConns"])
+		}
+	})
+
+	t.Run("without option", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String(content, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.MaxPercentUtil != 0 || cfg.ListenAddr != "" {
+			t.Errorf("want keys unmatched, got %+v", cfg)
+		}
+	})
+}
+
+func Test_confucius_Load_KeyMatcher(t *testing.T) {
+	type Config struct {
+		Host string `conf:"host"`
+		Port int
+	}
+
+	match := func(key, fieldName string) bool {
+		return strings.EqualFold(strings.TrimPrefix(key, "x-"), fieldName)
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`{"x-host": "example.com", "x-port": 443, "x-other": true}`, DecoderJSON), KeyMatcher(match))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Host != "example.com" {
+		t.Errorf("want host example.com, got %q", cfg.Host)
+	}
+	if cfg.Port != 443 {
+		t.Errorf("want port 443, got %d", cfg.Port)
+	}
+}
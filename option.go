@@ -1,12 +1,18 @@
 package confucius
 
 import (
+	"bufio"
 	"embed"
+	"encoding/base64"
 	"io"
+	"io/fs"
+	"math"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 // Option configures how confucius loads the configuration.
@@ -15,28 +21,69 @@ type Option func(c *confucius)
 // File returns an option that configures the filename that fig
 // looks for to provide the config values.
 //
-// The name must include the extension of the file. Supported
-// file types are `yaml`, `yml`, `json` and `toml`.
+// The extension of the name sets the type of the file. Supported
+// file types are `yaml`, `yml`, `json`, `jsonc`, `toml`, `hcl`, `ini`
+// and `properties`.
 //
 //   confucius.Load(&cfg, confucius.File("config.toml"))
 //
+// A name without an extension, e.g. `config`, is tried with the `yaml`,
+// `yml`, `json` and `toml` extensions in that order, and the first one
+// found in the dirs is used. The profile files are named after it.
+//
 // If this option is not used then confucius looks for a file with name `config.yaml`.
 func File(name string) Option {
 	return func(c *confucius) {
 		c.filename = name
+		c.filenameSet = true
+	}
+}
+
+// Files returns an option that configures confucius to load every file
+// matching the patterns, instead of the single file set with File. The
+// patterns use the syntax of filepath.Match and are relative to the dirs.
+//
+//   confucius.Load(&cfg, confucius.Files("config.d/*.yaml"))
+//
+// The files matching a pattern are merged in lexical order, after the ones
+// matching the patterns before it, so the values of later files override the
+// values of earlier ones. The profile files, still named after File, are
+// merged last. Each pattern must match at least one file in the dirs.
+func Files(patterns ...string) Option {
+	return func(c *confucius) {
+		c.filePatterns = patterns
+	}
+}
+
+// FilePaths returns an option that configures confucius to load exactly the
+// files at paths, instead of searching the dirs for the file set with File.
+// The files are merged in the order given, so the values of later files
+// override the values of earlier ones, and each one is decoded according
+// to its extension.
+//
+//   confucius.Load(&cfg, confucius.FilePaths("/etc/app/base.yaml", "/etc/app/override.yaml"))
+//
+// The dirs, the profile files and the embedded files are not used. Each
+// path must exist unless the OptionalFile option is used.
+func FilePaths(paths ...string) Option {
+	return func(c *confucius) {
+		c.filePaths = paths
 	}
 }
 
 // Reader returns an option that configure from reader for reference configuration.
+//
+// The option can be given several times to layer readers, each one is merged
+// over the ones given before it, and all of them before the config files.
 func Reader(reader io.Reader, decoder Decoder) Option {
 	return func(c *confucius) {
 		c.useReader = true
-		c.readerConfig = reader
-		c.readerDecoder = decoder
+		c.readers = append(c.readers, &readerSource{reader: reader, decoder: decoder})
 	}
 }
 
 // String returns an option that configure from string for reference configuration.
+// Like Reader, it can be given several times.
 func String(file string, decoder Decoder) Option {
 	return Reader(strings.NewReader(strings.TrimSpace(file)), decoder)
 }
@@ -58,6 +105,48 @@ func Dirs(dirs ...string) Option {
 	}
 }
 
+// Recursive returns an option that configures confucius to also search the
+// subdirectories of the dirs, up to maxDepth levels deep, for the config files.
+//
+//   confucius.Load(&cfg, confucius.Dirs("deploy"), confucius.Recursive(2))
+//
+// Subdirectories are searched breadth first so the file closest to the dir
+// wins. Symbolic links to directories are not followed.
+//
+// If this option is not used then only the dirs themselves are searched.
+func Recursive(maxDepth int) Option {
+	return func(c *confucius) {
+		c.searchDepth = maxDepth
+	}
+}
+
+// SearchRecursive returns an option that configures confucius to also search
+// every subdirectory of the dirs for the config files, however deep.
+//
+//   confucius.Load(&cfg, confucius.Dirs("deploy"), confucius.SearchRecursive())
+//
+// Like with Recursive, subdirectories are searched breadth first and the
+// first file found with each name wins.
+func SearchRecursive() Option {
+	return func(c *confucius) {
+		c.searchDepth = math.MaxInt32
+	}
+}
+
+// FirstDirWins returns an option that makes the first dir containing the
+// config file authoritative. The profile files are only looked up in that
+// same dir, so files from different dirs are never mixed.
+//
+//   confucius.Load(&cfg, confucius.Dirs(".", "/etc/myapp"), confucius.Profiles("test"), confucius.FirstDirWins())
+//
+// If this option is not used then a profile file is loaded from the first
+// dir that contains it, which may differ from the dir of the config file.
+func FirstDirWins() Option {
+	return func(c *confucius) {
+		c.firstDirWins = true
+	}
+}
+
 // Tag returns an option that configures the tag key that confucius uses
 // when for the alt name struct tag key in fields.
 //
@@ -77,8 +166,84 @@ func Tag(tag string) Option {
 //
 // If this option is not used then confucius parses times using `time.RFC3339` layout.
 func TimeLayout(layout string) Option {
+	return TimeLayouts(layout)
+}
+
+// TimeLayouts returns an option that configures multiple time layouts that
+// confucius tries in order when parsing a time in a config file, the
+// environment or in the default tag for time.Time fields. The first layout
+// that matches is used.
+//
+//   confucius.Load(&cfg, confucius.TimeLayouts(time.RFC3339, "2006-01-02"))
+//
+// If no layout matches the value then an error is returned.
+func TimeLayouts(layouts ...string) Option {
 	return func(c *confucius) {
-		c.timeLayout = layout
+		c.timeLayouts = layouts
+	}
+}
+
+// SliceDelimiter returns an option that configures the delimiter used to
+// split slices given as strings in the environment or in the default tag.
+//
+//   type Config struct {
+//     DNs []string `conf:"dns" default:"[cn=a,ou=b;cn=c,ou=d]"`
+//   }
+//
+//   confucius.Load(&cfg, confucius.SliceDelimiter(";"))
+//
+// Elements enclosed in quotes may contain the delimiter regardless of this
+// option, e.g. `["a,b","c"]`.
+// If this option is not used then confucius uses the value of `DefaultSliceDelimiter`.
+func SliceDelimiter(sep string) Option {
+	return func(c *confucius) {
+		c.sliceDelimiter = sep
+	}
+}
+
+// DecodeHook returns an option that adds hooks to the chain of mapstructure
+// decode hooks used when decoding the config files into the struct. This
+// lets types without built-in support be decoded, e.g. a string into a
+// net.IP or a custom enum.
+//
+//   confucius.Load(&cfg, confucius.DecodeHook(stringToIPHookFunc()))
+//
+// The hooks run in the given order after the built-in hooks, which expand
+// environment variables and parse durations and times. The option may be
+// used more than once, the hooks are appended to the chain each time.
+func DecodeHook(hooks ...mapstructure.DecodeHookFunc) Option {
+	return func(c *confucius) {
+		c.decodeHooks = append(c.decodeHooks, hooks...)
+	}
+}
+
+// UnixTime returns an option that configures confucius to accept Unix
+// timestamps for time.Time fields, whether they are numbers in a config file
+// or numeric strings in the environment or in the default tag.
+//
+//   confucius.Load(&cfg, confucius.UnixTime())
+//
+// The unit of the timestamp is detected from its magnitude: seconds,
+// milliseconds, microseconds or nanoseconds. Values that are not numeric
+// are still parsed using the time layouts.
+func UnixTime() Option {
+	return func(c *confucius) {
+		c.unixTime = true
+	}
+}
+
+// DisableEnvExpansion returns an option that disables the expansion of
+// environment variable references such as `${HOST:localhost}` in the string
+// values of the config files and of the environment. Use it when values
+// legitimately contain `${`, e.g. shell snippets or regular expressions.
+//
+//   confucius.Load(&cfg, confucius.DisableEnvExpansion())
+//
+// If this option is not used then references are replaced by the value of
+// the environment variable, or by the default following the colon.
+func DisableEnvExpansion() Option {
+	return func(c *confucius) {
+		c.disableEnvExpansion = true
 	}
 }
 
@@ -87,8 +252,8 @@ func TimeLayout(layout string) Option {
 //
 //   confucius.Load(&cfg, confucius.UseEnv("my_app"))
 //
-// This is meant to be used in conjunction with loading from a file. There
-// is no support to ONLY load from the environment.
+// This is meant to be used in conjunction with loading from a file. Use
+// UseEnvOnly to ONLY load from the environment.
 //
 // Fig looks for environment variables in the format PREFIX_FIELD_PATH or
 // FIELD_PATH if prefix is empty. Prefix is capitalised regardless of what
@@ -123,28 +288,90 @@ func UseEnv(prefix string) Option {
 //  confucius.Load(&cfg, confucius.UseProfile("test"))
 //
 // If this option is not used then confucius uses the tag `fig`.
+// A profile given more than once is only used at its first position.
 func Profiles(profiles ...string) Option {
 	return func(c *confucius) {
-		c.profiles = profiles
+		c.profiles = uniqueStrings(profiles)
 	}
 }
 
 // ProfileLayout returns an option that configures the profile layout that confucius uses
 //
-//  confucius.Load(&cfg, confucius.UseProfileLayout("config-test.yaml"))
+//  confucius.Load(&cfg, confucius.ProfileLayout("{name}-{profile}.{ext}"))
 //
-// If this option is not used then confucius uses the tag `fig`.
+// {name} is replaced with the name of the config file without its extension,
+// {profile} with the profile and {ext} with the extension of the config file.
+// Layouts without placeholders, e.g. "config-test.yaml", use the words config,
+// test and yaml in their place.
+//
+// If this option is not used then confucius uses DefaultProfileLayout.
 func ProfileLayout(layout string) Option {
 	return func(c *confucius) {
 		c.profileLayout = layout
 	}
 }
 
+// Keychain returns an option that configures confucius to load secrets from
+// the OS keychain for the given service. Fields reference a secret by name
+// using the `keychain` struct tag:
+//
+//   type Config struct {
+//     Password string `conf:"password" keychain:"db_password"`
+//   }
+//
+// Secrets are applied after the config files are loaded and before the
+// environment, so they can still be overridden using UseEnv. A secret
+// that does not exist in the keychain leaves the field untouched.
+//
+// By default the `security` tool is used on macOS and `secret-tool` on linux.
+// Use UseKeyring to provide a different implementation.
+func Keychain(service string) Option {
+	return func(c *confucius) {
+		c.keychainService = service
+	}
+}
+
+// UseKeyring returns an option that configures the Keyring used by the
+// Keychain option to look up secrets.
+func UseKeyring(keyring Keyring) Option {
+	return func(c *confucius) {
+		c.keyring = keyring
+	}
+}
+
+// RequireExportedFields returns an option that makes Load return an error
+// for every unexported field carrying a tag read by confucius: the tag key
+// set with the Tag option, `default`, `fallback`, `validate`, `warn`, `env`,
+// `envprefix`, `keychain`, `secret`, `unit`, `empty` or `format`. Such fields
+// can never be set by confucius, so the tag is almost always a mistake.
+//
+//   type Config struct {
+//     host string `conf:"host"` // host: unexported field cannot be set
+//   }
+//
+// If this option is not used then unexported fields are silently ignored.
+func RequireExportedFields() Option {
+	return func(c *confucius) {
+		c.requireExportedFields = true
+	}
+}
+
 // EmbedFS returns an option that configures the embed fs.
 func EmbedFS(fs embed.FS) Option {
+	return FileSystem(fs)
+}
+
+// FileSystem returns an option that searches any fs.FS for the config
+// files, like the embed fs given with EmbedFS, e.g. an os.DirFS or an
+// in-memory fstest.MapFS in tests.
+//
+//   confucius.Load(&cfg, confucius.FileSystem(os.DirFS("/etc/myapp")))
+//
+// The whole file system is searched, its files are used before the ones
+// found in the dirs given with the Dirs option.
+func FileSystem(fsys fs.FS) Option {
 	return func(c *confucius) {
-		c.useEmbedFS = true
-		c.embedFS = fs
+		c.fsys = fsys
 	}
 }
 
@@ -160,3 +387,518 @@ func Logger(opts ...LogOption) Option {
 		}
 	}
 }
+
+// TrackSources returns an option that fills sources with the origin of the
+// value of every field that was set, keyed by the field's path. This helps
+// to debug which layer of a configuration a value came from.
+//
+//   var sources map[string]string
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.TrackSources(&sources))
+//
+//   // sources["server.host"] == "env:MYAPP_SERVER_HOST"
+//
+// The origins are "file:<path>" or "reader" for values decoded from a config
+// file, "profile:<name>" for values from a profile's file, "env:<variable>",
+// "keychain:<secret>", "default" and "fallback".
+func TrackSources(sources *map[string]string) Option {
+	return func(c *confucius) {
+		c.trackSources = sources
+	}
+}
+
+// UnmaskSecrets returns an option that disables the masking of the values of
+// fields tagged with `secret:"true"`. Their values are masked as "****" in
+// the output of Dump and in the errors and logs of Load.
+//
+//   type Config struct {
+//     Password string `conf:"password" secret:"true"`
+//   }
+//
+//   out, err := confucius.Dump(&cfg, confucius.UnmaskSecrets())
+//
+// This is meant to be used for debugging only.
+func UnmaskSecrets() Option {
+	return func(c *confucius) {
+		c.unmaskSecrets = true
+	}
+}
+
+// IgnoreBadDefaults returns an option that makes Load tolerate default values
+// that fail to parse. Instead of returning an error, a warning is logged and
+// the field is left as its zero value.
+//
+//   type Config struct {
+//     Ports []int `conf:"ports" default:"[80,not-a-port]"` // ports is left empty
+//   }
+//
+//   confucius.Load(&cfg, confucius.IgnoreBadDefaults())
+//
+// If this option is not used then a bad default is returned as an error.
+func IgnoreBadDefaults() Option {
+	return func(c *confucius) {
+		c.ignoreBadDefaults = true
+	}
+}
+
+// MaxFileSize returns an option that limits the size of the config files and
+// readers that confucius loads, guarding against huge or malicious files in
+// semi-trusted locations.
+//
+//   confucius.Load(&cfg, confucius.MaxFileSize(1<<20)) // 1 MiB
+//
+// Loading a file larger than bytes returns an error wrapping ErrFileTooLarge.
+// If this option is not used then the size of the files is not limited.
+func MaxFileSize(bytes int64) Option {
+	return func(c *confucius) {
+		c.maxFileSize = bytes
+	}
+}
+
+// KeyOrder returns an option that records the paths of the keys of the
+// loaded config files, lowercased and in the order they appear in the
+// files, into order. Passing the same option to Dump writes the keys in
+// that order instead of the order of the struct fields, which keeps the
+// diffs of dumped configs small.
+//
+//   var order []string
+//   confucius.Load(&cfg, confucius.KeyOrder(&order))
+//   out, err := confucius.Dump(&cfg, confucius.KeyOrder(&order))
+//
+// The order is recorded for yaml, json and toml files. Keys of the other
+// formats, and keys missing from the files, follow the recorded ones.
+func KeyOrder(order *[]string) Option {
+	return func(c *confucius) {
+		c.keyOrder = order
+	}
+}
+
+// Precedence returns an option that sets the order in which the sources
+// of values are merged, from the lowest precedence to the highest. The
+// values of a source override the ones of the sources before it.
+//
+//   confucius.Load(&cfg, confucius.Precedence(confucius.SourceFile, confucius.SourceEnv, confucius.SourceReader))
+//
+// Sources that are not given have the lowest precedence, in their default
+// order. If this option is not used then the reader has the lowest
+// precedence, followed by the files and then by the environment.
+func Precedence(sources ...Source) Option {
+	return func(c *confucius) {
+		var precedence []Source
+		for _, src := range defaultPrecedence {
+			if !containsSource(sources, src) {
+				precedence = append(precedence, src)
+			}
+		}
+		for _, src := range sources {
+			if !containsSource(precedence, src) {
+				precedence = append(precedence, src)
+			}
+		}
+		c.precedence = precedence
+	}
+}
+
+// Diagnostics returns an option that logs, at debug level, every field
+// whose value is changed by the environment, a fallback or a default
+// along with its value before and after the change. Secrets are masked.
+//
+//   confucius.Load(&cfg, confucius.Diagnostics(), confucius.Logger(confucius.SetOutput(os.Stderr)))
+//
+// Each change is logged as a "field changed" entry with the fields
+// field, source, old and new.
+func Diagnostics() Option {
+	return func(c *confucius) {
+		c.diagnostics = true
+	}
+}
+
+// TranscodeUTF16 returns an option that transcodes UTF-16 encoded config
+// files and readers to UTF-8 before they are decoded, as saved by some
+// Windows editors.
+//
+//   confucius.Load(&cfg, confucius.TranscodeUTF16())
+//
+// If this option is not used then UTF-16 content is returned as an error.
+// A leading UTF-8 byte order mark is always stripped.
+func TranscodeUTF16() Option {
+	return func(c *confucius) {
+		c.transcodeUTF16 = true
+	}
+}
+
+// ByteEncoding returns an option that configures the base64 encoding of the
+// values of []byte fields set from the config files, the environment or a
+// default, and written by Dump.
+//
+//   confucius.Load(&cfg, confucius.ByteEncoding(base64.RawURLEncoding))
+//
+// If this option is not used then base64.StdEncoding is used.
+func ByteEncoding(enc *base64.Encoding) Option {
+	return func(c *confucius) {
+		c.byteEncoding = enc
+	}
+}
+
+// AutoConfD returns an option that also merges the files of the .d directory
+// next to the main config file over it, the files matching config.d/*.yaml
+// for config.yaml, like the drop-in overrides of many daemons.
+//
+//   confucius.Load(&cfg, confucius.AutoConfD())
+//
+// The files are merged in lexical order, e.g. config.d/10-base.yaml before
+// config.d/20-override.yaml, and before the profile files. Only the local
+// dirs are searched for them, not the embed FS or FileSystem.
+func AutoConfD() Option {
+	return func(c *confucius) {
+		c.autoConfD = true
+	}
+}
+
+// PromptMissing returns an option that prompts for the values of required
+// fields that are still missing after the files, readers, environment and
+// fallbacks were applied, for the setup flows of interactive tools. Each
+// field is prompted for by its path on out and its value read as a line
+// from in.
+//
+//   confucius.Load(&cfg, confucius.PromptMissing(os.Stdin, os.Stderr))
+//
+// A value that cannot be set is reported and asked for again. An empty
+// line leaves the field missing, failing its required validation. The
+// input of secret fields is not hidden.
+func PromptMissing(in io.Reader, out io.Writer) Option {
+	return func(c *confucius) {
+		c.prompter = &prompter{out: out}
+		if in != nil {
+			c.prompter.in = bufio.NewReader(in)
+		}
+	}
+}
+
+// FlatKeySeparator returns an option that configures the separator used to
+// split the keys and section names of flat formats, ini and properties,
+// into the nested keys of the config struct.
+//
+//   // server__tls__cert = /etc/cert sets Server.TLS.Cert
+//   confucius.Load(&cfg, confucius.File("app.properties"), confucius.FlatKeySeparator("__"))
+//
+// An empty separator disables the nesting, the keys are then used as is.
+// If this option is not used then confucius uses the value of `DefaultFlatKeySeparator`.
+func FlatKeySeparator(sep string) Option {
+	return func(c *confucius) {
+		c.flatKeySep = sep
+	}
+}
+
+// OptionalFile returns an option that makes the config files optional.
+// Files that are not found are skipped instead of failing with
+// ErrFileNotFound, so that a config made only of defaults loads when
+// there is no file at all.
+//
+//   confucius.Load(&cfg, confucius.File("config.yaml"), confucius.OptionalFile())
+//
+// The files that are found, e.g. the main file when a profile file is
+// missing, are still loaded and the fields are still validated.
+func OptionalFile() Option {
+	return func(c *confucius) {
+		c.optionalFile = true
+	}
+}
+
+// CaptureRaw returns an option that records the raw bytes read from each
+// config file and reader in raw, so that exactly what was loaded can be
+// persisted or hashed.
+//
+//   raw := map[string][]byte{}
+//   confucius.Load(&cfg, confucius.CaptureRaw(raw))
+//   sum := sha256.Sum256(raw["config.yaml"])
+//
+// Files are keyed by their path, the files of the embed FS or FileSystem by
+// their path prefixed with "embed:" and readers by their position, e.g. "reader[0]".
+// The bytes are recorded as read, before a byte order mark is stripped.
+func CaptureRaw(raw map[string][]byte) Option {
+	return func(c *confucius) {
+		c.captureRaw = raw
+	}
+}
+
+// PrefixEnvTags returns an option that prepends the prefix given to UseEnv
+// to the environment variables pinned with the env tag.
+//
+//   type Config struct {
+//     Host string `conf:"host" env:"SERVICE_HOST"`
+//   }
+//
+//   // Host is set from MYAPP_SERVICE_HOST
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.PrefixEnvTags())
+//
+// If this option is not used then the pinned names are used as is.
+func PrefixEnvTags() Option {
+	return func(c *confucius) {
+		c.prefixEnvTags = true
+	}
+}
+
+// SecretFileExpansion returns an option that replaces the values of the form
+// file:/run/secrets/name, in the config files, readers and environment, with
+// the content of the file they refer to, like the secrets mounted by Docker
+// and Kubernetes.
+//
+//   // export MYAPP_DB_PASSWORD=file:/run/secrets/db_password
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.SecretFileExpansion())
+//
+// The trailing newlines of the files are removed. The values are expanded
+// after the environment variable references, so file:${SECRETS_DIR}/name
+// works too. A file that cannot be read is an error.
+func SecretFileExpansion() Option {
+	return func(c *confucius) {
+		c.secretFileExpansion = true
+	}
+}
+
+// Section returns an option that loads only the section at key, a dot
+// separated path, of the config files and readers into the config struct,
+// e.g. the config of one app from a file shared by many.
+//
+//   // config.yaml holds the sections of many apps, e.g. apps.billing.server
+//   confucius.Load(&server, confucius.Section("apps.billing.server"))
+//
+// The environment, defaults and validations apply to the struct as usual.
+// Load returns an error if the section is not found.
+func Section(key string) Option {
+	return func(c *confucius) {
+		c.section = key
+	}
+}
+
+// DisableEnvSliceBinding returns an option that stops the environment from
+// setting the fields contained in the elements of slices, e.g. SERVER_0_HOST,
+// so that no environment variable can alter those elements unexpectedly.
+//
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.DisableEnvSliceBinding())
+//
+// The other fields, including whole slices set from a list, can still be
+// set from the environment.
+//
+// If this option is not used then confucius sets the fields of slice elements
+// from the environment in the form PARENT_IDX_FIELD.
+func DisableEnvSliceBinding() Option {
+	return func(c *confucius) {
+		c.disableEnvSliceBind = true
+	}
+}
+
+// BinaryBase64 returns an option that base64 decodes the values given to the
+// types implementing encoding.BinaryUnmarshaler, e.g. keys, before they are
+// passed to their UnmarshalBinary method. The encoding set with the
+// ByteEncoding option is used.
+//
+//   confucius.Load(&cfg, confucius.BinaryBase64())
+//
+// If this option is not used then the values are passed as is.
+func BinaryBase64() Option {
+	return func(c *confucius) {
+		c.binaryBase64 = true
+	}
+}
+
+// MergeSkipEmpty returns an option that keeps the empty values of a layer,
+// nulls, empty strings and empty lists in a profile file or reader, from
+// overriding the values of the layers merged before it.
+//
+//   // config.yaml sets host: localhost, config.test.yaml sets host: ""
+//   confucius.Load(&cfg, confucius.Profiles("test"), confucius.MergeSkipEmpty())
+//   // cfg.Host is localhost
+//
+// If this option is not used then the empty values of a layer override the
+// ones merged before it like any other value.
+func MergeSkipEmpty() Option {
+	return func(c *confucius) {
+		c.mergeSkipEmpty = true
+	}
+}
+
+// MergeAppendSlice returns an option that appends the lists of a layer, e.g.
+// a profile file or reader, to the lists of the layers merged before it.
+//
+//   // config.yaml sets hosts: [a], config.test.yaml sets hosts: [b]
+//   confucius.Load(&cfg, confucius.Profiles("test"), confucius.MergeAppendSlice())
+//   // cfg.Hosts is [a b]
+//
+// If this option is not used then the lists of a layer replace the ones
+// merged before it.
+func MergeAppendSlice() Option {
+	return func(c *confucius) {
+		c.mergeAppendSlice = true
+	}
+}
+
+// AfterLoad returns an option that registers a hook called with the config
+// struct once it is loaded, its defaults set and its fields validated, e.g.
+// to derive a field from other ones.
+//
+//   confucius.Load(&cfg, confucius.AfterLoad(func(cfg interface{}) error {
+//     c := cfg.(*Config)
+//     c.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+//     return nil
+//   }))
+//
+// The option can be given several times, the hooks run in order. An error
+// returned by a hook stops the hooks after it and is returned by Load,
+// wrapped. The hooks are not called when loading fails.
+func AfterLoad(hook func(cfg interface{}) error) Option {
+	return func(c *confucius) {
+		c.afterLoadHooks = append(c.afterLoadHooks, hook)
+	}
+}
+
+// TransformMap returns an option that registers a transform of the values of
+// the config files and readers, once merged and before they are loaded into
+// the config struct, e.g. to rename the keys of an old config layout.
+//
+//   confucius.Load(&cfg, confucius.TransformMap(func(m map[string]interface{}) (map[string]interface{}, error) {
+//     if v, ok := m["log-level"]; ok {
+//       m["log_level"] = v
+//       delete(m, "log-level")
+//     }
+//     return m, nil
+//   }))
+//
+// The transform may modify the map it is given or return another one. The
+// option can be given several times, the transforms run in order. An error
+// returned by a transform is returned by Load, wrapped.
+func TransformMap(transform func(map[string]interface{}) (map[string]interface{}, error)) Option {
+	return func(c *confucius) {
+		c.transforms = append(c.transforms, transform)
+	}
+}
+
+// ExtendedDurations returns an option that lets time.Duration fields be
+// written with days and weeks as units, e.g. "3d" or "1w12h", and as ISO 8601
+// durations, e.g. "P1DT2H", besides the formats of time.ParseDuration.
+//
+//   confucius.Load(&cfg, confucius.ExtendedDurations())
+//
+// Days are 24 hours long and weeks 7 days. ISO 8601 years and months are
+// rejected since their length varies.
+//
+// If this option is not used then durations are parsed with time.ParseDuration.
+func ExtendedDurations() Option {
+	return func(c *confucius) {
+		c.extendedDurations = true
+	}
+}
+
+// KeyMatcher returns an option that binds the keys of the config files and
+// readers to the fields of the config struct that match reports they match,
+// e.g. to load files written in another naming convention without tagging
+// every field. fieldName is the name of the field in its tag or, without
+// one, its Go name.
+//
+//   confucius.Load(&cfg, confucius.KeyMatcher(func(key, fieldName string) bool {
+//     return strings.EqualFold(strings.TrimPrefix(key, "x-"), fieldName)
+//   }))
+//
+// A key named exactly like a field is always bound to it.
+//
+// If this option is not used then keys are matched to fields ignoring their case.
+func KeyMatcher(match func(key, fieldName string) bool) Option {
+	return func(c *confucius) {
+		c.keyMatcher = match
+	}
+}
+
+// MatchCamelSnake returns an option that binds the keys of the config files
+// and readers to the fields of the config struct ignoring their case, their
+// underscores and their dashes, so that max_percent_util, max-percent-util
+// and maxPercentUtil are all loaded into a MaxPercentUtil field.
+//
+//   confucius.Load(&cfg, confucius.MatchCamelSnake())
+//
+// If this option is not used then keys are matched to fields ignoring their case.
+func MatchCamelSnake() Option {
+	return KeyMatcher(matchCamelSnake)
+}
+
+// WarnUnboundEnv returns an option that logs a warning, once the config is
+// loaded, for each environment variable with the prefix given to UseEnv
+// that matches no field, e.g. a misspelled MYAPP_HSOT.
+//
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.WarnUnboundEnv())
+//
+// This option requires UseEnv with a prefix.
+//
+// If this option is not used then such variables are silently ignored.
+func WarnUnboundEnv() Option {
+	return func(c *confucius) {
+		c.warnUnboundEnv = true
+	}
+}
+
+// StrictEnv returns an option that makes Load fail with an error wrapping
+// ErrUnboundEnv when environment variables with the prefix given to UseEnv
+// match no field, listing their names.
+//
+//   confucius.Load(&cfg, confucius.UseEnv("myapp"), confucius.StrictEnv())
+//
+// This option requires UseEnv with a prefix.
+//
+// If this option is not used then such variables are silently ignored.
+func StrictEnv() Option {
+	return func(c *confucius) {
+		c.strictEnv = true
+	}
+}
+
+// FailFast returns an option that makes Load return as soon as a field
+// fails to be set or validated, with the error of that field only, for
+// shorter feedback loops, e.g. in CLIs.
+//
+//   confucius.Load(&cfg, confucius.FailFast())
+//
+// If this option is not used then the errors of all the fields are returned.
+func FailFast() Option {
+	return func(c *confucius) {
+		c.failFast = true
+	}
+}
+
+// InterpolateConfig returns an option that replaces the references to other
+// keys of the config files and readers, written ${config:<path>}, with the
+// values of those keys once the sources are merged, so that values are not
+// repeated.
+//
+//   # config.yaml
+//   server:
+//     host: localhost
+//     port: 8080
+//   url: http://${config:server.host}:${config:server.port} # http://localhost:8080
+//
+// The paths are dot separated and may index lists, e.g. servers[0].host. A
+// value made of a single reference takes the value of the referenced key as
+// is, e.g. a number or a list. References that lead back to themselves fail
+// Load with an error wrapping ErrReferenceCycle.
+//
+// If this option is not used then the references are left as they are.
+func InterpolateConfig() Option {
+	return func(c *confucius) {
+		c.interpolateConfigRefs = true
+	}
+}
+
+// UseEnvOnly returns an option that configures confucius to load values from
+// the environment only, like UseEnv with the given prefix, without searching
+// for config files.
+//
+//   confucius.Load(&cfg, confucius.UseEnvOnly("my_app"))
+//
+// Load returns an error wrapping ErrConflictingOptions if it is used with
+// File, Files, FilePaths or Reader, whose sources would not be loaded.
+//
+// If this option is not used then the config files are loaded before the
+// environment.
+func UseEnvOnly(prefix string) Option {
+	return func(c *confucius) {
+		UseEnv(prefix)(c)
+		c.envOnly = true
+	}
+}
@@ -0,0 +1,156 @@
+package confucius
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+// recordKeyOrder appends the lowercased paths of the keys in data to the
+// key order, in the order they appear in the document. Keys that were
+// already recorded, e.g. by a previous file, keep their position. The
+// keys of the elements of a list are recorded under the path of the list.
+func (c *confucius) recordKeyOrder(data []byte, decoder Decoder) error {
+	if c.keyOrder == nil {
+		return nil
+	}
+
+	var keys []string
+	switch decoder {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.MapSlice
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			yamlKeys(doc, "", &keys)
+		}
+	case ".json", ".jsonc":
+		if decoder == ".jsonc" {
+			data = stripJSONC(data)
+		}
+		if err := jsonKeys(json.NewDecoder(bytes.NewReader(data)), "", &keys); err != nil {
+			return err
+		}
+	case ".toml":
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return err
+		}
+		tomlKeys(tree, "", &keys)
+	default:
+		// the order of the keys is not known for the other formats.
+		return nil
+	}
+
+	if c.keyIndex == nil {
+		c.keyIndex = make(map[string]int)
+	}
+	for _, key := range keys {
+		if _, ok := c.keyIndex[key]; !ok {
+			c.keyIndex[key] = len(c.keys)
+			c.keys = append(c.keys, key)
+		}
+	}
+	return nil
+}
+
+// keyPath joins the lowercased key to path.
+func keyPath(path string, key interface{}) string {
+	return strings.TrimPrefix(path+"."+strings.ToLower(fmt.Sprint(key)), ".")
+}
+
+func yamlKeys(val interface{}, path string, keys *[]string) {
+	switch v := val.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			p := keyPath(path, item.Key)
+			*keys = append(*keys, p)
+			yamlKeys(item.Value, p, keys)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			yamlKeys(elem, path, keys)
+		}
+	}
+}
+
+func jsonKeys(dec *json.Decoder, path string, keys *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			p := keyPath(path, key)
+			*keys = append(*keys, p)
+			if err := jsonKeys(dec, p, keys); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err := jsonKeys(dec, path, keys); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// consume the closing delimiter.
+	_, err = dec.Token()
+	return err
+}
+
+func tomlKeys(tree *toml.Tree, path string, keys *[]string) {
+	names := tree.Keys()
+	sort.SliceStable(names, func(i, j int) bool {
+		pi := tree.GetPositionPath([]string{names[i]})
+		pj := tree.GetPositionPath([]string{names[j]})
+		return pi.Line < pj.Line || (pi.Line == pj.Line && pi.Col < pj.Col)
+	})
+
+	for _, name := range names {
+		p := keyPath(path, name)
+		*keys = append(*keys, p)
+		switch v := tree.GetPath([]string{name}).(type) {
+		case *toml.Tree:
+			tomlKeys(v, p, keys)
+		case []*toml.Tree:
+			for _, elem := range v {
+				tomlKeys(elem, p, keys)
+			}
+		}
+	}
+}
+
+// sortKeys sorts the items of a dumped struct or map at path by the
+// recorded key order. Keys that were not recorded follow the ones that
+// were, keeping their relative order.
+func (c *confucius) sortKeys(items yaml.MapSlice, path string) {
+	index := func(item yaml.MapItem) int {
+		if i, ok := c.keyIndex[keyPath(path, item.Key)]; ok {
+			return i
+		}
+		return len(c.keyIndex)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return index(items[i]) < index(items[j])
+	})
+}
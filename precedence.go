@@ -0,0 +1,73 @@
+package confucius
+
+import "strings"
+
+// Source is a source of configuration values whose precedence can be
+// changed with the Precedence option.
+type Source int
+
+const (
+	// SourceReader is the reader or string given with the Reader and String options.
+	SourceReader Source = iota
+	// SourceFile is the config files, including the profile files.
+	SourceFile
+	// SourceEnv is the environment, used with the UseEnv option.
+	SourceEnv
+)
+
+// defaultPrecedence is the order in which sources are merged when the
+// Precedence option is not used, from the lowest precedence to the highest.
+var defaultPrecedence = []Source{SourceReader, SourceFile, SourceEnv}
+
+// String returns the name of the source.
+func (s Source) String() string {
+	switch s {
+	case SourceReader:
+		return "reader"
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	}
+	return "unknown"
+}
+
+// rank returns the precedence of src, sources with a higher rank
+// override the values of the sources with a lower one.
+func (c *confucius) rank(src Source) int {
+	precedence := c.precedence
+	if precedence == nil {
+		precedence = defaultPrecedence
+	}
+	for i, s := range precedence {
+		if s == src {
+			return i
+		}
+	}
+	return -1
+}
+
+// envOverrides reports whether a value found in the environment may
+// override the decoded value at path, given the precedence of the
+// source the value was decoded from.
+func (c *confucius) envOverrides(path string) bool {
+	origin, ok := c.fileSources[strings.ToLower(path)]
+	if !ok {
+		return true
+	}
+	src := SourceFile
+	if origin == "reader" {
+		src = SourceReader
+	}
+	return c.rank(SourceEnv) > c.rank(src)
+}
+
+// containsSource reports whether src is one of the sources in list.
+func containsSource(list []Source, src Source) bool {
+	for _, s := range list {
+		if s == src {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package confucius
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_confucius_Load_Precedence(t *testing.T) {
+	type Config struct {
+		Host   string            `conf:"host"`
+		Port   int               `conf:"port"`
+		Level  string            `conf:"level"`
+		Labels map[string]string `conf:"labels"`
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), "host: file\nport: 80\nlabels: {tier: file}")
+	reference := "host: reader\nport: 8080\nlevel: reader"
+
+	os.Clearenv()
+	setenv(t, "MYAPP_HOST", "env")
+	setenv(t, "MYAPP_LEVEL", "env")
+	setenv(t, "MYAPP_LABELS_tier", "env")
+	setenv(t, "MYAPP_LABELS_zone", "env")
+
+	for _, tc := range []struct {
+		Name    string
+		Options []Option
+		Want    Config
+	}{
+		{
+			Name: "default",
+			Want: Config{Host: "env", Port: 80, Level: "env", Labels: map[string]string{"tier": "env", "zone": "env"}},
+		},
+		{
+			Name:    "reader overrides file",
+			Options: []Option{Precedence(SourceFile, SourceReader, SourceEnv)},
+			Want:    Config{Host: "env", Port: 8080, Level: "env", Labels: map[string]string{"tier": "env", "zone": "env"}},
+		},
+		{
+			Name:    "reader overrides file and env",
+			Options: []Option{Precedence(SourceFile, SourceEnv, SourceReader)},
+			Want:    Config{Host: "reader", Port: 8080, Level: "reader", Labels: map[string]string{"tier": "env", "zone": "env"}},
+		},
+		{
+			Name:    "env does not override file",
+			Options: []Option{Precedence(SourceReader, SourceEnv, SourceFile)},
+			Want:    Config{Host: "file", Port: 80, Level: "env", Labels: map[string]string{"tier": "file", "zone": "env"}},
+		},
+		{
+			Name:    "missing sources have the lowest precedence",
+			Options: []Option{Precedence(SourceReader)},
+			Want:    Config{Host: "reader", Port: 8080, Level: "reader", Labels: map[string]string{"tier": "env", "zone": "env"}},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			options := append([]Option{Dirs(dir), String(reference, DecoderYaml), UseEnv("myapp")}, tc.Options...)
+			if err := Load(&cfg, options...); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(tc.Want, cfg) {
+				t.Fatalf("\nwant %+v\ngot  %+v", tc.Want, cfg)
+			}
+		})
+	}
+}
+
+func Test_Precedence(t *testing.T) {
+	c := defaultConfucius()
+	Precedence(SourceEnv, SourceFile, SourceEnv)(c)
+
+	want := []Source{SourceReader, SourceEnv, SourceFile}
+	if !reflect.DeepEqual(want, c.precedence) {
+		t.Fatalf("want %v, got %v", want, c.precedence)
+	}
+}
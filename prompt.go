@@ -0,0 +1,51 @@
+package confucius
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prompter asks for the values of required fields that are missing
+// after every other source was applied.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// promptField prompts for the value of the required field f on the
+// output of the PromptMissing option and sets it to the line that is
+// read. A value that cannot be set is reported and asked for again. The
+// field is left unset when the input is empty or ends.
+func (c *confucius) promptField(f *field) error {
+	p := c.prompter
+	for {
+		label := f.path()
+		if f.secret {
+			label += " (secret)"
+		}
+		if _, err := fmt.Fprintf(p.out, "%s: ", label); err != nil {
+			return err
+		}
+
+		line, err := p.in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		val := strings.TrimRight(line, "\r\n")
+		if val == "" {
+			return nil
+		}
+
+		if serr := c.setFormattedValue(f.v, f.structTag, val); serr != nil {
+			fmt.Fprintf(p.out, "invalid value: %v\n", serr)
+			if err == io.EOF {
+				return nil
+			}
+			continue
+		}
+		c.setSource(f.path(), "prompt")
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package confucius
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_Load_PromptMissing(t *testing.T) {
+	type Config struct {
+		Host    string `conf:"host" validate:"required"`
+		Port    int    `conf:"port" validate:"required"`
+		Token   string `conf:"token" secret:"true" validate:"required"`
+		Level   string `conf:"level" default:"info"`
+		Timeout int    `conf:"timeout"`
+	}
+
+	t.Run("sets the missing required fields", func(t *testing.T) {
+		in := strings.NewReader("example.com\nnot a port\n8080\r\nxyz\n")
+		var out bytes.Buffer
+		sources := map[string]string{}
+
+		var cfg Config
+		err := Load(&cfg, String(`timeout: 5`, DecoderYaml), PromptMissing(in, &out), TrackSources(&sources))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		want := Config{Host: "example.com", Port: 8080, Token: "xyz", Level: "info", Timeout: 5}
+		if want != cfg {
+			t.Errorf("want %+v, got %+v", want, cfg)
+		}
+		if sources["host"] != "prompt" || sources["token"] != "prompt" {
+			t.Errorf("want sources of prompted fields to be prompt, got %v", sources)
+		}
+
+		wantOut := "host: port: invalid value: strconv.ParseInt: parsing \"not a port\": invalid syntax\nport: token (secret): "
+		if out.String() != wantOut {
+			t.Errorf("\nwant output %q\ngot         %q", wantOut, out.String())
+		}
+	})
+
+	t.Run("does not prompt for set fields", func(t *testing.T) {
+		in := strings.NewReader("x\n")
+		var out bytes.Buffer
+
+		var cfg Config
+		err := Load(&cfg, String("host: a\nport: 1\ntoken: b", DecoderYaml), PromptMissing(in, &out))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("want no prompts, got %q", out.String())
+		}
+	})
+
+	t.Run("empty input leaves fields missing", func(t *testing.T) {
+		in := strings.NewReader("example.com\n\n")
+		var out bytes.Buffer
+
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderYaml), PromptMissing(in, &out))
+		fe, ok := err.(fieldErrors)
+		if !ok {
+			t.Fatalf("want fieldErrors, got %T: %v", err, err)
+		}
+		if _, ok := fe["host"]; ok {
+			t.Errorf("want host to be set, got %v", fe["host"])
+		}
+		for _, path := range []string{"port", "token"} {
+			if _, ok := fe[path]; !ok {
+				t.Errorf("want %s in fieldErrors, got %v", path, fe)
+			}
+		}
+	})
+}
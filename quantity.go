@@ -0,0 +1,202 @@
+package confucius
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var quantityType = reflect.TypeOf(Quantity{})
+
+// Quantity is an amount of a resource, such as the cpu or the memory of a
+// container, written like the resource limits of Kubernetes: a number
+// followed by an optional suffix.
+//
+//	Ki Mi Gi Ti Pi Ei   binary multiples, powers of 1024
+//	m k M G T P E       decimal multiples, from 1/1000 to 10^18
+//	e3 E-2              decimal exponents
+//
+// A quantity is kept in thousandths, rounded up, so values must be within
+// about ±9.2P. Quantities of the same amount are equal, e.g. 1Gi == 1024Mi.
+//
+//	type Resources struct {
+//	  CPU    confucius.Quantity `conf:"cpu"`    // "250m"
+//	  Memory confucius.Quantity `conf:"memory"` // "64Mi"
+//	}
+type Quantity struct {
+	milli int64
+}
+
+// quantitySuffixes holds the multipliers of the suffixes of a quantity as
+// exponents of their base.
+var quantitySuffixes = map[string]struct{ base, exp int64 }{
+	"":   {10, 0},
+	"m":  {10, -3},
+	"k":  {10, 3},
+	"M":  {10, 6},
+	"G":  {10, 9},
+	"T":  {10, 12},
+	"P":  {10, 15},
+	"E":  {10, 18},
+	"Ki": {2, 10},
+	"Mi": {2, 20},
+	"Gi": {2, 30},
+	"Ti": {2, 40},
+	"Pi": {2, 50},
+	"Ei": {2, 60},
+}
+
+// ParseQuantity parses a quantity such as 64Mi, 250m, 1.5G or 1e3.
+func ParseQuantity(s string) (Quantity, error) {
+	num, suffix := splitQuantity(s)
+	if num == "" {
+		return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+	}
+
+	base, exp := int64(10), int64(0)
+	if m, ok := quantitySuffixes[suffix]; ok {
+		base, exp = m.base, m.exp
+	} else if len(suffix) > 1 && (suffix[0] == 'e' || suffix[0] == 'E') {
+		n, err := strconv.ParseInt(suffix[1:], 10, 64)
+		if err != nil {
+			return Quantity{}, fmt.Errorf("invalid quantity %q: unknown suffix %q", s, suffix)
+		}
+		if n < -30 || n > 30 {
+			return Quantity{}, rangeError(s, quantityType, strconv.ErrRange)
+		}
+		exp = n
+	} else {
+		return Quantity{}, fmt.Errorf("invalid quantity %q: unknown suffix %q", s, suffix)
+	}
+
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return Quantity{}, fmt.Errorf("invalid quantity %q", s)
+	}
+	n := exp
+	if n < 0 {
+		n = -n
+	}
+	pow := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(base), big.NewInt(n), nil))
+	if exp < 0 {
+		r.Quo(r, pow)
+	} else {
+		r.Mul(r, pow)
+	}
+	r.Mul(r, big.NewRat(1000, 1))
+
+	// round fractions of thousandths up, like Kubernetes.
+	milli, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		milli.Add(milli, big.NewInt(1))
+	}
+	if !milli.IsInt64() {
+		return Quantity{}, rangeError(s, quantityType, strconv.ErrRange)
+	}
+	return Quantity{milli: milli.Int64()}, nil
+}
+
+// splitQuantity splits s into its number, an optional sign followed by
+// digits with an optional decimal point, and its suffix.
+func splitQuantity(s string) (num, suffix string) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits, point := 0, false
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			digits++
+			continue
+		case s[i] == '.' && !point:
+			point = true
+			continue
+		}
+		break
+	}
+	if digits == 0 {
+		return "", s
+	}
+	return s[:i], s[i:]
+}
+
+// Value returns the quantity in whole units, rounded up, e.g. 2 for 1500m.
+func (q Quantity) Value() int64 {
+	if q.milli > 0 && q.milli%1000 != 0 {
+		return q.milli/1000 + 1
+	}
+	return q.milli / 1000
+}
+
+// MilliValue returns the quantity in thousandths of units, e.g. 250 for 250m.
+func (q Quantity) MilliValue() int64 {
+	return q.milli
+}
+
+// Cmp returns -1, 0 or 1 if q is lower than, equal to or greater than o.
+func (q Quantity) Cmp(o Quantity) int {
+	switch {
+	case q.milli < o.milli:
+		return -1
+	case q.milli > o.milli:
+		return 1
+	}
+	return 0
+}
+
+// String formats the quantity with the largest suffix that keeps it whole,
+// preferring the binary ones on a tie, e.g. 64Mi, 250m or 2k.
+func (q Quantity) String() string {
+	if q.milli%1000 != 0 {
+		return strconv.FormatInt(q.milli, 10) + "m"
+	}
+	n := q.milli / 1000
+	if n == 0 {
+		return "0"
+	}
+	best, size := "", int64(1)
+	for _, suffix := range []string{"Ei", "Pi", "Ti", "Gi", "Mi", "Ki", "E", "P", "T", "G", "M", "k"} {
+		d := new(big.Int).Exp(big.NewInt(quantitySuffixes[suffix].base), big.NewInt(quantitySuffixes[suffix].exp), nil).Int64()
+		if n%d == 0 && d > size {
+			best, size = suffix, d
+		}
+	}
+	return strconv.FormatInt(n/size, 10) + best
+}
+
+// setQuantity sets fv, a settable Quantity, to the quantity in val.
+func setQuantity(fv reflect.Value, val string) error {
+	q, err := ParseQuantity(val)
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(q))
+	return nil
+}
+
+// quantityHookFunc returns a DecodeHookFunc that converts strings and
+// numbers to a Quantity, e.g. "64Mi" or 0.5.
+func quantityHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != quantityType {
+			return data, nil
+		}
+
+		d := reflect.ValueOf(data)
+		switch f.Kind() {
+		case reflect.String:
+			return ParseQuantity(d.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return ParseQuantity(strconv.FormatInt(d.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return ParseQuantity(strconv.FormatUint(d.Uint(), 10))
+		case reflect.Float32, reflect.Float64:
+			return ParseQuantity(strconv.FormatFloat(d.Float(), 'f', -1, 64))
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,118 @@
+package confucius
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func Test_ParseQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		milli int64
+		str   string
+	}{
+		{"64Mi", 64 << 20 * 1000, "64Mi"},
+		{"250m", 250, "250m"},
+		{"0.1", 100, "100m"},
+		{".5", 500, "500m"},
+		{"2", 2000, "2"},
+		{"1.5G", 1500000000000, "1500M"},
+		{"1k", 1000000, "1k"},
+		{"1T", 1000000000000000, "1T"},
+		{"1024Ki", 1 << 20 * 1000, "1Mi"},
+		{"1e3", 1000000, "1k"},
+		{"1E-3", 1, "1m"},
+		{"0.1m", 1, "1m"},
+		{"-250m", -250, "-250m"},
+		{"+1", 1000, "1"},
+		{"0", 0, "0"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			q, err := ParseQuantity(tc.in)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if q.MilliValue() != tc.milli {
+				t.Errorf("want %d thousandths, got %d", tc.milli, q.MilliValue())
+			}
+			if q.String() != tc.str {
+				t.Errorf("want %s, got %s", tc.str, q.String())
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, in := range []string{"", "abc", "Mi", "1.2.3", "1Xi", "1e", "1e3.5", "64 Mi"} {
+			if _, err := ParseQuantity(in); err == nil {
+				t.Errorf("ParseQuantity(%q): expected err", in)
+			}
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		for _, in := range []string{"1E", "16Ei", "1e40"} {
+			if _, err := ParseQuantity(in); !errors.Is(err, strconv.ErrRange) {
+				t.Errorf("ParseQuantity(%q): want ErrRange, got %v", in, err)
+			}
+		}
+	})
+}
+
+func Test_Quantity(t *testing.T) {
+	gi, _ := ParseQuantity("1Gi")
+	mi, _ := ParseQuantity("1024Mi")
+	cpu, _ := ParseQuantity("1500m")
+
+	if gi != mi || gi.Cmp(mi) != 0 {
+		t.Errorf("want 1Gi equal to 1024Mi")
+	}
+	if cpu.Cmp(gi) != -1 || gi.Cmp(cpu) != 1 {
+		t.Errorf("want 1500m lower than 1Gi")
+	}
+	if cpu.Value() != 2 {
+		t.Errorf("want value of 1500m rounded up to 2, got %d", cpu.Value())
+	}
+}
+
+func Test_confucius_Load_Quantity(t *testing.T) {
+	type Resources struct {
+		CPU    Quantity  `conf:"cpu"`
+		Memory Quantity  `conf:"memory"`
+		Cores  Quantity  `conf:"cores"`
+		Disk   *Quantity `conf:"disk" default:"10Gi"`
+		Swap   Quantity  `conf:"swap"`
+	}
+
+	setenv(t, "SWAP", "512Mi")
+
+	var cfg Resources
+	err := Load(&cfg, String("cpu: 250m\nmemory: \"64Mi\"\ncores: 0.5", DecoderYaml), UseEnv(""))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		got  Quantity
+		want string
+	}{
+		"cpu":    {cfg.CPU, "250m"},
+		"memory": {cfg.Memory, "64Mi"},
+		"cores":  {cfg.Cores, "500m"},
+		"swap":   {cfg.Swap, "512Mi"},
+	} {
+		if tc.got.String() != tc.want {
+			t.Errorf("want %s %s, got %s", name, tc.want, tc.got)
+		}
+	}
+	if cfg.Disk == nil || cfg.Disk.String() != "10Gi" {
+		t.Errorf("want disk 10Gi, got %v", cfg.Disk)
+	}
+
+	t.Run("invalid quantity", func(t *testing.T) {
+		var cfg Resources
+		err := Load(&cfg, String("memory: 64Xi", DecoderYaml))
+		if err == nil {
+			t.Fatal("expected err")
+		}
+	})
+}
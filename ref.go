@@ -0,0 +1,188 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// refChain is the chain of references being resolved, starting from
+// the outermost one. It guards against references that lead back to
+// themselves, directly or through other references.
+type refChain []string
+
+// push returns the chain extended with ref. If ref is already being
+// resolved then an error naming the cycle is returned instead:
+//
+//	refChain{"a", "b"}.push("a")   --->   reference cycle: a -> b -> a
+func (rc refChain) push(ref string) (refChain, error) {
+	for i, r := range rc {
+		if r == ref {
+			cycle := append(append([]string{}, rc[i:]...), ref)
+			return nil, fmt.Errorf("%w: %s", ErrReferenceCycle, strings.Join(cycle, " -> "))
+		}
+	}
+	return append(rc[:len(rc):len(rc)], ref), nil
+}
+
+// configRefPattern matches the references to other keys of the config, e.g.
+// ${config:server.host}.
+var configRefPattern = regexp.MustCompile(`\$\{config:([^}]*)\}`)
+
+// configRefs resolves the references to other keys of the merged config
+// values, keeping the values of the keys already resolved.
+type configRefs struct {
+	root     decodedObject
+	resolved map[string]interface{}
+}
+
+// interpolateConfig replaces the references to other keys in the strings of
+// vals, e.g. ${config:server.port}, with the values of those keys when the
+// InterpolateConfig option is used. A string made of a single reference
+// takes the value of the key as is, e.g. a number or a list.
+func (c *confucius) interpolateConfig(vals decodedObject) error {
+	if !c.interpolateConfigRefs {
+		return nil
+	}
+	r := &configRefs{root: vals, resolved: make(map[string]interface{})}
+	for key, val := range vals {
+		res, err := r.resolve(nil, key, val)
+		if err != nil {
+			return err
+		}
+		vals[key] = res
+	}
+	return nil
+}
+
+// resolve returns val, the value at path, with its references resolved.
+// chain holds the paths of the references being resolved.
+func (r *configRefs) resolve(chain refChain, path string, val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case string:
+		return r.interpolate(chain, path, v)
+	case decodedObject:
+		return r.resolveObject(chain, path, v)
+	case map[string]interface{}:
+		return r.resolveObject(chain, path, v)
+	case []interface{}:
+		for i, elem := range v {
+			res, err := r.resolve(chain, fmt.Sprintf("%s[%d]", path, i), elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = res
+		}
+	case []map[string]interface{}:
+		for i, elem := range v {
+			if _, err := r.resolveObject(chain, fmt.Sprintf("%s[%d]", path, i), elem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return val, nil
+}
+
+func (r *configRefs) resolveObject(chain refChain, path string, obj map[string]interface{}) (interface{}, error) {
+	for key, elem := range obj {
+		res, err := r.resolve(chain, joinPath(path, key), elem)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = res
+	}
+	return obj, nil
+}
+
+// interpolate replaces the references in s, the string at path.
+func (r *configRefs) interpolate(chain refChain, path, s string) (interface{}, error) {
+	matches := configRefPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	chain, err := chain.push(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		ref := s[m[2]:m[3]]
+		val, err := r.lookup(chain, ref)
+		if err != nil {
+			return nil, err
+		}
+		if m[0] == 0 && m[1] == len(s) {
+			return val, nil
+		}
+		switch val.(type) {
+		case decodedObject, map[string]interface{}, []interface{}, []map[string]interface{}:
+			return nil, fmt.Errorf("%s: ${config:%s} is not a scalar and cannot be interpolated in a string", path, ref)
+		case nil:
+			val = ""
+		}
+		sb.WriteString(s[last:m[0]])
+		sb.WriteString(fmt.Sprint(val))
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String(), nil
+}
+
+// lookup returns the resolved value of the key at ref.
+func (r *configRefs) lookup(chain refChain, ref string) (interface{}, error) {
+	if val, ok := r.resolved[ref]; ok {
+		return val, nil
+	}
+	raw, err := r.get(ref)
+	if err != nil {
+		return nil, err
+	}
+	val, err := r.resolve(chain, ref, raw)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[ref] = val
+	return val, nil
+}
+
+// get returns the value at ref, a dot separated path of keys that may
+// index lists, e.g. servers[0].host. Keys are matched case insensitively
+// when there is no exact match.
+func (r *configRefs) get(ref string) (interface{}, error) {
+	var cur interface{} = r.root
+	for _, seg := range strings.Split(ref, ".") {
+		key := seg
+		if i := strings.Index(seg, "["); i >= 0 {
+			key = seg[:i]
+		}
+		obj, ok := asObject(cur)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("${config:%s}: key not found", ref)
+		}
+		if cur, ok = lookupKey(obj, key); !ok {
+			return nil, fmt.Errorf("${config:%s}: key not found", ref)
+		}
+
+		for rest := seg[len(key):]; rest != ""; {
+			end := strings.Index(rest, "]")
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("${config:%s}: invalid index", ref)
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("${config:%s}: invalid index", ref)
+			}
+			rv := reflect.ValueOf(cur)
+			if rv.Kind() != reflect.Slice || idx < 0 || idx >= rv.Len() {
+				return nil, fmt.Errorf("${config:%s}: key not found", ref)
+			}
+			cur = rv.Index(idx).Interface()
+			rest = rest[end+1:]
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,125 @@
+package confucius
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_refChain_push(t *testing.T) {
+	chain, err := refChain{"a"}.push("b")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	// pushing onto a shared chain must not affect its other extensions.
+	c1, _ := chain.push("c")
+	c2, _ := chain.push("d")
+	if strings.Join(c1, ",") != "a,b,c" || strings.Join(c2, ",") != "a,b,d" {
+		t.Fatalf("chains share state: %v %v", c1, c2)
+	}
+
+	_, err = c1.push("b")
+	if !errors.Is(err, ErrReferenceCycle) {
+		t.Fatalf("want err %v, got %v", ErrReferenceCycle, err)
+	}
+	if want := "reference cycle: b -> c -> b"; err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+}
+
+func Test_confucius_Load_InterpolateConfig(t *testing.T) {
+	type Config struct {
+		Server struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		} `conf:"server"`
+		URL     string   `conf:"url"`
+		Health  string   `conf:"health"`
+		Port    int      `conf:"port"`
+		Hosts   []string `conf:"hosts"`
+		Primary string   `conf:"primary"`
+	}
+
+	t.Run("simple and nested", func(t *testing.T) {
+		content := `
+server:
+  host: localhost
+  port: 8080
+url: http://${config:server.host}:${config:server.port}
+health: ${config:url}/healthz
+port: ${config:server.port}
+hosts: [a.local, b.local]
+primary: ${config:hosts[1]}
+`
+		var cfg Config
+		if err := Load(&cfg, String(content, DecoderYaml), InterpolateConfig()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.URL != "http://localhost:8080" {
+			t.Errorf("want url http://localhost:8080, got %q", cfg.URL)
+		}
+		if cfg.Health != "http://localhost:8080/healthz" {
+			t.Errorf("want health http://localhost:8080/healthz, got %q", cfg.Health)
+		}
+		if cfg.Port != 8080 {
+			t.Errorf("want port 8080, got %d", cfg.Port)
+		}
+		if cfg.Primary != "b.local" {
+			t.Errorf("want primary b.local, got %q", cfg.Primary)
+		}
+	})
+
+	t.Run("across sources", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg,
+			String(`{"server": {"host": "localhost"}, "url": "http://${config:server.host}"}`, DecoderJSON),
+			String(`{"server": {"host": "example.com"}}`, DecoderJSON),
+			InterpolateConfig(),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.URL != "http://example.com" {
+			t.Errorf("want url resolved against the merged values, got %q", cfg.URL)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		content := `
+url: http://${config:health}
+health: ${config:primary}/healthz
+primary: ${config:url}
+`
+		var cfg Config
+		err := Load(&cfg, String(content, DecoderYaml), InterpolateConfig())
+		if !errors.Is(err, ErrReferenceCycle) {
+			t.Fatalf("want err %v, got %v", ErrReferenceCycle, err)
+		}
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`url: http://${config:server.name}`, DecoderYaml), InterpolateConfig())
+		if err == nil || !strings.Contains(err.Error(), "${config:server.name}: key not found") {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
+	t.Run("object in a string", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String("server:\n  host: localhost\nurl: http://${config:server}", DecoderYaml), InterpolateConfig())
+		if err == nil || !strings.Contains(err.Error(), "not a scalar") {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var cfg Config
+		if err := Load(&cfg, String("url: http://${config:server.host}", DecoderYaml)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.URL != "http://${config:server.host}" {
+			t.Errorf("want the reference left as is, got %q", cfg.URL)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// defaultRegistry holds the defaults registered with RegisterDefault,
+// keyed by their type.
+var defaultRegistry = struct {
+	sync.RWMutex
+	factories map[reflect.Type]func() reflect.Value
+}{factories: make(map[reflect.Type]func() reflect.Value)}
+
+// RegisterDefault registers fn as the factory of the default value of the
+// fields of type typ, for types whose default cannot be written in a default
+// tag, e.g. a *tls.Config. The fields of typ that are not set once loaded,
+// and that have no default tag, are set to the value returned by fn.
+//
+//	confucius.RegisterDefault(reflect.TypeOf(&tls.Config{}), func() reflect.Value {
+//	  return reflect.ValueOf(&tls.Config{MinVersion: tls.VersionTLS12})
+//	})
+//
+// fn is called for each field it sets and must return a value assignable to
+// typ. A default registered for a struct type also applies to the nil
+// pointers to that struct. The registry is shared by every Load, register
+// the defaults once, e.g. in init. A nil fn removes the default of typ.
+func RegisterDefault(typ reflect.Type, fn func() reflect.Value) {
+	defaultRegistry.Lock()
+	defer defaultRegistry.Unlock()
+
+	if fn == nil {
+		delete(defaultRegistry.factories, typ)
+		return
+	}
+	defaultRegistry.factories[typ] = fn
+}
+
+// registeredDefault returns the factory registered for t, if any.
+func registeredDefault(t reflect.Type) (func() reflect.Value, bool) {
+	defaultRegistry.RLock()
+	defer defaultRegistry.RUnlock()
+
+	fn, ok := defaultRegistry.factories[t]
+	return fn, ok
+}
+
+// setRegisteredDefault sets fv, a field that is not set, to the default
+// registered for its type or, for a nil pointer, for the type it points to.
+// It reports whether a default was registered.
+func setRegisteredDefault(fv reflect.Value) (bool, error) {
+	t := fv.Type()
+	fn, ok := registeredDefault(t)
+	if !ok && t.Kind() == reflect.Ptr {
+		if fn, ok = registeredDefault(t.Elem()); ok {
+			t = t.Elem()
+		}
+	}
+	if !ok {
+		return false, nil
+	}
+
+	v := fn()
+	if !v.IsValid() {
+		return true, fmt.Errorf("registered default of %v is invalid", t)
+	}
+	if !v.Type().AssignableTo(t) {
+		return true, fmt.Errorf("registered default of type %v is not assignable to %v", v.Type(), t)
+	}
+	if t != fv.Type() {
+		fv.Set(reflect.New(t))
+		fv = fv.Elem()
+	}
+	fv.Set(v)
+	return true, nil
+}
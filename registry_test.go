@@ -0,0 +1,107 @@
+package confucius
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type retryPolicy struct {
+	Attempts int      `conf:"attempts"`
+	Backoff  []string `conf:"backoff"`
+}
+
+type tlsSettings struct {
+	MinVersion string `conf:"min_version"`
+	Ciphers    []string
+}
+
+func Test_confucius_Load_RegisterDefault(t *testing.T) {
+	RegisterDefault(reflect.TypeOf(retryPolicy{}), func() reflect.Value {
+		return reflect.ValueOf(retryPolicy{Attempts: 3, Backoff: []string{"1s", "5s"}})
+	})
+	RegisterDefault(reflect.TypeOf(&tlsSettings{}), func() reflect.Value {
+		return reflect.ValueOf(&tlsSettings{MinVersion: "1.2"})
+	})
+	defer RegisterDefault(reflect.TypeOf(retryPolicy{}), nil)
+	defer RegisterDefault(reflect.TypeOf(&tlsSettings{}), nil)
+
+	type Config struct {
+		Retry     retryPolicy  `conf:"retry"`
+		Fallback  *retryPolicy `conf:"fallback"`
+		Custom    retryPolicy  `conf:"custom"`
+		TLS       *tlsSettings `conf:"tls"`
+		ClientTLS *tlsSettings `conf:"client_tls"`
+	}
+
+	sources := map[string]string{}
+	var cfg Config
+	content := `{"custom": {"attempts": 7}, "client_tls": {"min_version": "1.3"}}`
+	if err := Load(&cfg, String(content, DecoderJSON), TrackSources(&sources)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := Config{
+		Retry:     retryPolicy{Attempts: 3, Backoff: []string{"1s", "5s"}},
+		Fallback:  &retryPolicy{Attempts: 3, Backoff: []string{"1s", "5s"}},
+		Custom:    retryPolicy{Attempts: 7},
+		TLS:       &tlsSettings{MinVersion: "1.2"},
+		ClientTLS: &tlsSettings{MinVersion: "1.3"},
+	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\ngot %+v", want, cfg)
+	}
+	if sources["retry"] != "default" {
+		t.Errorf("want retry source default, got %q", sources["retry"])
+	}
+
+	t.Run("copies", func(t *testing.T) {
+		var a, b Config
+		if err := Load(&a, String(`{}`, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if err := Load(&b, String(`{}`, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if a.TLS == b.TLS {
+			t.Errorf("want each load to get its own default")
+		}
+	})
+
+	t.Run("default tag wins", func(t *testing.T) {
+		var cfg struct {
+			Retry *retryPolicy `conf:"retry"`
+			Names []string     `conf:"names" default:"[a]"`
+		}
+		RegisterDefault(reflect.TypeOf([]string{}), func() reflect.Value {
+			return reflect.ValueOf([]string{"registered"})
+		})
+		defer RegisterDefault(reflect.TypeOf([]string{}), nil)
+
+		if err := Load(&cfg, String(`{}`, DecoderJSON)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !reflect.DeepEqual(cfg.Names, []string{"a"}) {
+			t.Errorf("want names [a], got %v", cfg.Names)
+		}
+		if cfg.Retry == nil || cfg.Retry.Attempts != 3 {
+			t.Errorf("want the default of retryPolicy for a nil pointer, got %+v", cfg.Retry)
+		}
+	})
+
+	t.Run("not assignable", func(t *testing.T) {
+		type timeout int
+		RegisterDefault(reflect.TypeOf(timeout(0)), func() reflect.Value {
+			return reflect.ValueOf("30s")
+		})
+		defer RegisterDefault(reflect.TypeOf(timeout(0)), nil)
+
+		var cfg struct {
+			Timeout timeout `conf:"timeout"`
+		}
+		err := Load(&cfg, String(`{}`, DecoderJSON))
+		if e, ok := err.(fieldErrors)["timeout"]; !ok || !strings.Contains(e.Error(), "not assignable") {
+			t.Fatalf("want timeout in fieldErrors, got %+v", err)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package confucius
+
+import (
+	"reflect"
+	"strings"
+)
+
+// secretMask replaces the values of secret fields when they are printed.
+const secretMask = "****"
+
+// maskedError is an error whose message has the value of a secret
+// field masked. It unwraps to the original error.
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (e *maskedError) Error() string { return e.msg }
+
+func (e *maskedError) Unwrap() error { return e.err }
+
+// maskError masks val in the message of err if the field is a secret.
+func (c *confucius) maskError(st structTag, err error, val string) error {
+	if !st.secret || c.unmaskSecrets || val == "" || !strings.Contains(err.Error(), val) {
+		return err
+	}
+	return &maskedError{err: err, msg: strings.ReplaceAll(err.Error(), val, secretMask)}
+}
+
+// masked reports whether v, the value of a field, must be printed as
+// secretMask. Only secret fields that are set are masked.
+func (c *confucius) masked(st structTag, v reflect.Value) bool {
+	return st.secret && !c.unmaskSecrets && !isZero(v)
+}
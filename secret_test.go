@@ -0,0 +1,107 @@
+package confucius
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_maskError(t *testing.T) {
+	err := errors.New(`parsing "hunter2": invalid syntax`)
+
+	for _, tc := range []struct {
+		Name     string
+		Unmasked bool
+		St       structTag
+		Want     string
+	}{
+		{Name: "secret", St: structTag{secret: true}, Want: `parsing "****": invalid syntax`},
+		{Name: "not secret", St: structTag{}, Want: err.Error()},
+		{Name: "unmasked", Unmasked: true, St: structTag{secret: true}, Want: err.Error()},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := defaultConfucius()
+			c.unmaskSecrets = tc.Unmasked
+
+			got := c.maskError(tc.St, err, "hunter2")
+			if got.Error() != tc.Want {
+				t.Fatalf("want %q, got %q", tc.Want, got.Error())
+			}
+			if !errors.Is(got, err) {
+				t.Fatalf("masked error does not unwrap to %v", err)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_Secret(t *testing.T) {
+	type Config struct {
+		Database struct {
+			Username string `conf:"username"`
+			Password string `conf:"password" secret:"true"`
+			Token    string `conf:"token" secret:"true"`
+			Pin      int    `conf:"pin" secret:"true"`
+		} `conf:"database"`
+	}
+
+	var cfg Config
+	err := Load(&cfg, String(`database: {username: admin, password: S3cr3t}`, DecoderYaml))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if cfg.Database.Password != "S3cr3t" {
+		t.Fatalf("cfg.Database.Password == %s, expected %s", cfg.Database.Password, "S3cr3t")
+	}
+
+	t.Run("masked in dump", func(t *testing.T) {
+		out, err := Dump(&cfg)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := "database:\n  username: admin\n  password: '****'\n  token: \"\"\n  pin: 0\n"
+		if string(out) != want {
+			t.Fatalf("\nwant %s\ngot %s", want, out)
+		}
+	})
+
+	t.Run("unmasked in dump", func(t *testing.T) {
+		out, err := Dump(&cfg, UnmaskSecrets())
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if !strings.Contains(string(out), "password: S3cr3t") {
+			t.Fatalf("want unmasked password, got %s", out)
+		}
+	})
+
+	t.Run("masked in errors and logs", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "DATABASE_PIN", "98x76")
+
+		var logs []string
+		var cfg Config
+		err := Load(&cfg,
+			String(`{}`, DecoderYaml),
+			UseEnv(""),
+			Logger(Callback(func(level LogLevel, message, file string, line int) {
+				logs = append(logs, message)
+			})),
+		)
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+		if strings.Contains(err.Error(), "98x76") || !strings.Contains(err.Error(), secretMask) {
+			t.Fatalf("want masked pin in err, got %v", err)
+		}
+		if !errors.Is(err.(fieldErrors)["database.pin"], strconv.ErrSyntax) {
+			t.Fatalf("want err %v, got %v", strconv.ErrSyntax, err)
+		}
+		for _, log := range logs {
+			if strings.Contains(log, "98x76") {
+				t.Fatalf("secret leaked into log: %s", log)
+			}
+		}
+	})
+}
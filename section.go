@@ -0,0 +1,82 @@
+package confucius
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectSection returns the values of the section given with the Section
+// option, the object found in vals at its dot separated path. Keys are
+// matched case insensitively when there is no exact match, like the
+// fields of the config struct are. The sources and key order recorded
+// for the values are made relative to the section.
+func (c *confucius) selectSection(vals decodedObject) (decodedObject, error) {
+	if c.section == "" {
+		return vals, nil
+	}
+
+	var cur interface{} = vals
+	keys := strings.Split(c.section, ".")
+	for i, key := range keys {
+		obj, ok := asObject(cur)
+		if !ok {
+			return nil, fmt.Errorf("section %s: %s is not an object", c.section, strings.Join(keys[:i], "."))
+		}
+		if cur, ok = lookupKey(obj, key); !ok {
+			return nil, fmt.Errorf("section %s not found", c.section)
+		}
+	}
+
+	section, ok := asObject(cur)
+	if !ok {
+		return nil, fmt.Errorf("section %s is not an object", c.section)
+	}
+
+	prefix := strings.ToLower(c.section) + "."
+	if c.fileSources != nil {
+		sources := make(map[string]string, len(c.fileSources))
+		for path, origin := range c.fileSources {
+			if strings.HasPrefix(path, prefix) {
+				sources[strings.TrimPrefix(path, prefix)] = origin
+			}
+		}
+		c.fileSources = sources
+	}
+	if c.keyIndex != nil {
+		var paths []string
+		c.keyIndex = make(map[string]int)
+		for _, path := range c.keys {
+			if strings.HasPrefix(path, prefix) {
+				c.keyIndex[strings.TrimPrefix(path, prefix)] = len(paths)
+				paths = append(paths, strings.TrimPrefix(path, prefix))
+			}
+		}
+		c.keys = paths
+	}
+	return section, nil
+}
+
+// asObject returns val as a decodedObject if it is a decoded object.
+func asObject(val interface{}) (decodedObject, bool) {
+	switch v := val.(type) {
+	case decodedObject:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+// lookupKey returns the value of key in obj, falling back to a case
+// insensitive match of the key.
+func lookupKey(obj decodedObject, key string) (interface{}, bool) {
+	if val, ok := obj[key]; ok {
+		return val, true
+	}
+	for k, val := range obj {
+		if strings.EqualFold(k, key) {
+			return val, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,76 @@
+package confucius
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_Load_Section(t *testing.T) {
+	type Metadata struct {
+		Name   string `conf:"name"`
+		Master bool   `conf:"master" validate:"required"`
+		Team   string `conf:"team" default:"core"`
+	}
+
+	os.Clearenv()
+
+	t.Run("top level", func(t *testing.T) {
+		sources := map[string]string{}
+		var cfg Metadata
+		err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")), Section("metadata"), TrackSources(&sources))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := Metadata{Name: "redis", Master: true, Team: "core"}
+		if cfg != want {
+			t.Fatalf("want %+v, got %+v", want, cfg)
+		}
+		if src := sources["name"]; !strings.HasSuffix(src, "pod.yaml") {
+			t.Errorf("want name sourced from pod.yaml, got %q", src)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var cfg struct {
+			Host string `conf:"host"`
+			Port int    `conf:"port"`
+		}
+		err := Load(&cfg, String("apps:\n  billing:\n    Server:\n      host: localhost\n      port: 80", DecoderYaml), Section("apps.billing.server"))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "localhost" || cfg.Port != 80 {
+			t.Fatalf("want localhost:80, got %+v", cfg)
+		}
+	})
+
+	t.Run("env is relative to the section", func(t *testing.T) {
+		setenv(t, "POD_TEAM", "platform")
+		defer os.Unsetenv("POD_TEAM")
+
+		var cfg Metadata
+		if err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")), Section("metadata"), UseEnv("pod")); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Team != "platform" {
+			t.Fatalf("want team platform, got %s", cfg.Team)
+		}
+	})
+
+	for name, section := range map[string]string{
+		"missing":        "status",
+		"missing nested": "metadata.labels",
+		"not an object":  "kind",
+		"inside a value": "kind.name",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var cfg Metadata
+			err := Load(&cfg, File("pod.yaml"), Dirs(filepath.Join("testdata", "valid")), Section(section))
+			if err == nil || !strings.Contains(err.Error(), "section "+section) {
+				t.Fatalf("want section %s error, got %v", section, err)
+			}
+		})
+	}
+}
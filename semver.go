@@ -0,0 +1,151 @@
+package confucius
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version, see https://semver.org.
+type semver struct {
+	major, minor, patch uint64
+	pre                 []string // the dot separated identifiers of the pre-release.
+}
+
+// parseSemver parses a semantic version such as 1.2.3, v1.2.3-rc.1 or
+// 1.2.3+build.5. The build metadata is ignored.
+func parseSemver(s string) (semver, error) {
+	var v semver
+
+	str := strings.TrimPrefix(s, "v")
+	if i := strings.Index(str, "+"); i >= 0 {
+		if str[i+1:] == "" {
+			return v, fmt.Errorf("invalid semantic version %q: empty build metadata", s)
+		}
+		str = str[:i]
+	}
+	if i := strings.Index(str, "-"); i >= 0 {
+		v.pre = strings.Split(str[i+1:], ".")
+		for _, id := range v.pre {
+			if id == "" {
+				return v, fmt.Errorf("invalid semantic version %q: empty pre-release identifier", s)
+			}
+		}
+		str = str[:i]
+	}
+
+	parts := strings.Split(str, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid semantic version %q: want major.minor.patch", s)
+	}
+	nums := make([]uint64, len(parts))
+	for i, part := range parts {
+		if part == "" || (len(part) > 1 && part[0] == '0') {
+			return v, fmt.Errorf("invalid semantic version %q: invalid number %q", s, part)
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf("invalid semantic version %q: invalid number %q", s, part)
+		}
+		nums[i] = n
+	}
+	v.major, v.minor, v.patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 if v is lower than, equal to or greater
+// than o. Versions with a pre-release are lower than the ones without.
+func (v semver) compare(o semver) int {
+	for _, pair := range [][2]uint64{{v.major, o.major}, {v.minor, o.minor}, {v.patch, o.patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := comparePreRelease(v.pre[i], o.pre[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.pre) < len(o.pre):
+		return -1
+	case len(v.pre) > len(o.pre):
+		return 1
+	}
+	return 0
+}
+
+// comparePreRelease compares two pre-release identifiers. Numeric
+// identifiers are compared numerically and are lower than the others.
+func comparePreRelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an == bn {
+			return 0
+		}
+		if an < bn {
+			return -1
+		}
+		return 1
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// checkSemverConstraint reports whether v satisfies the constraint, made
+// of comparisons separated by spaces that must all hold, e.g. ">=1.2.0 <2.0.0".
+func checkSemverConstraint(v semver, constraint string) (bool, error) {
+	comparisons := strings.Fields(constraint)
+	if len(comparisons) == 0 {
+		return false, fmt.Errorf("empty version constraint")
+	}
+
+	for _, cmp := range comparisons {
+		op := strings.TrimRight(cmp, "0123456789.-+abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		o, err := parseSemver(cmp[len(op):])
+		if err != nil {
+			return false, err
+		}
+
+		c := v.compare(o)
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = c == 0
+		case "!=":
+			ok = c != 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		default:
+			return false, fmt.Errorf("unknown version comparison %q", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package confucius
+
+import "testing"
+
+func Test_parseSemver(t *testing.T) {
+	for _, tc := range []struct {
+		In      string
+		WantErr bool
+	}{
+		{In: "1.2.3"},
+		{In: "v1.2.3"},
+		{In: "0.0.0"},
+		{In: "1.2.3-rc.1"},
+		{In: "1.2.3+build.5"},
+		{In: "1.2.3-beta+exp.sha.5114f85"},
+		{In: "1.2", WantErr: true},
+		{In: "1.2.3.4", WantErr: true},
+		{In: "01.2.3", WantErr: true},
+		{In: "1.x.3", WantErr: true},
+		{In: "1.2.3-", WantErr: true},
+		{In: "1.2.3+", WantErr: true},
+		{In: "", WantErr: true},
+	} {
+		t.Run(tc.In, func(t *testing.T) {
+			_, err := parseSemver(tc.In)
+			if tc.WantErr && err == nil {
+				t.Fatalf("expected err")
+			}
+			if !tc.WantErr && err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+		})
+	}
+}
+
+func Test_semver_compare(t *testing.T) {
+	// ordered from the lowest to the highest, as in https://semver.org.
+	versions := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 0; i < len(versions)-1; i++ {
+		a, _ := parseSemver(versions[i])
+		b, _ := parseSemver(versions[i+1])
+		if a.compare(b) != -1 || b.compare(a) != 1 || a.compare(a) != 0 {
+			t.Errorf("want %s < %s", versions[i], versions[i+1])
+		}
+	}
+}
+
+func Test_checkSemverConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		Version    string
+		Constraint string
+		Want       bool
+		WantErr    bool
+	}{
+		{Version: "1.2.0", Constraint: ">=1.2.0", Want: true},
+		{Version: "1.1.9", Constraint: ">=1.2.0"},
+		{Version: "1.5.0", Constraint: ">=1.2.0 <2.0.0", Want: true},
+		{Version: "2.0.0", Constraint: ">=1.2.0 <2.0.0"},
+		{Version: "1.2.0", Constraint: "1.2.0", Want: true},
+		{Version: "1.2.0", Constraint: "!=1.2.0"},
+		{Version: "1.2.0", Constraint: "<=v1.2.0", Want: true},
+		{Version: "1.2.0", Constraint: "", WantErr: true},
+		{Version: "1.2.0", Constraint: "~1.2.0", WantErr: true},
+		{Version: "1.2.0", Constraint: ">=1.2", WantErr: true},
+	} {
+		t.Run(tc.Version+" "+tc.Constraint, func(t *testing.T) {
+			v, err := parseSemver(tc.Version)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			got, err := checkSemverConstraint(v, tc.Constraint)
+			if tc.WantErr {
+				if err == nil {
+					t.Fatalf("expected err")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.Want {
+				t.Fatalf("want %v, got %v", tc.Want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package confucius
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// LoadSnapshot loads the configuration like Load, into a fresh value of the
+// type cfg points to, and returns a function that returns a deep copy of the
+// loaded configuration, as a pointer of the same type as cfg, on each call.
+// Readers of a snapshot never see the changes made to another one, so the
+// snapshots can be handed out to concurrent readers. cfg is only used for
+// its type.
+//
+//	snapshot, err := confucius.LoadSnapshot(&Config{}, confucius.File("config.yaml"))
+//	...
+//	cfg := snapshot().(*Config)
+//
+// The values of unexported fields, channels and funcs are shared by the
+// snapshots rather than copied.
+func LoadSnapshot(cfg interface{}, options ...Option) (func() interface{}, error) {
+	if !isStructPtr(cfg) {
+		return nil, fmt.Errorf("cfg must be a pointer to a struct")
+	}
+
+	loaded := reflect.New(reflect.TypeOf(cfg).Elem())
+	if err := Load(loaded.Interface(), options...); err != nil {
+		return nil, err
+	}
+
+	return func() interface{} {
+		return deepCopy(loaded).Interface()
+	}, nil
+}
+
+// deepCopy returns a copy of v that shares no pointers, slices or maps
+// with it. Values referenced several times in v, including cycles, are
+// copied once.
+func deepCopy(v reflect.Value) reflect.Value {
+	cp := &copier{ptrs: make(map[copiedPtr]reflect.Value)}
+	return cp.copy(v)
+}
+
+// copiedPtr identifies a pointer already copied by a copier.
+type copiedPtr struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// copier deep copies values, keeping track of the pointers it copied.
+type copier struct {
+	ptrs map[copiedPtr]reflect.Value
+}
+
+func (cp *copier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := copiedPtr{addr: v.Pointer(), typ: v.Type()}
+		if c, ok := cp.ptrs[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		cp.ptrs[key] = c
+		c.Elem().Set(cp.copy(v.Elem()))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cp.copy(v.Elem()))
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cp.copy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cp.copy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(cp.copy(iter.Key()), cp.copy(iter.Value()))
+		}
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		if isBig(v.Type()) {
+			// the digits of big numbers live in unexported slices.
+			switch x := bigValue(v).(type) {
+			case *big.Int:
+				c.Set(reflect.ValueOf(new(big.Int).Set(x)).Elem())
+			case *big.Float:
+				c.Set(reflect.ValueOf(new(big.Float).Copy(x)).Elem())
+			}
+			return c
+		}
+		// unexported fields are copied as they are, they cannot be set.
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cp.copy(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+package confucius
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_LoadSnapshot(t *testing.T) {
+	type Server struct {
+		Host string `conf:"host"`
+	}
+	type Config struct {
+		Name    string            `conf:"name"`
+		Servers []Server          `conf:"servers"`
+		Labels  map[string]string `conf:"labels"`
+		Primary *Server           `conf:"primary"`
+		Limit   *big.Int          `conf:"limit"`
+	}
+
+	content := `{
+		"name": "app",
+		"servers": [{"host": "a.local"}],
+		"labels": {"tier": "web"},
+		"primary": {"host": "p.local"},
+		"limit": 100
+	}`
+
+	snapshot, err := LoadSnapshot(&Config{}, String(content, DecoderJSON))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	first := snapshot().(*Config)
+	first.Name = "changed"
+	first.Servers[0].Host = "changed"
+	first.Labels["tier"] = "changed"
+	first.Primary.Host = "changed"
+	first.Limit.SetInt64(7)
+
+	second := snapshot().(*Config)
+	want := &Config{
+		Name:    "app",
+		Servers: []Server{{Host: "a.local"}},
+		Labels:  map[string]string{"tier": "web"},
+		Primary: &Server{Host: "p.local"},
+		Limit:   big.NewInt(100),
+	}
+	if !reflect.DeepEqual(want, second) {
+		t.Errorf("\nwant %+v\ngot %+v", want, second)
+	}
+
+	t.Run("invalid cfg", func(t *testing.T) {
+		if _, err := LoadSnapshot(Config{}, String(content, DecoderJSON)); err == nil {
+			t.Fatal("expected err")
+		}
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		snapshot, err := LoadSnapshot(&Config{}, String(`{"name": [}`, DecoderJSON))
+		if err == nil || snapshot != nil {
+			t.Fatalf("want err and no snapshot, got %v", err)
+		}
+	})
+}
+
+func Test_deepCopy(t *testing.T) {
+	type node struct {
+		Name  string
+		Next  *node
+		Items [2][]int
+		Any   interface{}
+	}
+
+	n := &node{Name: "a", Items: [2][]int{{1}, {2}}, Any: []string{"x"}}
+	n.Next = n
+
+	c := deepCopy(reflect.ValueOf(n)).Interface().(*node)
+	if c == n {
+		t.Fatal("want a new pointer")
+	}
+	if c.Next != c {
+		t.Error("want the cycle preserved in the copy")
+	}
+
+	c.Items[0][0] = 9
+	c.Any.([]string)[0] = "y"
+	if n.Items[0][0] != 1 || n.Any.([]string)[0] != "x" {
+		t.Errorf("want original untouched, got %+v", n)
+	}
+
+	f := big.NewFloat(1.5).SetPrec(200)
+	fc := deepCopy(reflect.ValueOf(f)).Interface().(*big.Float)
+	fc.SetInt64(3)
+	if f.Cmp(big.NewFloat(1.5)) != 0 || fc.Prec() != 200 {
+		t.Errorf("want original 1.5 and copy prec 200, got %v and %d", f, fc.Prec())
+	}
+}
@@ -0,0 +1,77 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// setSource records origin as the source of the value of the field
+// at path. It does nothing unless sources are being tracked.
+func (c *confucius) setSource(path, origin string) {
+	if c.trackSources == nil {
+		return
+	}
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[path] = origin
+}
+
+// setFileSources records origin as the source of every value in data,
+// keyed by the lowercased path of the value. Values decoded later
+// override the sources of the ones decoded before them, like they do
+// when the files are merged. The sources are also recorded to decide
+// whether the environment overrides a value when the Precedence option
+// is used.
+func (c *confucius) setFileSources(data interface{}, path, origin string) {
+	if c.trackSources == nil && c.precedence == nil {
+		return
+	}
+	if c.fileSources == nil {
+		c.fileSources = make(map[string]string)
+	}
+
+	dv := reflect.ValueOf(data)
+	switch dv.Kind() {
+	case reflect.Map:
+		for _, key := range dv.MapKeys() {
+			keyPath := strings.TrimPrefix(path+"."+strings.ToLower(fmt.Sprint(key.Interface())), ".")
+			c.setFileSources(dv.MapIndex(key).Interface(), keyPath, origin)
+		}
+	case reflect.Slice, reflect.Array:
+		// slices are replaced as a whole when merged, forget the
+		// sources of elements that a previous slice may have had.
+		for p := range c.fileSources {
+			if strings.HasPrefix(p, path+"[") {
+				delete(c.fileSources, p)
+			}
+		}
+		for i := 0; i < dv.Len(); i++ {
+			c.setFileSources(dv.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i), origin)
+		}
+	}
+
+	if path != "" {
+		c.fileSources[path] = origin
+	}
+}
+
+// fileSource returns the source of a file found by findFiles. Profile
+// files are reported by the name of their profile, e.g. "profile:test",
+// every other file by its path, e.g. "file:config.yaml".
+func fileSource(file string) string {
+	sections := strings.SplitN(file, "=", 2)
+	if len(sections) != 2 {
+		return "file:" + file
+	}
+
+	if i := strings.Index(sections[0], ProfileFileIndicator); i >= 0 {
+		// #local:#profile_00_test --> test
+		parts := strings.SplitN(sections[0][i:], "_", 3)
+		if len(parts) == 3 {
+			return "profile:" + parts[2]
+		}
+	}
+	return "file:" + sections[1]
+}
@@ -0,0 +1,104 @@
+package confucius
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_fileSource(t *testing.T) {
+	for _, tc := range []struct {
+		In   string
+		Want string
+	}{
+		{In: "#local:#main=config.yaml", Want: "file:config.yaml"},
+		{In: "#local:#profile_00_test=conf/config.test.yaml", Want: "profile:test"},
+		{In: "#embed:#profile_01_dev_eu=config.dev_eu.yaml", Want: "profile:dev_eu"},
+		{In: "#embed:#main=testdata/config.yaml", Want: "file:testdata/config.yaml"},
+	} {
+		t.Run(tc.In, func(t *testing.T) {
+			if got := fileSource(tc.In); got != tc.Want {
+				t.Fatalf("want %s, got %s", tc.Want, got)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_TrackSources(t *testing.T) {
+	type Config struct {
+		Host    string `conf:"host"`
+		Port    int    `conf:"port" default:"8080"`
+		Timeout string `conf:"timeout"`
+		Logger  struct {
+			Level string `conf:"level"`
+		} `conf:"logger"`
+		Replicas []struct {
+			Name string `conf:"name"`
+		} `conf:"replicas"`
+		Unset string `conf:"unset"`
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	writeFile(t, config, "host: localhost\ntimeout: 5s\nLogger: {level: info}\nreplicas: [{name: a}, {name: b}]")
+	writeFile(t, filepath.Join(dir, "config.test.yaml"), "timeout: 10s\nreplicas: [{name: c}]")
+
+	os.Clearenv()
+	setenv(t, "MYAPP_HOST", "0.0.0.0")
+
+	var (
+		cfg     Config
+		sources map[string]string
+	)
+	err := Load(&cfg,
+		Dirs(dir),
+		Profiles("test"),
+		UseEnv("myapp"),
+		TrackSources(&sources),
+	)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := map[string]string{
+		"host":             "env:MYAPP_HOST",
+		"port":             "default",
+		"timeout":          "profile:test",
+		"logger":           "file:" + config,
+		"logger.level":     "file:" + config,
+		"replicas":         "profile:test",
+		"replicas[0].name": "profile:test",
+	}
+	if !reflect.DeepEqual(want, sources) {
+		t.Fatalf("\nwant %+v\ngot  %+v", want, sources)
+	}
+
+	t.Run("reader", func(t *testing.T) {
+		var (
+			cfg     Config
+			sources map[string]string
+		)
+		if err := Load(&cfg, String(`host: localhost`, DecoderYaml), TrackSources(&sources)); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		want := map[string]string{"host": "reader", "port": "default"}
+		if !reflect.DeepEqual(want, sources) {
+			t.Fatalf("want %+v, got %+v", want, sources)
+		}
+	})
+
+	t.Run("not tracked without option", func(t *testing.T) {
+		conf := defaultConfucius()
+		conf.useReader = true
+		conf.readers = []*readerSource{{content: []byte(`host: localhost`), decoder: DecoderYaml}}
+
+		var cfg Config
+		if err := conf.Load(&cfg); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if conf.sources != nil || conf.fileSources != nil {
+			t.Fatalf("want no sources, got %+v %+v", conf.sources, conf.fileSources)
+		}
+	})
+}
@@ -0,0 +1,2 @@
+kind = "Pod
+metadata {
@@ -1 +0,0 @@
-foo = [1, 2, "foo"]
\ No newline at end of file
@@ -0,0 +1,56 @@
+kind = "Pod"
+
+metadata {
+  name   = "${POD_NAME:redis}"
+  master = true
+}
+
+spec {
+  containers {
+    name    = "redis"
+    image   = "redis:5.0.4"
+    command = ["redis-server", "/redis-master/redis.conf"]
+
+    env {
+      name  = "MASTER"
+      value = "true"
+    }
+
+    ports {
+      containerPort = 6379
+    }
+
+    resources {
+      limits {
+        cpu = "0.1"
+      }
+    }
+
+    volumeMounts {
+      mountPath = "/redis-master-data"
+      name      = "data"
+    }
+
+    volumeMounts {
+      mountPath = "/redis-master"
+      name      = "config"
+    }
+  }
+
+  volumes {
+    name = "data"
+  }
+
+  volumes {
+    name = "config"
+
+    configMap {
+      name = "example-redis-config"
+
+      items {
+        key  = "redis-config"
+        path = "redis.conf"
+      }
+    }
+  }
+}
@@ -0,0 +1,71 @@
+package confucius
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// bindEnv records that the environment variable key was looked up for a
+// field. A key ending with an underscore records the prefix of the
+// variables of the entries of a map.
+func (c *confucius) bindEnv(key string) {
+	if !c.warnUnboundEnv && !c.strictEnv {
+		return
+	}
+	if c.boundEnv == nil {
+		c.boundEnv = make(map[string]bool)
+	}
+	c.boundEnv[key] = true
+}
+
+// isBoundEnv reports whether the environment variable name was looked up
+// for a field, or is one of the entries of a map field.
+func (c *confucius) isBoundEnv(name string) bool {
+	if c.boundEnv[name] {
+		return true
+	}
+	for key := range c.boundEnv {
+		if strings.HasSuffix(key, "_") && strings.HasPrefix(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// unboundEnv returns the names of the environment variables with the
+// prefix given to UseEnv that were not bound to any field, sorted.
+func (c *confucius) unboundEnv() []string {
+	prefix := strings.ToUpper(c.envPrefix) + "_"
+	var names []string
+	for _, env := range os.Environ() {
+		name := env
+		if i := strings.Index(env, "="); i >= 0 {
+			name = env[:i]
+		}
+		if strings.HasPrefix(strings.ToUpper(name), prefix) && !c.isBoundEnv(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkUnboundEnv logs a warning for each environment variable with the
+// prefix given to UseEnv that is not bound to any field when the
+// WarnUnboundEnv option is used, and returns an error wrapping
+// ErrUnboundEnv listing them when the StrictEnv option is used.
+func (c *confucius) checkUnboundEnv() error {
+	if !c.warnUnboundEnv && !c.strictEnv {
+		return nil
+	}
+	names := c.unboundEnv()
+	for _, name := range names {
+		c.logger.Event(WarningLevel, map[string]interface{}{"env": name}, "environment variable matches no field")
+	}
+	if c.strictEnv && len(names) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnboundEnv, strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package confucius
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_confucius_Load_UnboundEnv(t *testing.T) {
+	type Config struct {
+		Host   string            `conf:"host"`
+		Port   int               `conf:"port"`
+		Token  string            `conf:"token" env:"MYAPP_API_TOKEN"`
+		Labels map[string]string `conf:"labels"`
+	}
+
+	os.Clearenv()
+	setenv(t, "MYAPP_HOST", "example.com")
+	setenv(t, "MYAPP_HSOT", "typo.com")
+	setenv(t, "MYAPP_API_TOKEN", "secret")
+	setenv(t, "MYAPP_LABELS_tier", "web")
+	setenv(t, "OTHER_HOST", "other.com")
+
+	t.Run("warns", func(t *testing.T) {
+		var warnings []string
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("myapp"), WarnUnboundEnv(),
+			Logger(StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+				if level == WarningLevel {
+					warnings = append(warnings, fmt.Sprint(fields["env"]))
+				}
+			})),
+		)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if cfg.Host != "example.com" {
+			t.Errorf("want host example.com, got %q", cfg.Host)
+		}
+		if strings.Join(warnings, ",") != "MYAPP_HSOT" {
+			t.Errorf("want warning for MYAPP_HSOT, got %v", warnings)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("myapp"), StrictEnv())
+		if !errors.Is(err, ErrUnboundEnv) {
+			t.Fatalf("want ErrUnboundEnv, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "MYAPP_HSOT") {
+			t.Errorf("want MYAPP_HSOT in error, got %v", err)
+		}
+	})
+
+	t.Run("strict with every variable bound", func(t *testing.T) {
+		os.Unsetenv("MYAPP_HSOT")
+		defer setenv(t, "MYAPP_HSOT", "typo.com")
+
+		var cfg Config
+		if err := Load(&cfg, String(`{}`, DecoderJSON), UseEnv("myapp"), StrictEnv()); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+}
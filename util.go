@@ -7,16 +7,72 @@ import (
 	"time"
 )
 
+// ParseStringSlice splits s, a list formatted like the values of slice
+// fields in the environment and in defaults, into its fields, the way
+// confucius does with the default slice delimiter. It is meant for custom
+// decode hooks and validators handling such lists.
+//
+// The enclosing square brackets are optional and removed. The fields are
+// separated by commas and are not trimmed, except for fields enclosed in
+// single or double quotes, which may contain commas and have their quotes
+// and the whitespace around them removed. An empty list yields a single
+// empty field.
+//
+//   "[1,2,3]"        --->   []string{"1", "2", "3"}
+//   " foo , bar"     --->   []string{" foo ", " bar"}
+//   `["a,b", "c"]`   --->   []string{"a,b", "c"}
+//   "[]"             --->   []string{""}
+func ParseStringSlice(s string) []string {
+	return stringSlice(s, DefaultSliceDelimiter)
+}
+
 // stringSlice converts a Go slice represented as a string
 // into an an actual slice. The enclosing square brackets
 // are not necessary.
-// fields should be separated by a comma.
+// fields should be separated by sep. Fields enclosed in
+// quotes may contain sep, the quotes are removed.
 //
-//   "[1,2,3]"     --->   []string{"1", "2", "3"}
-//   " foo , bar"  --->   []string{" foo ", " bar"}
-func stringSlice(s string) []string {
+//   "[1,2,3]"        --->   []string{"1", "2", "3"}
+//   " foo , bar"     --->   []string{" foo ", " bar"}
+//   `["a,b", "c"]`   --->   []string{"a,b", "c"}
+func stringSlice(s, sep string) []string {
 	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
-	return strings.Split(s, ",")
+	if sep == "" {
+		sep = DefaultSliceDelimiter
+	}
+
+	var (
+		ss    []string
+		start int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			if strings.TrimSpace(s[start:i]) == "" {
+				quote = s[i]
+			}
+		case strings.HasPrefix(s[i:], sep):
+			ss = append(ss, unquoteField(s[start:i]))
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(ss, unquoteField(s[start:]))
+}
+
+// unquoteField removes the quotes of a quoted slice field,
+// along with the whitespace around them. Fields that are
+// not quoted are returned unchanged.
+func unquoteField(s string) string {
+	if t := strings.TrimSpace(s); t != unquote(t) {
+		return unquote(t)
+	}
+	return s
 }
 
 // fileExists returns true if the file exists and is not a
@@ -40,12 +96,15 @@ func isZero(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Ptr, reflect.Interface:
 		return v.IsNil()
-	case reflect.Slice, reflect.Array:
+	case reflect.Slice, reflect.Array, reflect.Map:
 		return v.Len() == 0
 	case reflect.Struct:
 		if t, ok := v.Interface().(time.Time); ok {
 			return t.IsZero()
 		}
+		if isBig(v.Type()) || v.Type() == quantityType || isBinaryUnmarshaler(v.Type()) {
+			return v.IsZero()
+		}
 		return false
 	case reflect.Invalid:
 		return true
@@ -53,3 +112,25 @@ func isZero(v reflect.Value) bool {
 		return v.IsZero()
 	}
 }
+
+// uniqueStrings returns the strings of list without the duplicates,
+// keeping the first occurrence of each.
+func uniqueStrings(list []string) []string {
+	result := make([]string, 0, len(list))
+	for _, s := range list {
+		if !contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// contains reports whether s is one of the strings in list.
+func contains(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
@@ -28,14 +28,57 @@ func Test_stringSlice(t *testing.T) {
 			In:   "[foo]",
 			Want: []string{"foo"},
 		},
+		{
+			In:   `["a,b", "c"]`,
+			Want: []string{"a,b", "c"},
+		},
+		{
+			In:   `'cn=a,ou=b', "x" ,y`,
+			Want: []string{"cn=a,ou=b", "x", "y"},
+		},
+		{
+			In:   `a"b,c`,
+			Want: []string{`a"b`, "c"},
+		},
 	} {
 		t.Run(tc.In, func(t *testing.T) {
-			got := stringSlice(tc.In)
+			got := stringSlice(tc.In, ",")
 			if !reflect.DeepEqual(tc.Want, got) {
 				t.Fatalf("want %+v, got %+v", tc.Want, got)
 			}
 		})
 	}
+
+	t.Run("custom delimiter", func(t *testing.T) {
+		got := stringSlice(`cn=a,ou=b;"x;y";z`, ";")
+		want := []string{"cn=a,ou=b", "x;y", "z"}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("want %+v, got %+v", want, got)
+		}
+	})
+}
+
+func Test_ParseStringSlice(t *testing.T) {
+	for _, tc := range []struct {
+		Name string
+		In   string
+		Want []string
+	}{
+		{Name: "bracketed", In: "[a,b,c]", Want: []string{"a", "b", "c"}},
+		{Name: "unbracketed", In: "a,b,c", Want: []string{"a", "b", "c"}},
+		{Name: "empty", In: "", Want: []string{""}},
+		{Name: "empty brackets", In: "[]", Want: []string{""}},
+		{Name: "whitespace padded", In: "[ a , b ]", Want: []string{" a ", " b "}},
+		{Name: "quoted with whitespace", In: ` "a, b" , 'c' `, Want: []string{"a, b", "c"}},
+		{Name: "single", In: "a", Want: []string{"a"}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			got := ParseStringSlice(tc.In)
+			if !reflect.DeepEqual(tc.Want, got) {
+				t.Fatalf("want %#v, got %#v", tc.Want, got)
+			}
+		})
+	}
 }
 
 func Test_fileExists(t *testing.T) {
@@ -94,6 +137,27 @@ func Test_isZero(t *testing.T) {
 		}
 	})
 
+	t.Run("nil map is zero", func(t *testing.T) {
+		var m map[string]int
+		if isZero(reflect.ValueOf(m)) == false {
+			t.Fatalf("isZero == false")
+		}
+	})
+
+	t.Run("empty map is zero", func(t *testing.T) {
+		m := map[string]int{}
+		if isZero(reflect.ValueOf(m)) == false {
+			t.Fatalf("isZero == false")
+		}
+	})
+
+	t.Run("populated map is not zero", func(t *testing.T) {
+		m := map[string]int{"a": 1}
+		if isZero(reflect.ValueOf(m)) == true {
+			t.Fatalf("isZero == true")
+		}
+	})
+
 	t.Run("nil pointer is zero", func(t *testing.T) {
 		var s *string
 		if isZero(reflect.ValueOf(s)) == false {
@@ -161,3 +225,19 @@ func Test_isZero(t *testing.T) {
 		}
 	})
 }
+
+func Test_uniqueStrings(t *testing.T) {
+	for _, tc := range []struct {
+		In   []string
+		Want []string
+	}{
+		{In: nil, Want: []string{}},
+		{In: []string{"dev"}, Want: []string{"dev"}},
+		{In: []string{"e2e", "e2e"}, Want: []string{"e2e"}},
+		{In: []string{"dev", "eu", "dev", "us", "eu"}, Want: []string{"dev", "eu", "us"}},
+	} {
+		if got := uniqueStrings(tc.In); !reflect.DeepEqual(tc.Want, got) {
+			t.Errorf("uniqueStrings(%q) == %q, expected %q", tc.In, got, tc.Want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package confucius
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// validateRequired checks that the field is set.
+	validateRequired = "required"
+	// validateRecommended warns when the field is not set, like a required
+	// rule given in the warn key.
+	validateRecommended = "recommended"
+	// validateFuture checks that a time.Time field is after the current time.
+	validateFuture = "future"
+	// validatePast checks that a time.Time field is before the current time.
+	validatePast = "past"
+	// validateRequiredWith makes the field required if any of the fields
+	// named in its parameter, e.g. required_with=TLSCert, is set.
+	validateRequiredWith = "required_with"
+	// validateRequiredWithout makes the field required if any of the fields
+	// named in its parameter, e.g. required_without=Token, is not set.
+	validateRequiredWithout = "required_without"
+	// validateSubset checks that every element of a slice field is one of
+	// the values in its parameter, e.g. subset=dev staging prod.
+	validateSubset = "subset"
+	// validateSemver checks that a string field is a semantic version.
+	validateSemver = "semver"
+	// validateSemverConstraint checks that a string field is a semantic
+	// version satisfying its parameter, e.g. semver_constraint=>=1.2.0.
+	validateSemverConstraint = "semver_constraint"
+	// validateUnique checks that a slice field has no duplicate elements.
+	validateUnique = "unique"
+	// validateSorted checks that the elements of a slice field of numbers
+	// or strings are in ascending order.
+	validateSorted = "sorted"
+)
+
+// validateRule checks that fv satisfies the validation rule.
+// Rules other than required are only checked for fields that are
+// set, combine them with required to make sure the field is set.
+func validateRule(fv reflect.Value, rule string) error {
+	if isZero(fv) {
+		return nil
+	}
+	for fv.Kind() == reflect.Ptr {
+		fv = fv.Elem()
+	}
+
+	name, param := splitRule(rule)
+	switch name {
+	case validateSubset:
+		if fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array {
+			return fmt.Errorf("%s validation is not supported for type %s", name, fv.Type())
+		}
+		allowed := strings.Fields(param)
+		if len(allowed) == 0 {
+			return fmt.Errorf("%s validation requires the allowed values", name)
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if val := fmt.Sprint(fv.Index(i).Interface()); !contains(allowed, val) {
+				return fmt.Errorf("%s validation failed: %s is not one of %s", name, val, strings.Join(allowed, ", "))
+			}
+		}
+	case validateUnique:
+		if fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array {
+			return fmt.Errorf("%s validation is not supported for type %s", name, fv.Type())
+		}
+		for i := 1; i < fv.Len(); i++ {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(fv.Index(i).Interface(), fv.Index(j).Interface()) {
+					return fmt.Errorf("%s validation failed: %v at index %d is a duplicate of index %d", name, fv.Index(i).Interface(), i, j)
+				}
+			}
+		}
+	case validateSorted:
+		if fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array {
+			return fmt.Errorf("%s validation is not supported for type %s", name, fv.Type())
+		}
+		for i := 1; i < fv.Len(); i++ {
+			less, ok := lessValue(fv.Index(i), fv.Index(i-1))
+			if !ok {
+				return fmt.Errorf("%s validation is not supported for type %s", name, fv.Type())
+			}
+			if less {
+				return fmt.Errorf("%s validation failed: %v at index %d is less than %v before it", name, fv.Index(i).Interface(), i, fv.Index(i-1).Interface())
+			}
+		}
+	case validateSemver, validateSemverConstraint:
+		if fv.Kind() != reflect.String {
+			return fmt.Errorf("%s validation is not supported for type %s", name, fv.Type())
+		}
+		v, err := parseSemver(fv.String())
+		if err != nil {
+			return fmt.Errorf("%s validation failed: %w", name, err)
+		}
+		if name == validateSemver {
+			break
+		}
+		ok, err := checkSemverConstraint(v, param)
+		if err != nil {
+			return fmt.Errorf("%s validation: %w", name, err)
+		}
+		if !ok {
+			return fmt.Errorf("%s validation failed: %s does not satisfy %s", name, fv.String(), param)
+		}
+	case validateFuture, validatePast:
+		t, ok := fv.Interface().(time.Time)
+		if !ok {
+			return fmt.Errorf("%s validation is not supported for type %s", rule, fv.Type())
+		}
+		if rule == validateFuture && !t.After(time.Now()) {
+			return fmt.Errorf("%s validation failed: %s is not in the future", rule, t.Format(time.RFC3339))
+		}
+		if rule == validatePast && !t.Before(time.Now()) {
+			return fmt.Errorf("%s validation failed: %s is not in the past", rule, t.Format(time.RFC3339))
+		}
+	default:
+		return fmt.Errorf("unknown validation %s", rule)
+	}
+	return nil
+}
+
+// lessValue reports whether a is less than b, which have the same type.
+// ok is false if values of their type cannot be ordered.
+func lessValue(a, b reflect.Value) (less, ok bool) {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float(), true
+	case reflect.String:
+		return a.String() < b.String(), true
+	}
+	return false, false
+}
+
+// splitRule splits a validation rule into its name and parameter.
+//
+//	"required_with=TLSCert"   --->   "required_with", "TLSCert"
+func splitRule(rule string) (name, param string) {
+	if i := strings.Index(rule, "="); i >= 0 {
+		return rule[:i], rule[i+1:]
+	}
+	return rule, ""
+}
+
+// isRule reports whether the name of rule is one of the validation rules.
+func isRule(rule string) bool {
+	switch name, _ := splitRule(rule); name {
+	case validateRequired, validateFuture, validatePast, validateRequiredWith, validateRequiredWithout,
+		validateSubset, validateSemver, validateSemverConstraint, validateUnique, validateSorted:
+		return true
+	}
+	return false
+}
+
+// isSiblingRule reports whether the rule depends on the values of the
+// field's siblings. Such rules are checked once every field is set.
+func isSiblingRule(rule string) bool {
+	name, _ := splitRule(rule)
+	return name == validateRequiredWith || name == validateRequiredWithout
+}
+
+// validateSiblingRule checks that f satisfies a rule depending on its
+// siblings. The siblings are named in the rule's parameter, separated by
+// spaces, using their alt name or their name in the struct.
+func validateSiblingRule(f *field, rule, tagKey string) error {
+	name, param := splitRule(rule)
+	refs := strings.Fields(param)
+	if len(refs) == 0 {
+		return fmt.Errorf("%s validation requires the name of a field", name)
+	}
+
+	for _, ref := range refs {
+		sv, ok := f.sibling(ref, tagKey)
+		if !ok {
+			return fmt.Errorf("%s validation refers to unknown field %s", name, ref)
+		}
+		if !isZero(f.v) {
+			continue
+		}
+		if name == validateRequiredWith && !isZero(sv) {
+			return fmt.Errorf("%s validation failed: required when %s is set", name, ref)
+		}
+		if name == validateRequiredWithout && isZero(sv) {
+			return fmt.Errorf("%s validation failed: required when %s is not set", name, ref)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package confucius
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_validateRule(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	for _, tc := range []struct {
+		Name    string
+		Val     interface{}
+		Rule    string
+		WantErr bool
+	}{
+		{Name: "future", Val: future, Rule: validateFuture},
+		{Name: "future in past", Val: past, Rule: validateFuture, WantErr: true},
+		{Name: "past", Val: past, Rule: validatePast},
+		{Name: "past in future", Val: future, Rule: validatePast, WantErr: true},
+		{Name: "ptr", Val: &future, Rule: validateFuture},
+		{Name: "ptr in past", Val: &past, Rule: validateFuture, WantErr: true},
+		{Name: "zero time", Val: time.Time{}, Rule: validateFuture},
+		{Name: "nil ptr", Val: (*time.Time)(nil), Rule: validatePast},
+		{Name: "unsupported type", Val: "tomorrow", Rule: validateFuture, WantErr: true},
+		{Name: "subset", Val: []string{"dev", "prod"}, Rule: "subset=dev staging prod"},
+		{Name: "subset of ints", Val: []int{80, 443}, Rule: "subset=80 443"},
+		{Name: "empty subset", Val: []string{}, Rule: "subset=dev staging prod"},
+		{Name: "not a subset", Val: []string{"dev", "qa"}, Rule: "subset=dev staging prod", WantErr: true},
+		{Name: "subset without values", Val: []string{"dev"}, Rule: "subset=", WantErr: true},
+		{Name: "subset of non slice", Val: "dev", Rule: "subset=dev", WantErr: true},
+		{Name: "unique ints", Val: []int{80, 443, 8080}, Rule: validateUnique},
+		{Name: "unique strings", Val: []string{"a", "b"}, Rule: validateUnique},
+		{Name: "duplicate ints", Val: []int{80, 443, 80}, Rule: validateUnique, WantErr: true},
+		{Name: "duplicate strings", Val: []string{"a", "b", "b"}, Rule: validateUnique, WantErr: true},
+		{Name: "unique of non slice", Val: 80, Rule: validateUnique, WantErr: true},
+		{Name: "sorted ints", Val: []int{1, 2, 2, 10}, Rule: validateSorted},
+		{Name: "sorted strings", Val: []string{"a", "b", "c"}, Rule: validateSorted},
+		{Name: "sorted durations", Val: []time.Duration{time.Second, time.Minute}, Rule: validateSorted},
+		{Name: "unsorted ints", Val: []int{1, 10, 2}, Rule: validateSorted, WantErr: true},
+		{Name: "unsorted strings", Val: []string{"b", "a"}, Rule: validateSorted, WantErr: true},
+		{Name: "sorted of unordered type", Val: []bool{true, false}, Rule: validateSorted, WantErr: true},
+		{Name: "unknown rule", Val: "a", Rule: "uppercase", WantErr: true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := validateRule(reflect.ValueOf(tc.Val), tc.Rule)
+			if tc.WantErr && err == nil {
+				t.Fatalf("expected err")
+			}
+			if !tc.WantErr && err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_TimeRange(t *testing.T) {
+	type Config struct {
+		Certificate struct {
+			NotBefore  time.Time `conf:"not_before" validate:"past"`
+			Expiration time.Time `conf:"expiration" validate:"required,future"`
+		} `conf:"certificate"`
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	tomorrow := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	t.Run("valid", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String("certificate: {not_before: "+yesterday+", expiration: "+tomorrow+"}", DecoderYaml))
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String("certificate: {not_before: "+tomorrow+", expiration: "+yesterday+"}", DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+
+		errs := err.(fieldErrors)
+		if len(errs) != 2 {
+			t.Fatalf("want 2 field errors, got %+v", errs)
+		}
+		for _, path := range []string{"certificate.not_before", "certificate.expiration"} {
+			if _, ok := errs[path]; !ok {
+				t.Errorf("want %s in fieldErrors, got %+v", path, errs)
+			}
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`certificate: {}`, DecoderYaml))
+		if err == nil {
+			t.Fatalf("expected err")
+		}
+
+		errs := err.(fieldErrors)
+		if _, ok := errs["certificate.not_before"]; ok {
+			t.Errorf("want no error for unset certificate.not_before, got %v", errs["certificate.not_before"])
+		}
+		if _, ok := errs["certificate.expiration"]; !ok {
+			t.Errorf("want certificate.expiration in fieldErrors, got %+v", errs)
+		}
+	})
+}
+
+func Test_confucius_Load_Subset(t *testing.T) {
+	type Config struct {
+		Environments []string `conf:"environments" validate:"subset=dev staging prod"`
+	}
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		WantErr bool
+	}{
+		{Name: "valid subset", Content: `environments: [dev, prod]`},
+		{Name: "empty slice", Content: `environments: []`},
+		{Name: "unset", Content: `{}`},
+		{Name: "disallowed value", Content: `environments: [dev, qa]`, WantErr: true},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.Content, DecoderYaml))
+			if !tc.WantErr {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(fieldErrors)["environments"]; !ok {
+				t.Fatalf("want environments in fieldErrors, got %+v", err)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_UniqueSorted(t *testing.T) {
+	type Config struct {
+		Ports      []int    `conf:"ports" validate:"unique,sorted"`
+		Priorities []string `conf:"priorities" validate:"unique"`
+	}
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		WantErr string
+	}{
+		{Name: "valid", Content: "ports: [80, 443, 8080]\npriorities: [high, low]"},
+		{Name: "unset", Content: `{}`},
+		{Name: "duplicate port", Content: `ports: [80, 443, 80]`, WantErr: "ports: unique validation failed: 80 at index 2 is a duplicate of index 0"},
+		{Name: "unsorted ports", Content: `ports: [443, 80]`, WantErr: "ports: sorted validation failed: 80 at index 1 is less than 443 before it"},
+		{Name: "duplicate priority", Content: `priorities: [high, high]`, WantErr: "priorities: unique validation failed: high at index 1 is a duplicate of index 0"},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.Content, DecoderYaml))
+			if tc.WantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.WantErr {
+				t.Fatalf("want err %q, got %v", tc.WantErr, err)
+			}
+		})
+	}
+}
+
+func Test_confucius_Load_Semver(t *testing.T) {
+	type Config struct {
+		Version string `conf:"version" validate:"required,semver"`
+		Plugin  struct {
+			API string `conf:"api" validate:"semver_constraint=>=1.2.0 <2.0.0"`
+		} `conf:"plugin"`
+	}
+
+	for _, tc := range []struct {
+		Name     string
+		Content  string
+		WantErrs []string
+	}{
+		{Name: "valid", Content: `{version: v1.0.0-rc.1, plugin: {api: 1.4.2}}`},
+		{Name: "unset constraint", Content: `{version: 1.0.0}`},
+		{Name: "invalid version", Content: `{version: "1.0", plugin: {api: 1.4.2}}`, WantErrs: []string{"version"}},
+		{Name: "failing constraint", Content: `{version: 1.0.0, plugin: {api: 1.1.0}}`, WantErrs: []string{"plugin.api"}},
+		{Name: "invalid constrained version", Content: `{version: 1.0.0, plugin: {api: latest}}`, WantErrs: []string{"plugin.api"}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.Content, DecoderYaml))
+			if len(tc.WantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+
+			errs, ok := err.(fieldErrors)
+			if !ok || len(errs) != len(tc.WantErrs) {
+				t.Fatalf("want errors for %v, got %v", tc.WantErrs, err)
+			}
+			for _, path := range tc.WantErrs {
+				if _, ok := errs[path]; !ok {
+					t.Errorf("want %s in fieldErrors, got %+v", path, errs)
+				}
+			}
+		})
+	}
+}
+
+func Test_splitRule(t *testing.T) {
+	name, param := splitRule("required_with=TLSCert TLSKey")
+	if name != validateRequiredWith || param != "TLSCert TLSKey" {
+		t.Fatalf("unexpected split: %q %q", name, param)
+	}
+	name, param = splitRule("future")
+	if name != validateFuture || param != "" {
+		t.Fatalf("unexpected split: %q %q", name, param)
+	}
+}
+
+func Test_confucius_Load_RequiredWith(t *testing.T) {
+	type Config struct {
+		TLS struct {
+			Cert string `conf:"cert" validate:"required_with=key"`
+			Key  string `conf:"key" validate:"required_with=Cert"`
+		} `conf:"tls"`
+		Auth struct {
+			Token    string `conf:"token" validate:"required_without=password"`
+			Password string `conf:"password"`
+		} `conf:"auth"`
+	}
+
+	for _, tc := range []struct {
+		Name     string
+		Content  string
+		WantErrs []string
+	}{
+		{Name: "pair", Content: `{tls: {cert: a.pem, key: a.key}, auth: {token: t}}`},
+		{Name: "neither", Content: `{auth: {password: p}}`},
+		{Name: "cert without key", Content: `{tls: {cert: a.pem}, auth: {token: t}}`, WantErrs: []string{"tls.key"}},
+		{Name: "key without cert", Content: `{tls: {key: a.key}, auth: {token: t}}`, WantErrs: []string{"tls.cert"}},
+		{Name: "no token nor password", Content: `{}`, WantErrs: []string{"auth.token"}},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var cfg Config
+			err := Load(&cfg, String(tc.Content, DecoderYaml))
+			if len(tc.WantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				return
+			}
+
+			errs, ok := err.(fieldErrors)
+			if !ok || len(errs) != len(tc.WantErrs) {
+				t.Fatalf("want errors for %v, got %v", tc.WantErrs, err)
+			}
+			for _, path := range tc.WantErrs {
+				if _, ok := errs[path]; !ok {
+					t.Errorf("want %s in fieldErrors, got %+v", path, errs)
+				}
+			}
+		})
+	}
+
+	t.Run("sibling set by env", func(t *testing.T) {
+		os.Clearenv()
+		setenv(t, "TLS_KEY", "a.key")
+
+		var cfg Config
+		err := Load(&cfg, String(`{auth: {token: t}}`, DecoderYaml), UseEnv(""))
+		if _, ok := err.(fieldErrors)["tls.cert"]; !ok {
+			t.Fatalf("want tls.cert in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("unknown sibling", func(t *testing.T) {
+		var cfg struct {
+			Cert string `validate:"required_with=Chain"`
+		}
+		if err := Load(&cfg, String(`{}`, DecoderYaml)); err == nil {
+			t.Fatalf("expected err")
+		}
+	})
+}
+
+func Test_confucius_Load_Warnings(t *testing.T) {
+	type Config struct {
+		Name     string   `conf:"name" validate:"recommended"`
+		Version  string   `conf:"version" warn:"semver"`
+		Ports    []int    `conf:"ports" warn:"unique,sorted"`
+		Owner    string   `conf:"owner" warn:"required"`
+		Cert     string   `conf:"cert" warn:"required_with=key"`
+		Key      string   `conf:"key"`
+		Replicas int      `conf:"replicas" validate:"required" warn:"required"`
+		Tags     []string `conf:"tags" warn:"subset=a b"`
+	}
+
+	for _, tc := range []struct {
+		Name    string
+		Content string
+		Want    []string
+	}{
+		{
+			Name:    "warnings",
+			Content: `{version: latest, ports: [443, 80], key: a.key, replicas: 1, tags: [a]}`,
+			Want:    []string{"cert", "name", "owner", "ports", "version"},
+		},
+		{
+			Name:    "no warnings",
+			Content: `{name: app, version: 1.2.3, ports: [80, 443], owner: me, replicas: 1}`,
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			var warned []string
+			var cfg Config
+			err := Load(&cfg, String(tc.Content, DecoderYaml),
+				Logger(StructuredCallback(func(level LogLevel, message string, fields map[string]interface{}) {
+					if level == WarningLevel && message == "validation warning" {
+						warned = append(warned, fields["field"].(string))
+					}
+				})),
+			)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			sort.Strings(warned)
+			if len(tc.Want) == 0 && len(warned) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(warned, tc.Want) {
+				t.Fatalf("want warnings for %v, got %v", tc.Want, warned)
+			}
+		})
+	}
+
+	t.Run("errors still fail", func(t *testing.T) {
+		var cfg Config
+		err := Load(&cfg, String(`{name: app}`, DecoderYaml))
+		if _, ok := err.(fieldErrors)["replicas"]; !ok {
+			t.Fatalf("want replicas in fieldErrors, got %+v", err)
+		}
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		var cfg struct {
+			Name string `conf:"name" warn:"bogus"`
+		}
+		err := Load(&cfg, String(`{name: app}`, DecoderYaml))
+		if err == nil || !strings.Contains(err.Error(), "unknown validation bogus") {
+			t.Fatalf("want unknown validation err, got %v", err)
+		}
+	})
+}
+
+func Test_confucius_Load_ForeignRules(t *testing.T) {
+	type Config struct {
+		Email string `conf:"email" validate:"email"`
+		Count int    `conf:"count" validate:"required,min=1"`
+	}
+
+	var cfg Config
+	if err := Load(&cfg, String(`{email: me@example.com, count: 2}`, DecoderYaml)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	cfg = Config{}
+	err := Load(&cfg, String(`{email: me@example.com}`, DecoderYaml))
+	fieldErrs, ok := err.(fieldErrors)
+	if !ok || len(fieldErrs) != 1 {
+		t.Fatalf("want a single field error, got %+v", err)
+	}
+	if _, ok := fieldErrs["count"]; !ok {
+		t.Fatalf("want count in fieldErrors, got %+v", fieldErrs)
+	}
+}